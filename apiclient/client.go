@@ -2,17 +2,25 @@ package apiclient
 
 import (
 	"bytes"
+	"crypto/rand"
 	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"net"
 	"net/http"
+	"os"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 )
 
+var ErrNoCertificate = errors.New("no PEM certificate found")
+
 type Client struct {
 	client        *http.Client
 	urlprefix     string
@@ -21,6 +29,9 @@ type Client struct {
 	Token         string
 	UsernameField string
 	PasswordField string
+	signer        func(*http.Request, []byte) error
+	requestID     string
+	policy        RequestPolicy
 }
 
 var (
@@ -48,13 +59,7 @@ func newClient(dial_timeout uint, InsecureSkipVerify bool) *http.Client {
 		}
 
 	}
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: InsecureSkipVerify},
-		Dial: (&net.Dialer{
-			Timeout: time.Duration(dial_timeout) * time.Second,
-		}).Dial,
-	}
-	http_client := &http.Client{Transport: tr}
+	http_client := newHTTPClient(dial_timeout, InsecureSkipVerify, nil)
 
 	if InsecureSkipVerify {
 		http_client_noverify[dial_timeout] = http_client
@@ -64,6 +69,46 @@ func newClient(dial_timeout uint, InsecureSkipVerify bool) *http.Client {
 	return http_client
 }
 
+// roots nil means system certificate authorities
+func newHTTPClient(dial_timeout uint, InsecureSkipVerify bool, roots *x509.CertPool) *http.Client {
+	tr := &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: InsecureSkipVerify, RootCAs: roots},
+		Dial: (&net.Dialer{
+			Timeout: time.Duration(dial_timeout) * time.Second,
+		}).Dial,
+	}
+	return &http.Client{Transport: tr}
+}
+
+// Return system certificate authorities and those of PEM file ca_file
+func LoadCAFile(ca_file string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(ca_file)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("%w in %s", ErrNoCertificate, ca_file)
+	}
+	return pool, nil
+}
+
+// Same as NewClientWithDialTimeout, trusting certificate authorities of PEM file ca_file in addition to system ones.
+// Empty ca_file means system certificate authorities only.
+func NewClientWithCAFile(urlprefix string, username string, password string, ca_file string, InsecureSkipVerify bool, dial_timeout uint) (*Client, error) {
+	if ca_file == "" {
+		return NewClientWithDialTimeout(urlprefix, username, password, InsecureSkipVerify, dial_timeout), nil
+	}
+	roots, err := LoadCAFile(ca_file)
+	if err != nil {
+		return nil, err
+	}
+	return newRichClientFromHTTPClient(urlprefix, username, password, newHTTPClient(dial_timeout, InsecureSkipVerify, roots)), nil
+}
+
 func NewClientWithDialTimeout(urlprefix string, username string, password string, InsecureSkipVerify bool, dial_timeout uint) *Client {
 	// get http client
 	http_client := newClient(dial_timeout, InsecureSkipVerify)
@@ -93,6 +138,27 @@ func newRichClientFromHTTPClient(urlprefix string, username string, password str
 	return &c
 }
 
+// Set the ID sent in X-Request-ID header of every request created from now on.
+// It lets the back-end correlate its logs with the client ones.
+func (c *Client) SetRequestID(id string) {
+	c.requestID = id
+}
+
+// Set retries, deadline and circuit breaker of every request created from now on
+func (c *Client) SetRequestPolicy(p RequestPolicy) {
+	c.policy = p
+}
+
+// Return a random request ID
+func NewRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		// should never happen: fall back on current time
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b)
+}
+
 /****** GET ******/
 
 func (c *Client) NewGET(url string, content string) (*Request, error) {
@@ -180,6 +246,7 @@ func (c *Client) newWithBody(method string, url_suffix string, body io.Reader, c
 	req := Request{
 		client:  c.client,
 		content: strings.ToLower(content),
+		signer:  c.signer,
 	}
 
 	if req.content != "xml" && req.content != "json" {
@@ -245,6 +312,11 @@ func (c *Client) newWithBody(method string, url_suffix string, body io.Reader, c
 		}
 	}
 
+	if c.requestID != "" {
+		req.SetHeader(HeaderRequestID, c.requestID)
+	}
+	req.SetPolicy(c.policy)
+
 	switch auth_mode {
 	case AuthMode_Basic:
 		if c.username == "" || c.password == "" {
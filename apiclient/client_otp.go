@@ -2,7 +2,10 @@
 
 package apiclient
 
-import "mediator/totp"
+import (
+	"mediator/signature"
+	"mediator/totp"
+)
 
 func NewClientWithOTP(url_prefix string, insecure_skip_verify bool) (*Client, error) {
 
@@ -24,10 +27,14 @@ func NewClientWithOTPAndTimeout(url_prefix string, insecure_skip_verify bool, di
 	return client, nil
 }
 
+// Get a new tOTP password for the next request.
+// Requests sent with this client are signed from now on
+// so the back-end can reject replayed requests.
 func (c *Client) SetToken() error {
 	var err error
 	if c.Token, err = totp.GetKey(); err != nil {
 		return err
 	}
+	c.signer = signature.Sign
 	return nil
 }
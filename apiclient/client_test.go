@@ -0,0 +1,51 @@
+package apiclient
+
+import (
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewClientWithCAFile(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(srv.Close)
+	dir := t.TempDir()
+	ca_file := filepath.Join(dir, "ca.pem")
+	if err := os.WriteFile(ca_file, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	empty_file := filepath.Join(dir, "empty.pem")
+	if err := os.WriteFile(empty_file, []byte("not a certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := map[string]struct {
+		ca_file string
+		err     error
+		trusted bool
+	}{
+		"system only":    {"", nil, false},
+		"ca file":        {ca_file, nil, true},
+		"no certificate": {empty_file, ErrNoCertificate, false},
+		"missing file":   {filepath.Join(dir, "missing.pem"), os.ErrNotExist, false},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			c, err := NewClientWithCAFile(srv.URL, "", "", tc.ca_file, false, 1)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expected error %v, got %v", tc.err, err)
+			}
+			if err != nil {
+				return
+			}
+			r, _ := c.NewGET("test", "json")
+			if _, err := r.RunWithoutDecode(); (err == nil) != tc.trusted {
+				t.Errorf("expected trusted %v, got error %v", tc.trusted, err)
+			}
+		})
+	}
+}
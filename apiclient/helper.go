@@ -1,6 +1,7 @@
 package apiclient
 
 import (
+	"context"
 	"errors"
 	"io"
 	"net/http"
@@ -14,6 +15,9 @@ type APIclientHelper struct {
 	time_out             uint
 	response             *http.Response
 	cookie               string
+	headers              http.Header
+	ctx                  context.Context
+	policy               RequestPolicy
 }
 type QueryParams map[string]string
 
@@ -43,6 +47,38 @@ func GetHelperWithTimeout(url string, skipVerify bool, timeout uint) *APIclientH
 	return &h
 }
 
+// Set a header that will be sent with every request
+func (h *APIclientHelper) SetHeader(key, value string) {
+	if h.headers == nil {
+		h.headers = http.Header{}
+	}
+	h.headers.Set(key, value)
+}
+
+// Set the context of every request. It can be used to cancel requests or to limit their total duration.
+func (h *APIclientHelper) SetContext(ctx context.Context) {
+	h.ctx = ctx
+}
+
+// Set retries, deadline and circuit breaker of every request.
+// Policy dial timeout replaces the one given when creating the helper.
+func (h *APIclientHelper) SetRequestPolicy(p RequestPolicy) {
+	h.policy = p
+	if p.DialTimeout > 0 {
+		h.time_out = p.DialTimeout
+	}
+}
+
+func (h *APIclientHelper) prepareRequest(r *Request) {
+	for key := range h.headers {
+		r.SetHeader(key, h.headers.Get(key))
+	}
+	if h.ctx != nil {
+		r.SetContext(h.ctx)
+	}
+	r.SetPolicy(h.policy)
+}
+
 func (h *APIclientHelper) GetLastRequestStatusCode() int {
 	if h.last_request_status == 0 {
 		return http.StatusInternalServerError
@@ -78,7 +114,39 @@ func (h *APIclientHelper) RunPOSTwithCredentials(url, u, p string, body io.Reade
 
 	if r, err = client.NewPOSTwithBasicAuth(url, body, content); err != nil {
 		return nil, err
-	} else if v == nil {
+	}
+
+	h.prepareRequest(r)
+
+	if v == nil {
+		// run without decode
+		return r.RunWithoutDecode()
+	} else {
+		return nil, r.Run(v)
+	}
+}
+
+func (h *APIclientHelper) RunPUTwithCredentials(url, u, p string, body io.Reader, content string, v any) (io.Reader, error) {
+	var (
+		err    error
+		r      *Request
+		client *Client
+	)
+	defer func() { h.saveStatusCodeAndSession(r) }()
+
+	if h.time_out != 0 {
+		client = NewClientWithDialTimeout(h.backend_url, u, p, h.ssl_skip_verify, h.time_out)
+	} else {
+		client = NewClient(h.backend_url, u, p, h.ssl_skip_verify)
+	}
+
+	if r, err = client.NewPUTwithBasicAuth(url, body, content); err != nil {
+		return nil, err
+	}
+
+	h.prepareRequest(r)
+
+	if v == nil {
 		// run without decode
 		return r.RunWithoutDecode()
 	} else {
@@ -102,7 +170,11 @@ func (h *APIclientHelper) RunDELETEwithCredentials(url, u, p string, content str
 
 	if r, err = client.NewDELETEwithBasicAuth(url, content); err != nil {
 		return nil, err
-	} else if v == nil {
+	}
+
+	h.prepareRequest(r)
+
+	if v == nil {
 		// run without decode
 		return r.RunWithoutDecode()
 	} else {
@@ -132,6 +204,8 @@ func (h *APIclientHelper) RunGETwithCredentialsAndParams(url, u, p string, param
 		return nil, err
 	}
 
+	h.prepareRequest(r)
+
 	r.AddQueryParams(params)
 
 	if v == nil {
@@ -170,6 +244,8 @@ func (h *APIclientHelper) RunGETwithCookie(url string, content string, v any) (i
 		return nil, err
 	}
 
+	h.prepareRequest(r)
+
 	if v == nil {
 		// run without decode
 		return r.RunWithoutDecode()
@@ -28,6 +28,8 @@ func (h *APIclientHelper) RunGETwithToken(url string, content string, v any) (io
 		return nil, err
 	}
 
+	h.prepareRequest(r)
+
 	if v == nil {
 		// run without decode
 		return r.RunWithoutDecode()
@@ -56,7 +58,11 @@ func (h *APIclientHelper) RunGETwithBodyAndToken(url string, body io.Reader, con
 
 	if r, err = client.NewGETwithBodyAndToken(url, body, content); err != nil {
 		return nil, err
-	} else if v == nil {
+	}
+
+	h.prepareRequest(r)
+
+	if v == nil {
 		// run without decode
 		return r.RunWithoutDecode()
 	} else {
@@ -84,7 +90,11 @@ func (h *APIclientHelper) RunPOSTwithToken(url string, body io.Reader, content s
 
 	if r, err = client.NewPOSTwithToken(url, body, content); err != nil {
 		return nil, err
-	} else if v == nil {
+	}
+
+	h.prepareRequest(r)
+
+	if v == nil {
 		// run without decode
 		return r.RunWithoutDecode()
 	} else {
@@ -112,7 +122,11 @@ func (h *APIclientHelper) RunPUTwithToken(url string, body io.Reader, content st
 
 	if r, err = client.NewPUTwithToken(url, body, content); err != nil {
 		return nil, err
-	} else if v == nil {
+	}
+
+	h.prepareRequest(r)
+
+	if v == nil {
 		// run without decode
 		return r.RunWithoutDecode()
 	} else {
@@ -140,7 +154,11 @@ func (h *APIclientHelper) RunDELETEwithToken(url string, content string, v any)
 
 	if r, err = client.NewDELETEwithToken(url, content); err != nil {
 		return nil, err
-	} else if v == nil {
+	}
+
+	h.prepareRequest(r)
+
+	if v == nil {
 		// run without decode
 		return r.RunWithoutDecode()
 	} else {
@@ -168,7 +186,11 @@ func (h *APIclientHelper) RunDELETEwithBodyAndToken(url string, body io.Reader,
 
 	if r, err = client.NewDELETEwithBodyAndToken(url, body, content); err != nil {
 		return nil, err
-	} else if v == nil {
+	}
+
+	h.prepareRequest(r)
+
+	if v == nil {
 		// run without decode
 		return r.RunWithoutDecode()
 	} else {
@@ -0,0 +1,236 @@
+package apiclient
+
+import (
+	"encoding/json"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+var ErrCircuitOpen = errors.New("back-end is unavailable: circuit breaker is open")
+
+// Status codes retried when RequestPolicy.Statuses is empty.
+// 504 is not part of it: request may have reached the back-end.
+var DefaultRetryStatuses = []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable}
+
+// Status codes telling that the back-end did not process the request.
+// Requests that cannot be replayed safely are only retried on these.
+var unprocessedStatuses = []int{http.StatusTooManyRequests, http.StatusServiceUnavailable}
+
+// How requests are sent: retries, deadline and circuit breaker.
+// Zero value sends every request once, without deadline.
+type RequestPolicy struct {
+	// max number of attempts, including the first one
+	Attempts int `json:"attempts,omitempty" mapstructure:"attempts"`
+	// delay before first retry, in milliseconds. Delay is doubled on every retry
+	Backoff int `json:"backoff,omitempty" mapstructure:"backoff"`
+	// max delay between two attempts, in milliseconds. 0 means no limit
+	MaxBackoff int `json:"maxbackoff,omitempty" mapstructure:"maxbackoff"`
+	// random part of the delay, between 0 and 1. 0.2 means delay +/- 20%
+	Jitter float64 `json:"jitter,omitempty" mapstructure:"jitter"`
+	// HTTP status codes that are retried. Requests that are not idempotent are only retried
+	// on 429 and 503, and on connection errors when they got no response
+	Statuses []int `json:"statuses,omitempty" mapstructure:"statuses"`
+	// max duration of a request, retries included, in seconds
+	Timeout int `json:"timeout,omitempty" mapstructure:"timeout"`
+	// max duration of connection establishment, in seconds. Default is 10
+	DialTimeout uint          `json:"dialtimeout,omitempty" mapstructure:"dialtimeout"`
+	Breaker     BreakerPolicy `json:"breaker,omitempty" mapstructure:"breaker"`
+}
+
+// Circuit breaker: after Threshold consecutive failures (no response or 5xx status code),
+// requests to the same host fail immediately with ErrCircuitOpen for Cooldown seconds.
+// Then one request is let through: breaker closes if it succeeds and opens again otherwise.
+// Threshold 0 disables the breaker.
+// Breaker state lives in process memory unless SetBreakerStateDir is called.
+type BreakerPolicy struct {
+	Threshold int `json:"threshold,omitempty" mapstructure:"threshold"`
+	Cooldown  int `json:"cooldown,omitempty" mapstructure:"cooldown"`
+}
+
+func (p RequestPolicy) attempts() int {
+	return max(p.Attempts, 1)
+}
+
+// Tell whether a failed attempt is sent again.
+// A request that is not idempotent (POST without idempotency key) may have been run by the back-end
+// when it gets no response or a 502: it is only retried when it surely did not reach the back-end.
+func (p RequestPolicy) isRetryable(status int, err error, idempotent bool) bool {
+	if status == 0 {
+		return idempotent || isDialError(err)
+	} else if !idempotent && !slices.Contains(unprocessedStatuses, status) {
+		return false
+	} else if len(p.Statuses) == 0 {
+		return slices.Contains(DefaultRetryStatuses, status)
+	}
+	return slices.Contains(p.Statuses, status)
+}
+
+// Tell whether error happened while connecting (connection refused, dial timeout, name resolution),
+// i.e. before request was sent
+func isDialError(err error) bool {
+	var op_err *net.OpError
+	return errors.As(err, &op_err) && op_err.Op == "dial"
+}
+
+// Delay before given retry (1 is the first one)
+func (p RequestPolicy) delay(retry int) time.Duration {
+	d := time.Duration(p.Backoff) * time.Millisecond
+	for i := 1; i < retry && (p.MaxBackoff == 0 || d < time.Duration(p.MaxBackoff)*time.Millisecond); i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 {
+		d = min(d, time.Duration(p.MaxBackoff)*time.Millisecond)
+	}
+	if p.Jitter > 0 {
+		d += time.Duration((rand.Float64()*2 - 1) * p.Jitter * float64(d))
+	}
+	return max(d, 0)
+}
+
+type circuitBreaker struct {
+	mutex     sync.Mutex
+	policy    BreakerPolicy
+	failures  int
+	openUntil time.Time
+	now       func() time.Time
+	// file the state is shared through, if any
+	filename string
+}
+
+// Breaker state as written in state file
+type breakerState struct {
+	Failures  int       `json:"failures"`
+	OpenUntil time.Time `json:"open_until"`
+}
+
+var (
+	breakers      = map[string]*circuitBreaker{}
+	breakersMutex sync.Mutex
+	// directory where breaker state is kept. Empty keeps it in memory
+	breakerStateDir string
+)
+
+// Keep breaker state in a file of dir, so processes that send requests to the same host share it
+// (e.g. mediator-client, which runs once per trigger). Must be called before requests are created.
+// Directory is created if needed.
+func SetBreakerStateDir(dir string) error {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return err
+		}
+	}
+	breakersMutex.Lock()
+	defer breakersMutex.Unlock()
+	breakerStateDir = dir
+	return nil
+}
+
+// Name of the file breaker state of host is kept in. It is hidden and has no .json extension
+// so it is not mistaken for a spooled request.
+func breakerStateFile(dir string, host string) string {
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, ".breaker-"+strings.NewReplacer(":", "_", "/", "_").Replace(host))
+}
+
+// Return the breaker of given host. Breakers are shared by all clients of the process.
+func getBreaker(host string, p BreakerPolicy) *circuitBreaker {
+	if p.Threshold <= 0 {
+		return nil
+	}
+	breakersMutex.Lock()
+	defer breakersMutex.Unlock()
+
+	if b, ok := breakers[host]; ok {
+		b.mutex.Lock()
+		b.policy = p
+		b.filename = breakerStateFile(breakerStateDir, host)
+		b.mutex.Unlock()
+		return b
+	}
+	b := &circuitBreaker{policy: p, now: time.Now, filename: breakerStateFile(breakerStateDir, host)}
+	breakers[host] = b
+	return b
+}
+
+// Run update on breaker state.
+// With a state file, state is read before and written after update while file is locked.
+// If state file cannot be used, breaker falls back to its in-memory state: requests must not fail
+// because of it.
+func (b *circuitBreaker) update(update func()) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.filename == "" {
+		update()
+		return
+	}
+
+	file, err := os.OpenFile(b.filename, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		update()
+		return
+	}
+	defer file.Close()
+	if err := unix.Flock(int(file.Fd()), unix.LOCK_EX); err != nil {
+		update()
+		return
+	}
+	defer unix.Flock(int(file.Fd()), unix.LOCK_UN)
+
+	var state breakerState
+	if err := json.NewDecoder(file).Decode(&state); err == nil {
+		b.failures, b.openUntil = state.Failures, state.OpenUntil
+	}
+	update()
+
+	if data, err := json.Marshal(breakerState{Failures: b.failures, OpenUntil: b.openUntil}); err == nil && file.Truncate(0) == nil {
+		file.WriteAt(data, 0)
+	}
+}
+
+// Tell whether a request can be sent
+func (b *circuitBreaker) allow() bool {
+	if b == nil {
+		return true
+	}
+	allowed := true
+	b.update(func() {
+		if b.failures < b.policy.Threshold {
+			return
+		} else if b.now().Before(b.openUntil) {
+			allowed = false
+			return
+		}
+		// half-open: let this request through and keep others out until it ends
+		b.openUntil = b.now().Add(time.Duration(b.policy.Cooldown) * time.Second)
+	})
+	return allowed
+}
+
+// Record the outcome of a request
+func (b *circuitBreaker) record(success bool) {
+	if b == nil {
+		return
+	}
+	b.update(func() {
+		if success {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.failures >= b.policy.Threshold {
+			b.openUntil = b.now().Add(time.Duration(b.policy.Cooldown) * time.Second)
+		}
+	})
+}
@@ -0,0 +1,240 @@
+package apiclient
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Fake back-end answering with given status codes, in order. Last one is repeated.
+func statusServer(t *testing.T, statuses ...int) (*httptest.Server, *[]string) {
+	bodies := []string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		w.WriteHeader(statuses[min(len(bodies), len(statuses))-1])
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &bodies
+}
+
+func TestRequestPolicyRetries(t *testing.T) {
+	testCases := map[string]struct {
+		statuses []int
+		policy   RequestPolicy
+		key      string
+		attempts int
+		wantErr  bool
+	}{
+		"no policy":             {[]int{503, 204}, RequestPolicy{}, "k", 1, true},
+		"retry until success":   {[]int{503, 502, 204}, RequestPolicy{Attempts: 5}, "k", 3, false},
+		"max attempts":          {[]int{503}, RequestPolicy{Attempts: 3}, "k", 3, true},
+		"not retryable":         {[]int{400, 204}, RequestPolicy{Attempts: 3}, "k", 1, true},
+		"custom statuses":       {[]int{500, 204}, RequestPolicy{Attempts: 3, Statuses: []int{500}}, "k", 2, false},
+		"no key, unprocessed":   {[]int{429, 503, 204}, RequestPolicy{Attempts: 5}, "", 3, false},
+		"no key, bad gateway":   {[]int{502, 204}, RequestPolicy{Attempts: 3}, "", 1, true},
+		"no key, custom status": {[]int{500, 204}, RequestPolicy{Attempts: 3, Statuses: []int{500}}, "", 1, true},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			srv, bodies := statusServer(t, tc.statuses...)
+			c := NewClient(srv.URL, "", "", false)
+			c.SetRequestPolicy(tc.policy)
+			r, err := c.NewPOST("test", strings.NewReader(`{"a":1}`), "json")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.key != "" {
+				r.SetHeader(HeaderIdempotencyKey, tc.key)
+			}
+			if _, err := r.RunWithoutDecode(); (err != nil) != tc.wantErr {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if len(*bodies) != tc.attempts {
+				t.Errorf("expected %d attempts, got %d", tc.attempts, len(*bodies))
+			}
+			// body is sent again on every attempt
+			for _, b := range *bodies {
+				if b != `{"a":1}` {
+					t.Errorf("unexpected body: %q", b)
+				}
+			}
+		})
+	}
+}
+
+// A request that gets no response may have been run by the back-end:
+// it is only sent again if it is idempotent or if connection failed.
+func TestRequestPolicyNoResponse(t *testing.T) {
+	var attempts atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		conn.Close()
+	}))
+	t.Cleanup(srv.Close)
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	testCases := map[string]struct {
+		url      string
+		method   string
+		key      string
+		attempts int32
+	}{
+		"post":                {srv.URL, http.MethodPost, "", 1},
+		"post with key":       {srv.URL, http.MethodPost, "k", 3},
+		"get":                 {srv.URL, http.MethodGet, "", 3},
+		"post, no connection": {closed.URL, http.MethodPost, "", 0},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			attempts.Store(0)
+			c := NewClient(tc.url, "", "", false)
+			c.SetRequestPolicy(RequestPolicy{Attempts: 3, Backoff: 1})
+			var r *Request
+			if tc.method == http.MethodGet {
+				r, _ = c.NewGET("test", "json")
+			} else {
+				r, _ = c.NewPOST("test", strings.NewReader(`{"a":1}`), "json")
+			}
+			if tc.key != "" {
+				r.SetHeader(HeaderIdempotencyKey, tc.key)
+			}
+			if _, err := r.RunWithoutDecode(); err == nil {
+				t.Error("expected an error")
+			}
+			if got := attempts.Load(); got != tc.attempts {
+				t.Errorf("expected %d attempts, got %d", tc.attempts, got)
+			}
+		})
+	}
+}
+
+func TestIsDialError(t *testing.T) {
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	_, err := http.Get(closed.URL)
+	if !isDialError(err) {
+		t.Errorf("connection refused should be a dial error: %v", err)
+	}
+	if isDialError(io.ErrUnexpectedEOF) {
+		t.Error("unexpected EOF is not a dial error")
+	}
+}
+
+func TestRequestPolicyTimeout(t *testing.T) {
+	srv, bodies := statusServer(t, 503)
+	c := NewClient(srv.URL, "", "", false)
+	c.SetRequestPolicy(RequestPolicy{Attempts: 100, Backoff: 400, Timeout: 1})
+
+	r, _ := c.NewGET("test", "json")
+	start := time.Now()
+	if _, err := r.RunWithoutDecode(); err == nil {
+		t.Error("expected an error")
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("request lasted %s", d)
+	}
+	if len(*bodies) < 2 || len(*bodies) > 3 {
+		t.Errorf("unexpected number of attempts: %d", len(*bodies))
+	}
+}
+
+func TestRequestPolicyDelay(t *testing.T) {
+	p := RequestPolicy{Backoff: 100, MaxBackoff: 300}
+	for retry, want := range []time.Duration{100, 200, 300, 300} {
+		if got := p.delay(retry + 1); got != want*time.Millisecond {
+			t.Errorf("retry %d: expected %s, got %s", retry+1, want*time.Millisecond, got)
+		}
+	}
+	p.Jitter = 0.5
+	for range 100 {
+		if d := p.delay(1); d < 50*time.Millisecond || d > 150*time.Millisecond {
+			t.Fatalf("delay out of jitter range: %s", d)
+		}
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Now()
+	b := &circuitBreaker{policy: BreakerPolicy{Threshold: 2, Cooldown: 30}, now: func() time.Time { return now }}
+
+	b.record(false)
+	if !b.allow() {
+		t.Fatal("breaker should be closed after one failure")
+	}
+	b.record(false)
+	if b.allow() {
+		t.Fatal("breaker should be open after two failures")
+	}
+
+	// half-open: one request goes through
+	now = now.Add(31 * time.Second)
+	if !b.allow() {
+		t.Fatal("breaker should let one request through after cooldown")
+	} else if b.allow() {
+		t.Fatal("breaker should let only one request through")
+	}
+	b.record(false)
+	if b.allow() {
+		t.Fatal("breaker should open again after failure")
+	}
+
+	now = now.Add(31 * time.Second)
+	b.allow()
+	b.record(true)
+	if !b.allow() || !b.allow() {
+		t.Fatal("breaker should be closed after success")
+	}
+}
+
+// Breakers sharing a state file behave as one, as mediator-client runs do
+func TestCircuitBreakerStateFile(t *testing.T) {
+	now := time.Now()
+	filename := breakerStateFile(t.TempDir(), "mediator:8443")
+	newBreaker := func() *circuitBreaker {
+		return &circuitBreaker{policy: BreakerPolicy{Threshold: 2, Cooldown: 30}, now: func() time.Time { return now }, filename: filename}
+	}
+
+	newBreaker().record(false)
+	newBreaker().record(false)
+	if newBreaker().allow() {
+		t.Fatal("breaker should be open after two failures in two processes")
+	}
+
+	now = now.Add(31 * time.Second)
+	if !newBreaker().allow() {
+		t.Fatal("breaker should let one request through after cooldown")
+	} else if newBreaker().allow() {
+		t.Fatal("breaker should let only one request through")
+	}
+	newBreaker().record(true)
+	if !newBreaker().allow() {
+		t.Fatal("breaker should be closed after success")
+	}
+}
+
+func TestCircuitBreakerFailsFast(t *testing.T) {
+	srv, bodies := statusServer(t, 503)
+	c := NewClient(srv.URL, "", "", false)
+	c.SetRequestPolicy(RequestPolicy{Breaker: BreakerPolicy{Threshold: 2, Cooldown: 60}})
+
+	for i := range 3 {
+		r, _ := c.NewGET("test", "json")
+		_, err := r.RunWithoutDecode()
+		if i < 2 && (err == nil || errors.Is(err, ErrCircuitOpen)) {
+			t.Errorf("request %d: expected back-end error, got %v", i, err)
+		} else if i == 2 && !errors.Is(err, ErrCircuitOpen) {
+			t.Errorf("request %d: expected %v, got %v", i, ErrCircuitOpen, err)
+		}
+	}
+	if len(*bodies) != 2 {
+		t.Errorf("expected 2 requests to reach back-end, got %d", len(*bodies))
+	}
+}
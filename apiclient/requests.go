@@ -2,6 +2,7 @@ package apiclient
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"encoding/xml"
 	"errors"
@@ -10,8 +11,15 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 )
 
+// Header used to correlate client and server logs
+const HeaderRequestID = "X-Request-ID"
+
+// Header identifying a trigger event so a replayed request does not run the script twice
+const HeaderIdempotencyKey = "Idempotency-Key"
+
 type Request struct {
 	httpreq    *http.Request
 	response   *http.Response
@@ -19,6 +27,9 @@ type Request struct {
 	JsessionID string
 	client     *http.Client
 	content    string
+	signer     func(*http.Request, []byte) error
+	policy     RequestPolicy
+	breaker    *circuitBreaker
 }
 
 func (req *Request) AddCookie(cookie *http.Cookie) {
@@ -29,6 +40,26 @@ func (req *Request) SetHeader(key string, value string) {
 	req.httpreq.Header.Set(key, value)
 }
 
+// Set retries, deadline and circuit breaker used when request is run
+func (req *Request) SetPolicy(p RequestPolicy) {
+	req.policy = p
+	req.breaker = getBreaker(req.httpreq.URL.Host, p.Breaker)
+}
+
+func (req *Request) SetContext(ctx context.Context) {
+	req.httpreq = req.httpreq.WithContext(ctx)
+}
+
+// Return the request ID echoed by the server or the one that was sent
+func (req *Request) GetRequestID() string {
+	if req.response != nil {
+		if id := req.response.Header.Get(HeaderRequestID); id != "" {
+			return id
+		}
+	}
+	return req.httpreq.Header.Get(HeaderRequestID)
+}
+
 func (req *Request) GetAllHeaders() http.Header {
 	return req.httpreq.Header
 }
@@ -79,8 +110,67 @@ func (req *Request) Run(v any) error {
 	return nil
 }
 
+// Run request according to its policy: it is sent again on retryable failure,
+// until max number of attempts or request deadline is reached.
 func (req *Request) RunWithoutDecode() (io.Reader, error) {
+	if req.policy.Timeout > 0 {
+		ctx, cancel := context.WithTimeout(req.httpreq.Context(), time.Duration(req.policy.Timeout)*time.Second)
+		defer cancel()
+		req.httpreq = req.httpreq.WithContext(ctx)
+	}
+	if req.policy.attempts() > 1 {
+		// keep body so it can be sent again
+		if _, err := req.bufferBody(); err != nil {
+			return nil, fmt.Errorf("error while reading request %s body: %w", req.httpreq.URL, err)
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		if !req.breaker.allow() {
+			return nil, fmt.Errorf("%w: %s", ErrCircuitOpen, req.httpreq.URL.Host)
+		}
+		req.StatusCode = 0
+		if attempt > 1 && req.httpreq.GetBody != nil {
+			if body, err := req.httpreq.GetBody(); err != nil {
+				return nil, err
+			} else {
+				req.httpreq.Body = body
+			}
+		}
+
+		resp, err := req.run()
+		req.breaker.record(req.StatusCode != 0 && req.StatusCode < 500)
+		if err == nil || attempt >= req.policy.attempts() || !req.policy.isRetryable(req.StatusCode, err, req.isIdempotent()) {
+			return resp, err
+		}
+
+		select {
+		case <-time.After(req.policy.delay(attempt)):
+		case <-req.httpreq.Context().Done():
+			return resp, err
+		}
+	}
+}
+
+// Tell whether request can be sent twice without side effect
+func (req *Request) isIdempotent() bool {
+	switch req.httpreq.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return req.httpreq.Header.Get(HeaderIdempotencyKey) != ""
+}
+
+// Send request once
+func (req *Request) run() (io.Reader, error) {
 	var err error
+	if req.signer != nil {
+		if body, err := req.bufferBody(); err != nil {
+			return nil, fmt.Errorf("error while reading request %s body: %w", req.httpreq.URL, err)
+		} else if err := req.signer(req.httpreq, body); err != nil {
+			return nil, fmt.Errorf("error while signing request %s: %w", req.httpreq.URL, err)
+		}
+	}
 	req.response, err = req.client.Do(req.httpreq)
 	defer func() {
 		if req.response != nil && req.response.Body != nil {
@@ -112,6 +202,25 @@ func (req *Request) RunWithoutDecode() (io.Reader, error) {
 	}
 }
 
+// Read request body in memory so it can be signed and sent.
+// Body is replaced by an in-memory copy.
+func (req *Request) bufferBody() ([]byte, error) {
+	if req.httpreq.Body == nil || req.httpreq.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.httpreq.Body)
+	if err != nil {
+		return nil, err
+	}
+	req.httpreq.Body.Close()
+	req.httpreq.Body = io.NopCloser(bytes.NewReader(body))
+	req.httpreq.ContentLength = int64(len(body))
+	req.httpreq.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	return body, nil
+}
+
 func (req *Request) decodeError(resp_body []byte) error {
 	// let's see if body is meaningful
 	// can we unmarshall body in an error struct.
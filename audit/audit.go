@@ -0,0 +1,177 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// header used by mediator-cli to tell who is running the command
+	HeaderCaller = "X-Mediator-Caller"
+
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+const (
+	ActionRegister            = "register"
+	ActionUnregister          = "unregister"
+	ActionRefresh             = "refresh"
+	ActionSettingsUpload      = "settings-upload"
+	ActionTriggerCreate       = "securechange-trigger-create"
+	ActionTriggerDelete       = "securechange-trigger-delete"
+	ActionAuthenticationError = "authentication-failure"
+)
+
+// One line of the audit log
+type Entry struct {
+	Time    time.Time `json:"time"`
+	Action  string    `json:"action"`
+	Target  string    `json:"target,omitempty"`
+	Caller  string    `json:"caller,omitempty"`
+	IP      string    `json:"ip,omitempty"`
+	Before  any       `json:"before,omitempty"`
+	After   any       `json:"after,omitempty"`
+	Outcome string    `json:"outcome"`
+	Error   string    `json:"error,omitempty"`
+	// X-Request-ID of the request that triggered the action
+	RequestID string `json:"request_id,omitempty"`
+	// action was performed by a client and reported to the server: target, before and after states
+	// and outcome are declared by the client, not observed by the server. IP is the one of the client
+	Reported bool `json:"reported,omitempty"`
+}
+
+var (
+	mutex        sync.Mutex
+	logFile      *os.File
+	logFilename  string
+	knownActions = []string{
+		ActionRegister,
+		ActionUnregister,
+		ActionRefresh,
+		ActionSettingsUpload,
+		ActionTriggerCreate,
+		ActionTriggerDelete,
+		ActionAuthenticationError,
+	}
+)
+
+// Open audit log file. Entries are appended to the file.
+// Audit log is disabled if no filename is provided.
+func Init(filename string) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if logFile != nil {
+		logFile.Close()
+		logFile = nil
+	}
+	logFilename = filename
+	if filename == "" {
+		return ErrNoAuditFile
+	}
+
+	f, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0640)
+	if err != nil {
+		return fmt.Errorf("cannot open audit log '%s': %w", filename, err)
+	}
+	logFile = f
+	logrus.Infof("Audit log will be written in file '%s'", filename)
+	return nil
+}
+
+func Close() {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if logFile != nil {
+		logFile.Sync()
+		logFile.Close()
+		logFile = nil
+	}
+}
+
+// Create a new entry for an action requested through the API.
+// Caller identity and IP address are taken from the request.
+// Caller identity is declared by mediator-cli: only the IP address is checked by the server.
+func NewEntry(c echo.Context, action string) *Entry {
+	e := Entry{
+		Action: action,
+	}
+	if c != nil {
+		e.IP = c.RealIP()
+		e.Caller = c.Request().Header.Get(HeaderCaller)
+		e.RequestID = c.Response().Header().Get(echo.HeaderXRequestID)
+	}
+	return &e
+}
+
+// Set entry outcome according to provided error and write it to the audit log
+func (e *Entry) Save(err error) {
+	if err != nil {
+		e.Outcome = OutcomeFailure
+		if he, ok := err.(*echo.HTTPError); ok {
+			e.Error = fmt.Sprint(he.Message)
+		} else {
+			e.Error = err.Error()
+		}
+	} else if e.Outcome == "" {
+		e.Outcome = OutcomeSuccess
+	}
+	Record(e)
+}
+
+// Write an entry to the audit log.
+// Entry time is set if missing.
+func Record(e *Entry) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if logFile == nil {
+		return
+	}
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		logrus.Warningf("cannot encode audit entry for action %s on %s: %v", e.Action, e.Target, err)
+		return
+	}
+	line = append(line, '\n')
+	if _, err := logFile.Write(line); err != nil {
+		logrus.Warningf("cannot write audit entry for action %s on %s: %v", e.Action, e.Target, err)
+	}
+}
+
+// Readiness check: fails if audit log is configured but cannot be written
+func Check() error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if logFilename == "" {
+		return nil
+	} else if logFile == nil {
+		return ErrAuditNotOpen
+	} else if _, err := logFile.Stat(); err != nil {
+		return fmt.Errorf("%w: %v", ErrAuditNotOpen, err)
+	}
+	return nil
+}
+
+func IsKnownAction(action string) bool {
+	for _, a := range knownActions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,10 @@
+package audit
+
+import "errors"
+
+var (
+	ErrNoAuditFile      = errors.New("no audit log file: audit log is disabled")
+	ErrUnknownAction    = errors.New("unknown audit action")
+	ErrActionNotAllowed = errors.New("this action cannot be reported by clients")
+	ErrAuditNotOpen     = errors.New("audit log is configured but cannot be written")
+)
@@ -0,0 +1,84 @@
+package audit
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func AddAuditAPI(g *echo.Group) {
+	g.GET("/audit", GetAuditLog)
+	g.POST("/audit", ReportAction)
+}
+
+// Return audit entries matching query parameters:
+// action, target, caller, outcome, since and until (RFC3339) and limit
+func GetAuditLog(c echo.Context) error {
+	var (
+		f   Filter
+		err error
+	)
+	f.Action = c.QueryParam("action")
+	f.Target = c.QueryParam("target")
+	f.Caller = c.QueryParam("caller")
+	f.Outcome = c.QueryParam("outcome")
+
+	if s := c.QueryParam("since"); s != "" {
+		if f.Since, err = time.Parse(time.RFC3339, s); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("invalid 'since' parameter: %w", err))
+		}
+	}
+	if s := c.QueryParam("until"); s != "" {
+		if f.Until, err = time.Parse(time.RFC3339, s); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("invalid 'until' parameter: %w", err))
+		}
+	}
+	if s := c.QueryParam("limit"); s != "" {
+		if f.Limit, err = strconv.Atoi(s); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("invalid 'limit' parameter: %w", err))
+		}
+	}
+
+	if entries, err := Query(f); err != nil {
+		if errors.Is(err, ErrNoAuditFile) {
+			return echo.NewHTTPError(http.StatusNotFound, err)
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	} else {
+		return c.JSON(http.StatusOK, entries)
+	}
+}
+
+// Record an action performed by a client on its own.
+// mediator-cli manages Securechange API triggers directly with Securechange:
+// it reports what it did so the server audit log stays complete.
+// Such entries are marked as reported: the server did not observe the action.
+func ReportAction(c echo.Context) error {
+	var e Entry
+	if err := c.Bind(&e); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("error while processing parameters: %w", err))
+	}
+	if !IsKnownAction(e.Action) {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("%w: %s", ErrUnknownAction, e.Action))
+	}
+	if e.Action != ActionTriggerCreate && e.Action != ActionTriggerDelete {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("%w: %s", ErrActionNotAllowed, e.Action))
+	}
+	if e.Outcome != OutcomeSuccess && e.Outcome != OutcomeFailure {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("invalid outcome: '%s'", e.Outcome))
+	}
+
+	// never trust client for these
+	e.Reported = true
+	e.Time = time.Now()
+	e.IP = c.RealIP()
+	e.Caller = c.Request().Header.Get(HeaderCaller)
+	e.RequestID = c.Response().Header().Get(echo.HeaderXRequestID)
+
+	Record(&e)
+	return c.NoContent(http.StatusNoContent)
+}
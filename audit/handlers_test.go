@@ -0,0 +1,82 @@
+package audit
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestReportAction(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "audit.log")
+	if err := Init(filename); err != nil {
+		t.Fatal(err)
+	}
+	defer Close()
+
+	e := echo.New()
+	e.IPExtractor = echo.ExtractIPDirect()
+	body := `{"action":"securechange-trigger-create","target":"wf create","outcome":"success","reported":false,"ip":"1.2.3.4","time":"2020-01-01T00:00:00Z"}`
+	req := httptest.NewRequest(http.MethodPost, "/audit", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(HeaderCaller, "alice@host")
+	req.Header.Set(echo.HeaderXForwardedFor, "5.6.7.8")
+	req.RemoteAddr = "10.0.0.2:40000"
+	rec := httptest.NewRecorder()
+	if err := ReportAction(e.NewContext(req, rec)); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	entries, err := readEntries(f, Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("%d entries recorded, want 1", len(entries))
+	}
+	got := entries[0]
+	if !got.Reported {
+		t.Errorf("reported entry is not marked as reported")
+	}
+	if got.IP != "10.0.0.2" {
+		t.Errorf("IP = %s, want connection address 10.0.0.2", got.IP)
+	}
+	if got.Time.Year() == 2020 {
+		t.Errorf("time was set by client")
+	}
+	if got.Caller != "alice@host" {
+		t.Errorf("caller = %s", got.Caller)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	defer Close()
+
+	if err := Init(""); !errors.Is(err, ErrNoAuditFile) {
+		t.Fatalf("Init() error = %v", err)
+	} else if err := Check(); err != nil {
+		t.Errorf("Check() with disabled audit log = %v", err)
+	}
+
+	if err := Init(filepath.Join(t.TempDir(), "missing", "audit.log")); err == nil {
+		t.Fatalf("Init() with invalid path: no error")
+	} else if err := Check(); !errors.Is(err, ErrAuditNotOpen) {
+		t.Errorf("Check() with invalid path = %v", err)
+	}
+
+	if err := Init(filepath.Join(t.TempDir(), "audit.log")); err != nil {
+		t.Fatal(err)
+	} else if err := Check(); err != nil {
+		t.Errorf("Check() = %v", err)
+	}
+}
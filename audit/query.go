@@ -0,0 +1,90 @@
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+)
+
+// Criteria used to select audit entries.
+// Zero values match everything.
+type Filter struct {
+	Action  string
+	Target  string
+	Caller  string
+	Outcome string
+	Since   time.Time
+	Until   time.Time
+	// only keep the <Limit> most recent entries
+	Limit int
+}
+
+func (f Filter) match(e *Entry) bool {
+	if f.Action != "" && f.Action != e.Action {
+		return false
+	}
+	if f.Target != "" && !strings.Contains(e.Target, f.Target) {
+		return false
+	}
+	if f.Caller != "" && !strings.Contains(e.Caller, f.Caller) {
+		return false
+	}
+	if f.Outcome != "" && f.Outcome != e.Outcome {
+		return false
+	}
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && e.Time.After(f.Until) {
+		return false
+	}
+	return true
+}
+
+// Read audit log and return entries matching the filter, oldest first.
+func Query(f Filter) ([]*Entry, error) {
+	mutex.Lock()
+	filename := logFilename
+	mutex.Unlock()
+
+	if filename == "" {
+		return nil, ErrNoAuditFile
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return []*Entry{}, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	return readEntries(file, f)
+}
+
+func readEntries(r io.Reader, f Filter) ([]*Entry, error) {
+	entries := []*Entry{}
+	dec := json.NewDecoder(r)
+	for {
+		var e Entry
+		if err := dec.Decode(&e); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("cannot read audit log: %w", err)
+		}
+		if f.match(&e) {
+			entries = append(entries, &e)
+		}
+	}
+
+	if f.Limit > 0 && len(entries) > f.Limit {
+		entries = entries[len(entries)-f.Limit:]
+	}
+	return entries, nil
+}
@@ -0,0 +1,53 @@
+package audit
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const testLog = `{"time":"2024-05-01T10:00:00Z","action":"register","target":"trigger/run.sh","caller":"alice@host","outcome":"success"}
+{"time":"2024-05-01T11:00:00Z","action":"unregister","target":"trigger/run.sh","caller":"bob@host","outcome":"success"}
+{"time":"2024-05-01T12:00:00Z","action":"register","target":"interactive/ask.sh","caller":"alice@host","outcome":"failure","error":"invalid script"}
+{"time":"2024-05-01T13:00:00Z","action":"settings-upload","target":"Access Request","caller":"bob@host","outcome":"success"}
+`
+
+func TestReadEntries(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  Filter
+		targets []string
+	}{
+		{"no filter", Filter{}, []string{"trigger/run.sh", "trigger/run.sh", "interactive/ask.sh", "Access Request"}},
+		{"action", Filter{Action: ActionRegister}, []string{"trigger/run.sh", "interactive/ask.sh"}},
+		{"target", Filter{Target: "ask"}, []string{"interactive/ask.sh"}},
+		{"caller", Filter{Caller: "bob"}, []string{"trigger/run.sh", "Access Request"}},
+		{"outcome", Filter{Outcome: OutcomeFailure}, []string{"interactive/ask.sh"}},
+		{"since", Filter{Since: time.Date(2024, 5, 1, 11, 30, 0, 0, time.UTC)}, []string{"interactive/ask.sh", "Access Request"}},
+		{"until", Filter{Until: time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)}, []string{"trigger/run.sh"}},
+		{"limit keeps most recent", Filter{Limit: 1}, []string{"Access Request"}},
+		{"combined", Filter{Caller: "alice", Limit: 5}, []string{"trigger/run.sh", "interactive/ask.sh"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := readEntries(strings.NewReader(testLog), tt.filter)
+			if err != nil {
+				t.Fatalf("readEntries() error = %v", err)
+			}
+			if len(entries) != len(tt.targets) {
+				t.Fatalf("readEntries() returned %d entries, want %d", len(entries), len(tt.targets))
+			}
+			for i, e := range entries {
+				if e.Target != tt.targets[i] {
+					t.Errorf("entry %d: target = %s, want %s", i, e.Target, tt.targets[i])
+				}
+			}
+		})
+	}
+}
+
+func TestReadEntries_invalid(t *testing.T) {
+	if _, err := readEntries(strings.NewReader("not json"), Filter{}); err == nil {
+		t.Errorf("readEntries() expected an error")
+	}
+}
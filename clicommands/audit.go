@@ -0,0 +1,159 @@
+package clicommands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"os/user"
+	"strconv"
+	"time"
+
+	"mediator/audit"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	audit_action  string
+	audit_target  string
+	audit_caller  string
+	audit_outcome string
+	audit_since   string
+	audit_until   string
+	audit_limit   int
+	audit_details bool
+	audit_json    bool
+	AuditCmd      = &cobra.Command{
+		Use:   "audit",
+		Short: "Show the audit log of administrative actions",
+		Long: `Show who registered, unregistered or refreshed scripts, uploaded settings
+or managed Securechange API triggers, when and with which outcome.
+
+Entries are shown oldest first. Use flags to filter them.
+--since and --until accept a date (RFC3339, e.g. 2024-01-31T00:00:00Z) or a duration relative to now (e.g. 24h).`,
+		Args: cobra.ExactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v := url.Values{}
+			for key, value := range map[string]string{
+				"action":  audit_action,
+				"target":  audit_target,
+				"caller":  audit_caller,
+				"outcome": audit_outcome,
+			} {
+				if value != "" {
+					v.Add(key, value)
+				}
+			}
+			for key, value := range map[string]string{
+				"since": audit_since,
+				"until": audit_until,
+			} {
+				if value == "" {
+					continue
+				}
+				if t, err := parseAuditTime(value); err != nil {
+					return fmt.Errorf("invalid --%s flag: %w", key, err)
+				} else {
+					v.Add(key, t.Format(time.RFC3339))
+				}
+			}
+			if audit_limit > 0 {
+				v.Add("limit", strconv.Itoa(audit_limit))
+			}
+
+			endpoint := "audit"
+			if len(v) > 0 {
+				endpoint = fmt.Sprintf("%s?%s", endpoint, v.Encode())
+			}
+
+			entries := []*audit.Entry{}
+			if _, err := BackendClient.RunGETwithToken(endpoint, "json", &entries); err != nil {
+				return err
+			}
+
+			if audit_json {
+				enc := json.NewEncoder(os.Stdout)
+				for _, e := range entries {
+					if err := enc.Encode(e); err != nil {
+						return err
+					}
+				}
+				return nil
+			}
+
+			if len(entries) == 0 {
+				fmt.Println("No audit entry found.")
+				return nil
+			}
+			for _, e := range entries {
+				fmt.Printf("%s %-8s %s '%s' by %s (%s)", e.Time.Local().Format(time.RFC3339), e.Outcome, e.Action, e.Target, e.Caller, e.IP)
+				if e.Reported {
+					fmt.Print(" [reported by client]")
+				}
+				if e.Error != "" {
+					fmt.Printf(": %s", e.Error)
+				}
+				fmt.Println()
+				if audit_details {
+					printAuditState("before", e.Before)
+					printAuditState("after", e.After)
+				}
+			}
+			return nil
+		},
+	}
+)
+
+func init() {
+	AuditCmd.Flags().StringVar(&audit_action, "action", "", "Only show entries for this action. One of: register, unregister, refresh, settings-upload, securechange-trigger-create, securechange-trigger-delete, authentication-failure.")
+	AuditCmd.Flags().StringVar(&audit_target, "target", "", "Only show entries whose target (script, workflow or trigger) contains this string.")
+	AuditCmd.Flags().StringVar(&audit_caller, "caller", "", "Only show entries whose caller contains this string.")
+	AuditCmd.Flags().StringVar(&audit_outcome, "outcome", "", "Only show entries with this outcome: success or failure.")
+	AuditCmd.Flags().StringVar(&audit_since, "since", "", "Only show entries recorded after this date or duration.")
+	AuditCmd.Flags().StringVar(&audit_until, "until", "", "Only show entries recorded before this date or duration.")
+	AuditCmd.Flags().IntVarP(&audit_limit, "limit", "n", 50, "Maximum number of entries to show (most recent ones). 0 for no limit.")
+	AuditCmd.Flags().BoolVarP(&audit_details, "details", "d", false, "Show state before and after each action.")
+	AuditCmd.Flags().BoolVar(&audit_json, "json", false, "Print entries as JSON lines.")
+}
+
+func parseAuditTime(s string) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+func printAuditState(label string, state any) {
+	if state == nil {
+		return
+	}
+	if b, err := json.MarshalIndent(state, "    ", "  "); err == nil {
+		fmt.Printf("  - %s: %s\n", label, b)
+	}
+}
+
+// Identity sent to the back-end so it can be recorded in audit log:
+// current user and host names
+func GetCallerIdentity() string {
+	name := "unknown"
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	if host, err := os.Hostname(); err == nil {
+		name = fmt.Sprintf("%s@%s", name, host)
+	}
+	return name
+}
+
+// Report an action performed by the CLI on its own so it is recorded in the back-end audit log.
+// Actions performed through the back-end API are recorded by the back-end itself.
+func ReportAction(e *audit.Entry) error {
+	if jsoninput, err := json.Marshal(e); err != nil {
+		return err
+	} else if _, err := BackendClient.RunPOSTwithToken("audit", bytes.NewBuffer(jsoninput), "json", nil); err != nil {
+		return fmt.Errorf("cannot record %s in audit log: %w", e.Action, err)
+	}
+	return nil
+}
@@ -0,0 +1,241 @@
+package clicommands
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"text/template"
+	"time"
+
+	"mediator/mediatorscript"
+	"mediator/scworkflow"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	bundle        clientBundle
+	bundle_output string
+	ClientCmd     = &cobra.Command{
+		Use:   "client <sub-command>",
+		Short: "Prepare mediator-client deployment on Securechange",
+		// no back-end needed: override root command checks
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return nil
+		},
+	}
+	ClientBundleCmd = &cobra.Command{
+		Use:   "bundle",
+		Short: "Generate mediator-client helper scripts and configuration file as a tarball",
+		Long: `Generate one helper script for every Securechange trigger and every interactive script type,
+and mediator-client.yml configuration file. Helpers call mediator-client from the install path.
+
+With --symlinks, helpers are symlinks to mediator-client instead of scripts: mediator-client infers
+the trigger or interactive script from the name it is called with. Securechange API triggers must then
+be created with the same flag.
+
+Files are written in a gzipped tarball, with a SHA256SUMS file that can be checked with 'sha256sum -c'.
+Once extracted, all files of the bundle directory can be pushed to Securechange with 'tos scripts sc push'.`,
+		Args: cobra.ExactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			f, err := os.Create(bundle_output)
+			if err != nil {
+				return err
+			}
+			sums, err := bundle.write(f)
+			if err != nil {
+				f.Close()
+				os.Remove(bundle_output)
+				return err
+			} else if err := f.Close(); err != nil {
+				return err
+			}
+			fmt.Printf("Bundle written to %s\n%s", bundle_output, sums)
+			return nil
+		},
+	}
+)
+
+func init() {
+	ClientBundleCmd.Flags().StringVar(&bundle.InstallPath, "install-path", scworkflow.DEFAULT_CLIENT_INSTALL_PATH, "Directory where mediator-client and its helpers are installed on Securechange")
+	ClientBundleCmd.Flags().StringVar(&bundle.BackendURL, "backend-url", "", "URL mediator-client uses to reach back-end (required). Example: https://MEDIATOR_SERVER_HOST/v1/otp/mediatorscript")
+	ClientBundleCmd.Flags().BoolVar(&bundle.SSLSkipVerify, "ssl-skip-verify", false, "Make mediator-client skip SSL certificate verification (insecure)")
+	ClientBundleCmd.Flags().StringVar(&bundle.LogFile, "log-file", "/var/log/mediator-client.log", "mediator-client log file")
+	ClientBundleCmd.Flags().StringVar(&bundle.SpoolDir, "spool-dir", "", "Directory where mediator-client keeps trigger requests when back-end is unavailable. Empty disables spool")
+	ClientBundleCmd.Flags().BoolVar(&bundle.Symlinks, "symlinks", false, "Generate symlinks to mediator-client instead of helper scripts")
+	ClientBundleCmd.Flags().StringVar(&bundle.ClientBinary, "client", "", "Path to mediator-client executable to add to the bundle")
+	ClientBundleCmd.Flags().StringVarP(&bundle_output, "output", "o", "mediator-client-bundle.tar.gz", "Path of generated tarball")
+	ClientBundleCmd.MarkFlagRequired("backend-url")
+
+	ClientCmd.AddCommand(ClientBundleCmd)
+}
+
+// Top directory of bundle archive
+const BUNDLE_DIR = "mediator-client-bundle"
+
+// Name of the checksum file of bundle archive. It can be checked with 'sha256sum -c'
+const BUNDLE_CHECKSUMS = "SHA256SUMS"
+
+// mediator-client path is quoted: install path may contain spaces or shell special characters
+var helperTemplate = template.Must(template.New("helper").Funcs(template.FuncMap{"shellquote": shellQuote}).Parse(`#!/bin/bash
+{{shellquote .Client}} {{.Args}} "$1"
+`))
+
+// Quote s for POSIX shells: it is enclosed in single quotes and its own single quotes are escaped
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+var clientConfigurationTemplate = template.Must(template.New("mediator-client.yml").Parse(`# Generated by 'mediator-cli client bundle'. See mediator-client_dist.yml for all options.
+configuration:
+  backend_url: {{printf "%q" .BackendURL}}
+  ssl_skip_verify: {{.SSLSkipVerify}}
+  log:
+    file: {{printf "%q" .LogFile}}
+    level: info
+    format: text
+  # directory where trigger requests are kept when mediator-server is unavailable. Empty disables spool
+  spool_dir: {{printf "%q" .SpoolDir}}
+  requests:
+    attempts: 3
+    backoff: 500
+    maxbackoff: 5000
+    jitter: 0.2
+    timeout: 60
+    dialtimeout: 10
+    breaker:
+      threshold: 3
+      cooldown: 30
+`))
+
+// What 'client bundle' generates
+type clientBundle struct {
+	// directory where mediator-client and its helpers are installed on Securechange
+	InstallPath   string
+	BackendURL    string
+	SSLSkipVerify bool
+	LogFile       string
+	SpoolDir      string
+	// path of mediator-client executable to add to the bundle. Not added if empty
+	ClientBinary string
+	// helpers are symlinks to mediator-client instead of scripts
+	Symlinks bool
+}
+
+type bundleFile struct {
+	name    string
+	mode    int64
+	content []byte
+	// target of symlink. File is a regular file if empty
+	link string
+}
+
+// Interactive script types that have a helper script, with the mediator-client flag they use
+var interactiveHelperTypes = []mediatorscript.ScriptType{
+	mediatorscript.ScriptCondition,
+	mediatorscript.ScriptTask,
+	mediatorscript.ScriptAssignment,
+	mediatorscript.RiskAnalysis,
+}
+
+// Return all files of the bundle: one helper per trigger and per interactive script type,
+// mediator-client configuration and executable
+func (b clientBundle) files() ([]bundleFile, error) {
+	files := []bundleFile{}
+	client := path.Join(b.InstallPath, "mediator-client")
+
+	add_helper := func(name, args string) error {
+		if b.Symlinks {
+			// relative link: mediator-client is in the same directory
+			files = append(files, bundleFile{name: name, mode: 0777, link: "mediator-client"})
+			return nil
+		}
+		name += ".sh"
+		var buffer bytes.Buffer
+		if err := helperTemplate.Execute(&buffer, map[string]string{"Client": client, "Args": args}); err != nil {
+			return err
+		}
+		files = append(files, bundleFile{name: name, mode: 0755, content: buffer.Bytes()})
+		return nil
+	}
+	for i := 1; i < int(scworkflow.LAST_TRIGGER); i++ {
+		t := scworkflow.SecurechangeTrigger(i)
+		if err := add_helper(t.HelperName(), fmt.Sprintf("--trigger %q", t.String())); err != nil {
+			return nil, err
+		}
+	}
+	for _, st := range interactiveHelperTypes {
+		if err := add_helper(st.HelperName(), "--"+st.Slug()); err != nil {
+			return nil, err
+		}
+	}
+
+	var buffer bytes.Buffer
+	if err := clientConfigurationTemplate.Execute(&buffer, b); err != nil {
+		return nil, err
+	}
+	files = append(files, bundleFile{name: "mediator-client.yml", mode: 0644, content: buffer.Bytes()})
+
+	if b.ClientBinary != "" {
+		if content, err := os.ReadFile(b.ClientBinary); err != nil {
+			return nil, fmt.Errorf("cannot read mediator-client executable: %w", err)
+		} else {
+			files = append(files, bundleFile{name: "mediator-client", mode: 0755, content: content})
+		}
+	}
+	return files, nil
+}
+
+// Write bundle as a gzipped tarball, with a checksum file. Symlinks have no checksum.
+// Returns the content of the checksum file.
+func (b clientBundle) write(w io.Writer) (string, error) {
+	files, err := b.files()
+	if err != nil {
+		return "", err
+	}
+	var sums bytes.Buffer
+	for _, f := range files {
+		if f.link != "" {
+			continue
+		}
+		sum := sha256.Sum256(f.content)
+		fmt.Fprintf(&sums, "%s  %s\n", hex.EncodeToString(sum[:]), f.name)
+	}
+	files = append(files, bundleFile{name: BUNDLE_CHECKSUMS, mode: 0644, content: sums.Bytes()})
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	now := time.Now().Truncate(time.Second)
+	if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: BUNDLE_DIR + "/", Mode: 0755, ModTime: now}); err != nil {
+		return "", err
+	}
+	for _, f := range files {
+		header := tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     path.Join(BUNDLE_DIR, f.name),
+			Mode:     f.mode,
+			Size:     int64(len(f.content)),
+			ModTime:  now,
+		}
+		if f.link != "" {
+			header.Typeflag = tar.TypeSymlink
+			header.Linkname = f.link
+		}
+		if err := tw.WriteHeader(&header); err != nil {
+			return "", err
+		} else if _, err := tw.Write(f.content); err != nil {
+			return "", err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return "", err
+	}
+	return sums.String(), gz.Close()
+}
@@ -0,0 +1,247 @@
+package clicommands
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mediator/configparser"
+	"mediator/mediatorscript"
+	"mediator/scworkflow"
+)
+
+// Read bundle tarball: file name => content and mode, symlink name => target
+func readBundle(t *testing.T, r io.Reader) (map[string][]byte, map[string]int64, map[string]string) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+	files := map[string][]byte{}
+	modes := map[string]int64{}
+	links := map[string]string{}
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if header.Typeflag == tar.TypeDir {
+			continue
+		}
+		name, found := strings.CutPrefix(header.Name, BUNDLE_DIR+"/")
+		if !found {
+			t.Errorf("file %s is not in %s directory", header.Name, BUNDLE_DIR)
+		}
+		if header.Typeflag == tar.TypeSymlink {
+			links[name] = header.Linkname
+			continue
+		}
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[name] = content
+		modes[name] = header.Mode
+	}
+	return files, modes, links
+}
+
+func TestClientBundle_write(t *testing.T) {
+	client_binary := filepath.Join(t.TempDir(), "mediator-client")
+	if err := os.WriteFile(client_binary, []byte("ELF"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		b          clientBundle
+		wantClient bool
+		wantErr    bool
+	}{
+		{
+			name: "default install path",
+			b: clientBundle{
+				InstallPath: scworkflow.DEFAULT_CLIENT_INSTALL_PATH,
+				BackendURL:  "https://mediator.example.com/v1/otp/mediatorscript",
+				LogFile:     "/var/log/mediator-client.log",
+			},
+		},
+		{
+			name: "custom install path with executable",
+			b: clientBundle{
+				InstallPath:   "/opt/custom/scripts",
+				BackendURL:    "https://mediator.example.com/v1/otp/mediatorscript",
+				SSLSkipVerify: true,
+				LogFile:       "/var/log/mediator-client.log",
+				SpoolDir:      "/opt/custom/scripts/mediator-spool",
+				ClientBinary:  client_binary,
+			},
+			wantClient: true,
+		},
+		{
+			name: "symlinks",
+			b: clientBundle{
+				InstallPath:  scworkflow.DEFAULT_CLIENT_INSTALL_PATH,
+				BackendURL:   "https://mediator.example.com/v1/otp/mediatorscript",
+				LogFile:      "/var/log/mediator-client.log",
+				ClientBinary: client_binary,
+				Symlinks:     true,
+			},
+			wantClient: true,
+		},
+		{
+			name: "install path with space",
+			b: clientBundle{
+				InstallPath: "/opt/my scripts",
+				BackendURL:  "https://mediator.example.com/v1/otp/mediatorscript",
+				LogFile:     "/var/log/mediator-client.log",
+			},
+		},
+		{
+			name: "missing executable",
+			b: clientBundle{
+				InstallPath:  scworkflow.DEFAULT_CLIENT_INSTALL_PATH,
+				BackendURL:   "https://mediator.example.com/v1/otp/mediatorscript",
+				ClientBinary: filepath.Join(t.TempDir(), "missing"),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buffer bytes.Buffer
+			sums, err := tt.b.write(&buffer)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("clientBundle.write() error = %v, wantErr %v", err, tt.wantErr)
+			} else if err != nil {
+				return
+			}
+			files, modes, links := readBundle(t, &buffer)
+
+			if string(files[BUNDLE_CHECKSUMS]) != sums {
+				t.Errorf("%s does not match returned checksums", BUNDLE_CHECKSUMS)
+			}
+			nb_sums := 0
+			for line := range strings.Lines(sums) {
+				nb_sums++
+				sum, name, _ := strings.Cut(strings.TrimSpace(line), "  ")
+				content, ok := files[name]
+				if !ok {
+					t.Errorf("%s is in checksums but not in bundle", name)
+					continue
+				}
+				got := sha256.Sum256(content)
+				if hex.EncodeToString(got[:]) != sum {
+					t.Errorf("checksum of %s does not match", name)
+				}
+			}
+			if nb_sums != len(files)-1 {
+				t.Errorf("%d checksums for %d files", nb_sums, len(files)-1)
+			}
+
+			client := filepath.Join(tt.b.InstallPath, "mediator-client")
+			helpers := map[string]string{}
+			for i := 1; i < int(scworkflow.LAST_TRIGGER); i++ {
+				tr := scworkflow.SecurechangeTrigger(i)
+				helpers[tr.HelperName()] = fmt.Sprintf("--trigger %q", tr)
+			}
+			for _, st := range []mediatorscript.ScriptType{mediatorscript.ScriptCondition, mediatorscript.ScriptTask, mediatorscript.ScriptAssignment, mediatorscript.RiskAnalysis} {
+				helpers[st.HelperName()] = "--" + st.Slug()
+			}
+			for name, args := range helpers {
+				if tt.b.Symlinks {
+					if links[name] != "mediator-client" {
+						t.Errorf("symlink %s targets '%s'", name, links[name])
+					} else if _, ok := files[name+".sh"]; ok {
+						t.Errorf("bundle has both symlink and helper script %s", name)
+					}
+					continue
+				}
+				want := fmt.Sprintf("#!/bin/bash\n%s %s \"$1\"\n", shellQuote(client), args)
+				if got := string(files[name+".sh"]); got != want {
+					t.Errorf("helper %s = %q, want %q", name, got, want)
+				}
+				if modes[name+".sh"] != 0755 {
+					t.Errorf("helper %s is not executable", name)
+				}
+			}
+			if !tt.b.Symlinks && len(links) > 0 {
+				t.Errorf("bundle has symlinks: %v", links)
+			}
+			if _, ok := files["mediator-client"]; ok != tt.wantClient {
+				t.Errorf("bundle has mediator-client executable: %v, want %v", ok, tt.wantClient)
+			}
+
+			// generated configuration must be readable by mediator-client
+			conf_file := filepath.Join(t.TempDir(), "mediator-client.yml")
+			if err := os.WriteFile(conf_file, files["mediator-client.yml"], 0644); err != nil {
+				t.Fatal(err)
+			}
+			conf := mediatorscript.MediatorLegacyConfiguration{}
+			if err := configparser.ReadConfAbsolutePath(conf_file, &conf, nil); err != nil {
+				t.Fatal(err)
+			}
+			if conf.Configuration.BackendURL != tt.b.BackendURL ||
+				conf.Configuration.SSLSkipVerify != tt.b.SSLSkipVerify ||
+				conf.Configuration.Log.File != tt.b.LogFile ||
+				conf.Configuration.SpoolDir != tt.b.SpoolDir {
+				t.Errorf("configuration = %+v, want %+v", conf.Configuration, tt.b)
+			}
+			if conf.Configuration.Requests.Attempts != 3 {
+				t.Errorf("configuration requests = %+v", conf.Configuration.Requests)
+			}
+		})
+	}
+}
+
+// Helper scripts run mediator-client even if install path has spaces or quotes
+func TestClientBundle_helperQuoting(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not found")
+	}
+	install_path := filepath.Join(t.TempDir(), "my scripts", "it's here")
+	if err := os.MkdirAll(install_path, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// fake mediator-client prints its arguments
+	if err := os.WriteFile(filepath.Join(install_path, "mediator-client"), []byte("#!/bin/sh\nprintf '%s|' \"$@\"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	b := clientBundle{InstallPath: install_path, BackendURL: "https://mediator.example.com/v1/otp/mediatorscript"}
+	files, err := b.files()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		if f.name != scworkflow.ADVANCE.HelperScriptName() {
+			continue
+		}
+		helper := filepath.Join(install_path, f.name)
+		if err := os.WriteFile(helper, f.content, 0755); err != nil {
+			t.Fatal(err)
+		}
+		out, err := exec.Command(bash, helper, "workflow name").CombinedOutput()
+		if err != nil {
+			t.Fatalf("helper failed: %v: %s", err, out)
+		}
+		if want := "--trigger|Advance|workflow name|"; string(out) != want {
+			t.Errorf("mediator-client arguments = %q, want %q", out, want)
+		}
+		return
+	}
+	t.Fatalf("no %s helper in bundle", scworkflow.ADVANCE.HelperScriptName())
+}
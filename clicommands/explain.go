@@ -0,0 +1,104 @@
+package clicommands
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+
+	"mediator/mediatorscript"
+	"mediator/mediatorsettings"
+	"mediator/scworkflow"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	explain_workflow string
+	explain_trigger  string
+	explain_ticket   string
+	explain_settings string
+	explain_json     bool
+	ExplainCmd       = &cobra.Command{
+		Use:   "explain",
+		Short: "Show which scripts would be run for a ticket, without running them",
+		Long: `Show which rules of a workflow match a ticket and a trigger, and why.
+
+Ticket step is read from the ticket XML the same way back-end does when a trigger fires:
+from completion_data if present, from current_stage otherwise.
+Nothing is run.
+
+Rules are the ones back-end uses unless a local settings file is provided with --settings.`,
+		Args: cobra.ExactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := os.ReadFile(explain_ticket)
+			if err != nil {
+				return err
+			}
+
+			var e *mediatorsettings.Explanation
+			if explain_settings != "" {
+				e, err = explainLocally(explain_settings, data)
+			} else {
+				e, err = explainOnBackend(data)
+			}
+			if err != nil {
+				return err
+			}
+
+			if explain_json {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(e)
+			}
+			e.Print(os.Stdout)
+			return nil
+		},
+	}
+)
+
+func init() {
+	ExplainCmd.Flags().StringVarP(&explain_workflow, "workflow", "w", "", "Workflow name (required)")
+	ExplainCmd.Flags().StringVarP(&explain_trigger, "trigger", "t", "", "Securechange trigger, e.g. Advance (required)")
+	ExplainCmd.Flags().StringVar(&explain_ticket, "ticket", "", "Path to ticket XML file, as received by mediator-client (required)")
+	ExplainCmd.Flags().StringVarP(&explain_settings, "settings", "s", "", "Path to local Mediator client settings file. Back-end settings are used if not provided.")
+	ExplainCmd.Flags().BoolVar(&explain_json, "json", false, "Print explanation as JSON.")
+	ExplainCmd.MarkFlagRequired("workflow")
+	ExplainCmd.MarkFlagRequired("trigger")
+	ExplainCmd.MarkFlagRequired("ticket")
+
+	MediatorSettingsCmd.AddCommand(ExplainCmd)
+}
+
+func explainLocally(filename string, data []byte) (*mediatorsettings.Explanation, error) {
+	var ti mediatorscript.TicketInfo
+	if err := xml.Unmarshal(data, &ti); err != nil {
+		return nil, fmt.Errorf("cannot parse ticket XML: %w", err)
+	}
+	settings, err := mediatorsettings.ReadWorkflowsSettings(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error while reading configuration file %s: %w", filename, err)
+	}
+	t := scworkflow.GetTriggerFromString(explain_trigger)
+	if t == scworkflow.NO_TRIGGER {
+		return nil, fmt.Errorf("%w: '%s'", mediatorsettings.ErrUnknownTrigger, explain_trigger)
+	}
+	return settings.Explain(explain_workflow, t, &ti)
+}
+
+func explainOnBackend(data []byte) (*mediatorsettings.Explanation, error) {
+	var e mediatorsettings.Explanation
+	body, err := json.Marshal(mediatorscript.BatchRequest{
+		Workflow:  explain_workflow,
+		Trigger:   explain_trigger,
+		TicketXML: string(data),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := BackendClient.RunPOSTwithToken("explain-trigger", bytes.NewReader(body), "json", &e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
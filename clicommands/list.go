@@ -16,7 +16,23 @@ func listScripts(script_type mediatorscript.ScriptType) error {
 	list_lines := []string{}
 	for _, s := range list {
 		if s.Type == script_type {
-			list_lines = append(list_lines, fmt.Sprintf("- %s: %s\n", s.Name, s.Fullpath))
+			line := fmt.Sprintf("- %s: %s", s.Name, s.Fullpath)
+			if s.Options.Feedback != mediatorscript.FEEDBACK_NONE {
+				line = fmt.Sprintf("%s (feedback: %s)", line, s.Options.Feedback)
+				if s.Options.FeedbackStderr {
+					line = fmt.Sprintf("%s (feedback stderr)", line)
+				}
+			}
+			if s.Options.Input != mediatorscript.INPUT_XML {
+				line = fmt.Sprintf("%s (input: %s)", line, s.Options.Input)
+			}
+			if s.Options.Enrich {
+				line = fmt.Sprintf("%s (enrich)", line)
+			}
+			if s.Options.Result != mediatorscript.RESULT_TEXT {
+				line = fmt.Sprintf("%s (result: %s)", line, s.Options.Result)
+			}
+			list_lines = append(list_lines, line+"\n")
 		}
 	}
 	fmt.Printf("Nb of %s: %d\n", script_type, len(list_lines))
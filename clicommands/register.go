@@ -13,7 +13,13 @@ import (
 
 // registerCmd represents the register command
 var (
-	name_flg string
+	name_flg     string
+	feedback_flg string
+	field_flg    string
+	stderr_flg   bool
+	result_flg   string
+	input_flg    string
+	enrich_flg   bool
 )
 
 // return a Cobra "register" sub-command for provided script type.
@@ -55,6 +61,17 @@ If the file changes, the script must be refreshed using the "refresh" command.`
 
 	// add --name flag
 	cmd.Flags().StringVarP(&name_flg, "name", "n", "", "Script name")
+	cmd.Flags().StringVar(&input_flg, "input", "xml", "Ticket data sent to script: 'xml' (on stdin), 'json' (on stdin) or 'both' (XML on stdin, JSON in a file named by MEDIATOR_TICKET_JSON environment variable)")
+	cmd.Flags().BoolVar(&enrich_flg, "enrich", false, "Fetch complete ticket from Securechange before running the script. It is written in a file named by MEDIATOR_TICKET_DETAILS environment variable. Requires Securechange API settings on back-end.")
+
+	if script_type == mediatorscript.ScriptCondition || script_type == mediatorscript.ScriptTask || script_type == mediatorscript.ScriptAssignment {
+		cmd.Flags().StringVar(&result_flg, "result", "text", "How script sends its result: 'text' (output and exit code are relayed to Securechange as is) or 'json' (see documentation)")
+	}
+	if script_type == mediatorscript.ScriptTrigger {
+		cmd.Flags().StringVar(&feedback_flg, "feedback", "", "Post script output (or its 'mediator:' block) and failures back to the ticket: 'comment' or 'field'. Requires Securechange API settings on back-end.")
+		cmd.Flags().StringVar(&field_flg, "feedback-field", "", "Name of the task text field updated when --feedback is 'field'")
+		cmd.Flags().BoolVar(&stderr_flg, "feedback-stderr", false, "Post the end of script stderr with failures. Only use it if stderr shows no paths, hosts or credentials")
+	}
 
 	return &cmd
 }
@@ -67,9 +84,28 @@ func registerScript(script_type mediatorscript.ScriptType, path string) error {
 	if fp, err := filepath.Abs(path); err != nil {
 		return err
 	} else {
+		// text result and XML input are the default ones
+		if result_flg == "text" {
+			result_flg = mediatorscript.RESULT_TEXT
+		}
+		if input_flg == "xml" {
+			input_flg = mediatorscript.INPUT_XML
+		}
+		// --feedback-field alone means field feedback
+		if field_flg != "" && feedback_flg == "" {
+			feedback_flg = mediatorscript.FEEDBACK_FIELD
+		}
 		s := mediatorscript.Script{
 			Fullpath: fp,
 			Type:     script_type,
+			Options: mediatorscript.ScriptOptions{
+				Feedback:       feedback_flg,
+				FeedbackField:  field_flg,
+				FeedbackStderr: stderr_flg,
+				Result:         result_flg,
+				Input:          input_flg,
+				Enrich:         enrich_flg,
+			},
 		}
 
 		// If no name has been provided via a flag, create a name
@@ -2,6 +2,7 @@ package securechangeapi
 
 import (
 	"fmt"
+	"mediator/audit"
 	"mediator/console"
 	"mediator/scworkflow"
 	"strings"
@@ -10,6 +11,10 @@ import (
 )
 
 var (
+	// where mediator-client helper scripts are installed on Securechange
+	install_path string
+	// mediator-client is called through symlinks instead of helper scripts
+	use_symlinks                     bool
 	MediatorSecurechangeAPICreateCmd = &cobra.Command{
 		Use:   "create",
 		Short: "Create a new SecurechangeAPI trigger configuration",
@@ -91,12 +96,17 @@ var (
 				wf_name := w.Name
 				for _, tg_name := range list_triggers {
 					// create data set
-					tg_slug := scworkflow.GetTriggerFromString(tg_name).Slug()
+					tg := scworkflow.GetTriggerFromString(tg_name)
+					tg_slug := tg.Slug()
 					new_trigger := scworkflow.WorkflowTrigger{}
 					new_trigger.Name = fmt.Sprintf("%s %s", wf_name, tg_slug)
 					new_trigger.Executer.Type = "ScriptDTO"
 					new_trigger.Executer.Arguments = wf_name
-					new_trigger.Executer.Path = fmt.Sprintf("/opt/tufin/data/securechange/scripts/mediator-client-%s.sh", strings.ReplaceAll(strings.ToLower(tg_name), " ", "-"))
+					if use_symlinks {
+						new_trigger.Executer.Path = tg.HelperLinkPath(install_path)
+					} else {
+						new_trigger.Executer.Path = tg.HelperScriptPath(install_path)
+					}
 
 					new_trigger_group := scworkflow.WorkflowTriggerGroup{}
 					new_trigger_group.Name = fmt.Sprintf("trigger %s", tg_name)
@@ -120,7 +130,13 @@ var (
 				return nil
 			}
 			// send creation request to SC
-			if err := Manager.CreateSecurechangeWorkflowTriggers(&New_wf_triggers); err != nil {
+			err = Manager.CreateSecurechangeWorkflowTriggers(&New_wf_triggers)
+			trigger_names := []string{}
+			for _, t := range New_wf_triggers.WorkflowTriggers.WorkflowTrigger {
+				trigger_names = append(trigger_names, t.Name)
+			}
+			reportTriggerAction(audit.ActionTriggerCreate, strings.Join(trigger_names, ","), nil, New_wf_triggers.WorkflowTriggers.WorkflowTrigger, err)
+			if err != nil {
 				return err
 			} else {
 				fmt.Printf("%d SecurechangeAPI triggers were created.\n", len(New_wf_triggers.WorkflowTriggers.WorkflowTrigger))
@@ -129,3 +145,8 @@ var (
 		},
 	}
 )
+
+func init() {
+	MediatorSecurechangeAPICreateCmd.Flags().StringVar(&install_path, "install-path", scworkflow.DEFAULT_CLIENT_INSTALL_PATH, "Directory where mediator-client helper scripts are installed on Securechange. Must match the one used by 'client bundle'.")
+	MediatorSecurechangeAPICreateCmd.Flags().BoolVar(&use_symlinks, "symlinks", false, "Call mediator-client through symlinks instead of helper scripts. Must match the one used by 'client bundle'.")
+}
@@ -2,6 +2,7 @@ package securechangeapi
 
 import (
 	"fmt"
+	"mediator/audit"
 	"mediator/console"
 	"mediator/scworkflow"
 	"sort"
@@ -143,7 +144,9 @@ Otherwise, you will be prompted to select the trigger you want to delete.`,
 			if confirmation {
 				var err error
 				for _, t := range triggers_to_delete {
-					if err = Manager.DeleteSecurechangeWorkflowTriggers(t.ID); err != nil {
+					err = Manager.DeleteSecurechangeWorkflowTriggers(t.ID)
+					reportTriggerAction(audit.ActionTriggerDelete, fmt.Sprintf("%s (#%d)", t.Name, t.ID), t, nil, err)
+					if err != nil {
 						fmt.Printf("ERROR: SecurechangeAPI trigger '%s' (id=%d) could not be deleted: %v\n", t.Name, t.ID, err)
 					} else {
 						fmt.Printf("SecurechangeAPI trigger '%s' (id=%d) was deleted.\n", t.Name, t.ID)
@@ -2,6 +2,8 @@ package securechangeapi
 
 import (
 	"fmt"
+	"mediator/audit"
+	"mediator/clicommands"
 	"mediator/console"
 	"mediator/scworkflow"
 	"slices"
@@ -90,3 +92,23 @@ func getWorkflowsFromFlag(cmd *cobra.Command) []*scworkflow.WorkflowXML {
 func isTriggerRelatedToWorkflowInList(l []*scworkflow.WorkflowXML, t *scworkflow.WorkflowTrigger) bool {
 	return t.IsTriggerRelatedToWorkflowInList(l)
 }
+
+// Record an action on Securechange API triggers in back-end audit log.
+// Failing to do so must not prevent the action itself so we only warn the user.
+func reportTriggerAction(action string, target string, before any, after any, err error) {
+	entry := &audit.Entry{
+		Action: action,
+		Target: target,
+		Before: before,
+		After:  after,
+	}
+	if err != nil {
+		entry.Outcome = audit.OutcomeFailure
+		entry.Error = err.Error()
+	} else {
+		entry.Outcome = audit.OutcomeSuccess
+	}
+	if err := clicommands.ReportAction(entry); err != nil {
+		fmt.Printf("WARNING: %v\n", err)
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"mediator/apiclient"
+	"mediator/audit"
 	"mediator/clicommands"
 	"mediator/clicommands/securechangeapi"
 	"os"
@@ -23,17 +24,23 @@ var (
 * Un-register useless scripts
 * Refresh script checksum
 * Test scripts
+* Show audit log
 `,
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 			if URL == "" {
 				return fmt.Errorf("provided Back-End URL is empty")
 			}
 			clicommands.BackendClient = apiclient.GetHelper(URL, InsecureSkipVerify)
+			clicommands.BackendClient.SetRequestPolicy(RequestPolicy)
+			clicommands.BackendClient.SetHeader(audit.HeaderCaller, clicommands.GetCallerIdentity())
 			return nil
 		},
 	}
 )
 
+// retries, deadline and circuit breaker of requests sent to back-end
+var RequestPolicy apiclient.RequestPolicy
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
@@ -45,10 +52,22 @@ func Execute() {
 
 func init() {
 	rootCmd.PersistentFlags().BoolVarP(&InsecureSkipVerify, "sslskipverify", "", false, "Skip SSL certificate verification (insecure)")
+	// not marked as required: 'client' commands do not use back-end. Checked by PersistentPreRunE
 	rootCmd.PersistentFlags().StringVarP(&URL, "url", "u", "", "Back-end URL (required)")
-	rootCmd.MarkPersistentFlagRequired("url")
+
+	rootCmd.PersistentFlags().IntVar(&RequestPolicy.Attempts, "attempts", 1, "Max number of attempts of every request, including the first one")
+	rootCmd.PersistentFlags().IntVar(&RequestPolicy.Backoff, "backoff", 500, "Delay before first retry, in milliseconds. Delay is doubled on every retry")
+	rootCmd.PersistentFlags().IntVar(&RequestPolicy.MaxBackoff, "max-backoff", 5000, "Max delay between two attempts, in milliseconds. 0 means no limit")
+	rootCmd.PersistentFlags().Float64Var(&RequestPolicy.Jitter, "jitter", 0.2, "Random part of the delay between two attempts, between 0 and 1")
+	rootCmd.PersistentFlags().IntSliceVar(&RequestPolicy.Statuses, "retry-status", apiclient.DefaultRetryStatuses, "HTTP status codes that are retried. POST requests are only retried on 429 and 503, and when connection fails")
+	rootCmd.PersistentFlags().IntVar(&RequestPolicy.Timeout, "timeout", 0, "Max duration of every request, retries included, in seconds. 0 means no limit")
+	rootCmd.PersistentFlags().UintVar(&RequestPolicy.DialTimeout, "dial-timeout", 10, "Max duration of connection establishment, in seconds")
+	rootCmd.PersistentFlags().IntVar(&RequestPolicy.Breaker.Threshold, "breaker-threshold", 0, "Number of consecutive failures after which back-end is considered down and requests fail immediately. 0 disables circuit breaker")
+	rootCmd.PersistentFlags().IntVar(&RequestPolicy.Breaker.Cooldown, "breaker-cooldown", 30, "How long requests fail immediately once back-end is considered down, in seconds")
 
 	rootCmd.AddCommand(clicommands.MediatorSettingsCmd)
 	rootCmd.AddCommand(securechangeapi.MediatorSecurechangeAPICmd)
 	rootCmd.AddCommand(clicommands.ScriptCmd)
+	rootCmd.AddCommand(clicommands.AuditCmd)
+	rootCmd.AddCommand(clicommands.ClientCmd)
 }
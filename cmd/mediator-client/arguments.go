@@ -1,5 +1,13 @@
 package main
 
+import (
+	"path/filepath"
+	"strings"
+
+	"mediator/mediatorscript"
+	"mediator/scworkflow"
+)
+
 type arguments struct {
 	positional        []string
 	data_filename     string
@@ -9,6 +17,10 @@ type arguments struct {
 	riskAnalysis      bool
 	trigger           string
 	settings_filename string
+	config_filename   string
+	flushSpool        bool
+	diagnose          bool
+	explain           bool
 }
 
 func (args arguments) NPositional() int {
@@ -40,3 +52,27 @@ func (args arguments) isUniqueInteractiveScriptFlag() error {
 	}
 	return nil
 }
+
+// Infer trigger or interactive script from the name mediator-client is called with,
+// so it can be installed as symlinks (e.g. mediator-client-advance) instead of helper scripts.
+// Flags take precedence: nothing is inferred if --trigger or an interactive flag is set.
+func (args *arguments) inferFromProgramName(program string) {
+	if args.trigger != "" || args.isInteractiveScript() {
+		return
+	}
+	name := strings.TrimSuffix(filepath.Base(program), ".sh")
+	if t := scworkflow.GetTriggerFromHelperName(name); t != scworkflow.NO_TRIGGER {
+		args.trigger = t.String()
+		return
+	}
+	for st, flag := range map[mediatorscript.ScriptType]*bool{
+		mediatorscript.ScriptCondition:  &args.scriptedCondition,
+		mediatorscript.ScriptAssignment: &args.preAssignment,
+		mediatorscript.ScriptTask:       &args.scriptedTask,
+		mediatorscript.RiskAnalysis:     &args.riskAnalysis,
+	} {
+		if name == st.HelperName() {
+			*flag = true
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+
+	"mediator/configparser"
+	"mediator/mediatorscript"
+)
+
+// Environment variable giving configuration file path when --config is not used
+const ENV_CONFIG = "MEDIATOR_CONFIG"
+
+// Environment variables overriding configuration file values
+var envOverrides = map[string]string{
+	"MEDIATOR_BACKEND_URL":     "configuration.backend_url",
+	"MEDIATOR_SSL_SKIP_VERIFY": "configuration.ssl_skip_verify",
+	"MEDIATOR_CA_FILE":         "configuration.ca_file",
+	"MEDIATOR_LOG_FILE":        "configuration.log.file",
+	"MEDIATOR_LOG_LEVEL":       "configuration.log.level",
+	"MEDIATOR_LOG_FORMAT":      "configuration.log.format",
+	"MEDIATOR_SPOOL_DIR":       "configuration.spool_dir",
+}
+
+// Path of configuration file. Precedence, highest first:
+// --config flag, MEDIATOR_CONFIG environment variable, mediator-client.yml in executable folder
+func configurationPath(args arguments, currPath string) string {
+	if args.config_filename != "" {
+		return args.config_filename
+	} else if p := os.Getenv(ENV_CONFIG); p != "" {
+		return p
+	}
+	return filepath.Join(currPath, "mediator-client.yml")
+}
+
+// Path of workflow settings file: relative names are resolved from configuration file folder
+func settingsPath(args arguments, conf_filename string) string {
+	if filepath.IsAbs(args.settings_filename) {
+		return args.settings_filename
+	}
+	return filepath.Join(filepath.Dir(conf_filename), args.settings_filename)
+}
+
+// Read configuration file. MEDIATOR_* environment variables override its values
+func readConfiguration(filename string, conf *mediatorscript.MediatorLegacyConfiguration) error {
+	return configparser.ReadConfAbsolutePathWithEnv(filename, conf, nil, envOverrides)
+}
+
+// Names of environment variables that are set and override configuration file values
+func activeEnvOverrides() []string {
+	names := []string{}
+	for variable := range envOverrides {
+		if os.Getenv(variable) != "" {
+			names = append(names, variable)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"mediator/mediatorscript"
+)
+
+func Test_configurationPath(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         arguments
+		env          string
+		want         string
+		wantSettings string
+	}{
+		{
+			name:         "default",
+			args:         arguments{settings_filename: "mediator-client.json"},
+			want:         "/opt/scripts/mediator-client.yml",
+			wantSettings: "/opt/scripts/mediator-client.json",
+		},
+		{
+			name:         "environment",
+			args:         arguments{settings_filename: "mediator-client.json"},
+			env:          "/etc/mediator/client.yml",
+			want:         "/etc/mediator/client.yml",
+			wantSettings: "/etc/mediator/mediator-client.json",
+		},
+		{
+			name:         "flag overrides environment",
+			args:         arguments{config_filename: "/tmp/test.yml", settings_filename: "mediator-client.json"},
+			env:          "/etc/mediator/client.yml",
+			want:         "/tmp/test.yml",
+			wantSettings: "/tmp/mediator-client.json",
+		},
+		{
+			name:         "absolute settings",
+			args:         arguments{config_filename: "/tmp/test.yml", settings_filename: "/var/lib/settings.json"},
+			want:         "/tmp/test.yml",
+			wantSettings: "/var/lib/settings.json",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(ENV_CONFIG, tt.env)
+			got := configurationPath(tt.args, "/opt/scripts")
+			if got != tt.want {
+				t.Errorf("configurationPath() = %s, want %s", got, tt.want)
+			}
+			if settings := settingsPath(tt.args, got); settings != tt.wantSettings {
+				t.Errorf("settingsPath() = %s, want %s", settings, tt.wantSettings)
+			}
+		})
+	}
+}
+
+func Test_readConfiguration(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "mediator-client.yml")
+	content := `configuration:
+  backend_url: https://file.example.com/v1/otp/mediatorscript
+  ssl_skip_verify: false
+  log:
+    file: /var/log/mediator-client.log
+    level: info
+`
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		env      map[string]string
+		want     mediatorscript.MediatorBasicConfiguration
+		wantVars []string
+	}{
+		{
+			name: "file only",
+			want: mediatorscript.MediatorBasicConfiguration{
+				BackendURL: "https://file.example.com/v1/otp/mediatorscript",
+				Log:        mediatorscript.MediatorLoggingConfiguration{File: "/var/log/mediator-client.log", Level: "info"},
+			},
+			wantVars: []string{},
+		},
+		{
+			name: "environment overrides file",
+			env: map[string]string{
+				"MEDIATOR_BACKEND_URL":     "https://env.example.com/v1/otp/mediatorscript",
+				"MEDIATOR_SSL_SKIP_VERIFY": "true",
+				"MEDIATOR_LOG_LEVEL":       "debug",
+				"MEDIATOR_LOG_FORMAT":      "json",
+				"MEDIATOR_SPOOL_DIR":       "/tmp/spool",
+				"MEDIATOR_CA_FILE":         "/etc/ssl/mediator-ca.pem",
+			},
+			want: mediatorscript.MediatorBasicConfiguration{
+				BackendURL:    "https://env.example.com/v1/otp/mediatorscript",
+				SSLSkipVerify: true,
+				Log:           mediatorscript.MediatorLoggingConfiguration{File: "/var/log/mediator-client.log", Level: "debug", Format: "json"},
+				SpoolDir:      "/tmp/spool",
+				CAFile:        "/etc/ssl/mediator-ca.pem",
+			},
+			wantVars: []string{"MEDIATOR_BACKEND_URL", "MEDIATOR_CA_FILE", "MEDIATOR_LOG_FORMAT", "MEDIATOR_LOG_LEVEL", "MEDIATOR_SPOOL_DIR", "MEDIATOR_SSL_SKIP_VERIFY"},
+		},
+		{
+			name: "empty variable is ignored",
+			env:  map[string]string{"MEDIATOR_LOG_FILE": ""},
+			want: mediatorscript.MediatorBasicConfiguration{
+				BackendURL: "https://file.example.com/v1/otp/mediatorscript",
+				Log:        mediatorscript.MediatorLoggingConfiguration{File: "/var/log/mediator-client.log", Level: "info"},
+			},
+			wantVars: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for variable := range envOverrides {
+				t.Setenv(variable, tt.env[variable])
+			}
+			var conf mediatorscript.MediatorLegacyConfiguration
+			if err := readConfiguration(filename, &conf); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(conf.Configuration, tt.want) {
+				t.Errorf("readConfiguration() = %+v, want %+v", conf.Configuration, tt.want)
+			}
+			if got := activeEnvOverrides(); !reflect.DeepEqual(got, tt.wantVars) {
+				t.Errorf("activeEnvOverrides() = %v, want %v", got, tt.wantVars)
+			}
+		})
+	}
+}
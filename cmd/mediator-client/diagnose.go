@@ -0,0 +1,345 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"mediator/apiclient"
+	"mediator/mediatorscript"
+	"mediator/mediatorsettings"
+	"mediator/scworkflow"
+)
+
+// Number of authenticated requests sent to measure back-end latency
+const DIAGNOSE_LATENCY_SAMPLES = 3
+
+type diagnosticStatus int
+
+const (
+	DIAGNOSTIC_PASS diagnosticStatus = iota
+	DIAGNOSTIC_WARN
+	DIAGNOSTIC_FAIL
+	// check was not run because a previous one failed
+	DIAGNOSTIC_SKIP
+)
+
+func (s diagnosticStatus) String() string {
+	switch s {
+	case DIAGNOSTIC_PASS:
+		return "PASS"
+	case DIAGNOSTIC_WARN:
+		return "WARN"
+	case DIAGNOSTIC_FAIL:
+		return "FAIL"
+	default:
+		return "SKIP"
+	}
+}
+
+// Result of one check of --diagnose mode
+type diagnostic struct {
+	name    string
+	status  diagnosticStatus
+	summary string
+	details []string
+}
+
+func (d *diagnostic) detail(format string, a ...any) {
+	d.details = append(d.details, fmt.Sprintf(format, a...))
+}
+
+func (d *diagnostic) set(status diagnosticStatus, format string, a ...any) *diagnostic {
+	d.status = status
+	d.summary = fmt.Sprintf(format, a...)
+	return d
+}
+
+type diagnostics []*diagnostic
+
+// Print report and return true if no check failed
+func (diags diagnostics) report(w io.Writer) bool {
+	count := map[diagnosticStatus]int{}
+	for _, d := range diags {
+		count[d.status]++
+		fmt.Fprintf(w, "[%s] %s: %s\n", d.status, d.name, d.summary)
+		for _, line := range d.details {
+			fmt.Fprintf(w, "       %s\n", line)
+		}
+	}
+	fmt.Fprintf(w, "\n%d passed, %d warning(s), %d failed, %d skipped\n", count[DIAGNOSTIC_PASS], count[DIAGNOSTIC_WARN], count[DIAGNOSTIC_FAIL], count[DIAGNOSTIC_SKIP])
+	if count[DIAGNOSTIC_FAIL] > 0 {
+		fmt.Fprintln(w, "Diagnostics FAILED")
+		return false
+	}
+	fmt.Fprintln(w, "Diagnostics PASSED")
+	return true
+}
+
+// Handle --diagnose flag: check configuration, settings and connection to back-end and print a report.
+// Exit code is 1 if a check failed.
+func diagnoseAndExit(conf_filename string, settings_filename string) {
+	diags := diagnostics{}
+	conf, d := diagnoseConfiguration(conf_filename)
+	diags = append(diags, d, diagnoseSettings(settings_filename))
+
+	if conf == nil {
+		for _, name := range []string{"Back-end URL", "TLS", "Authentication", "Latency"} {
+			diags = append(diags, (&diagnostic{name: name}).set(DIAGNOSTIC_SKIP, "configuration is invalid"))
+		}
+	} else {
+		u, d := diagnoseBackendURL(conf.Configuration.BackendURL)
+		diags = append(diags, d)
+		if u == nil {
+			for _, name := range []string{"TLS", "Authentication", "Latency"} {
+				diags = append(diags, (&diagnostic{name: name}).set(DIAGNOSTIC_SKIP, "back-end cannot be reached"))
+			}
+		} else {
+			diags = append(diags, diagnoseTLS(u, conf.Configuration.CAFile, conf.Configuration.SSLSkipVerify, dialTimeout(conf)))
+			auth, latency := diagnoseBackend(conf)
+			diags = append(diags, auth, latency)
+		}
+	}
+
+	if diags.report(os.Stdout) {
+		os.Exit(0)
+	}
+	os.Exit(1)
+}
+
+// Read and check configuration file. Returned configuration is nil if it cannot be used.
+func diagnoseConfiguration(filename string) (*mediatorscript.MediatorLegacyConfiguration, *diagnostic) {
+	d := &diagnostic{name: "Configuration"}
+	var conf mediatorscript.MediatorLegacyConfiguration
+	if err := readConfiguration(filename, &conf); err != nil {
+		return nil, d.set(DIAGNOSTIC_FAIL, "cannot read %s: %v", filename, err)
+	}
+	for _, variable := range activeEnvOverrides() {
+		d.detail("%s overrides %s", variable, envOverrides[variable])
+	}
+	c := conf.Configuration
+	if c.BackendURL == "" {
+		return nil, d.set(DIAGNOSTIC_FAIL, "backend_url is empty in %s", filename)
+	}
+	d.set(DIAGNOSTIC_PASS, "%s is valid", filename)
+	d.detail("back-end URL: %s", c.BackendURL)
+	d.detail("log file: %s (level %s)", c.Log.File, GetLogLevel(c.Log.Level))
+
+	if c.SSLSkipVerify {
+		d.set(DIAGNOSTIC_WARN, "ssl_skip_verify is set: connection to back-end is insecure")
+	}
+	if c.CAFile != "" {
+		if _, err := apiclient.LoadCAFile(c.CAFile); err != nil {
+			d.set(DIAGNOSTIC_FAIL, "certificate authorities cannot be read: %v", err)
+		} else {
+			d.detail("certificate authorities: system and %s", c.CAFile)
+		}
+	}
+	if c.SpoolDir == "" {
+		d.detail("spool is disabled: trigger requests are lost when back-end is unavailable")
+	} else if err := checkWritableDir(c.SpoolDir); err != nil {
+		d.set(DIAGNOSTIC_FAIL, "spool directory %s is not usable: %v", c.SpoolDir, err)
+	} else if files, err := (spool{c.SpoolDir}).list(); err == nil {
+		d.detail("spool directory: %s (%d request(s) waiting)", c.SpoolDir, len(files))
+	}
+	if c.Requests.Attempts > 1 {
+		d.detail("requests are sent up to %d times", c.Requests.Attempts)
+	}
+	return &conf, d
+}
+
+// Read settings file and list workflows and rules
+func diagnoseSettings(filename string) *diagnostic {
+	d := &diagnostic{name: "Settings"}
+	if _, err := os.Stat(filename); errors.Is(err, os.ErrNotExist) {
+		// only used in test mode
+		return d.set(DIAGNOSTIC_WARN, "%s does not exist: test mode cannot find workflow scripts", filename)
+	}
+	settings, err := mediatorsettings.ReadWorkflowsSettings(filename)
+	if err != nil {
+		return d.set(DIAGNOSTIC_FAIL, "%v", err)
+	}
+
+	invalid := 0
+	for name, wf := range settings {
+		d.detail("workflow '%s' (ID=%d): %d rule(s)", name, wf.WFid, len(wf.Rules))
+		for _, rule := range wf.Rules {
+			if rule == nil {
+				continue
+			}
+			trigger := scworkflow.GetTriggerFromString(rule.Trigger)
+			if trigger == scworkflow.NO_TRIGGER {
+				invalid++
+				d.detail("  - INVALID: unknown trigger '%s' for script '%s'", rule.Trigger, rule.Script)
+			} else if trigger.NeedStepToGetScript() && (rule.Step == nil || *rule.Step == "") {
+				invalid++
+				d.detail("  - INVALID: trigger '%s' requires a step for script '%s'", rule.Trigger, rule.Script)
+			} else {
+				d.detail("  - %s", rule)
+			}
+		}
+	}
+	if invalid > 0 {
+		return d.set(DIAGNOSTIC_FAIL, "%d invalid rule(s) in %s", invalid, filename)
+	} else if len(settings) == 0 {
+		return d.set(DIAGNOSTIC_WARN, "no workflow in %s", filename)
+	}
+	return d.set(DIAGNOSTIC_PASS, "%d workflow(s) loaded from %s", len(settings), filename)
+}
+
+// Parse back-end URL and resolve its host name. Returned URL is nil if back-end cannot be reached.
+func diagnoseBackendURL(backend_url string) (*url.URL, *diagnostic) {
+	d := &diagnostic{name: "Back-end URL"}
+	u, err := url.Parse(backend_url)
+	if err != nil {
+		return nil, d.set(DIAGNOSTIC_FAIL, "cannot parse %s: %v", backend_url, err)
+	} else if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, d.set(DIAGNOSTIC_FAIL, "unexpected scheme '%s' in %s: expected http or https", u.Scheme, backend_url)
+	}
+
+	addrs, err := net.LookupHost(u.Hostname())
+	if err != nil {
+		return nil, d.set(DIAGNOSTIC_FAIL, "cannot resolve %s: %v", u.Hostname(), err)
+	}
+	d.set(DIAGNOSTIC_PASS, "%s resolves to %s", u.Hostname(), strings.Join(addrs, ", "))
+	if u.Scheme == "http" {
+		d.set(DIAGNOSTIC_WARN, "%s resolves to %s but connection is not encrypted", u.Hostname(), strings.Join(addrs, ", "))
+	}
+	return u, d
+}
+
+// Connect to back-end, print its certificate chain and check it is trusted by system certificate authorities or those of ca_file
+func diagnoseTLS(u *url.URL, ca_file string, skip_verify bool, timeout time.Duration) *diagnostic {
+	d := &diagnostic{name: "TLS"}
+	if u.Scheme != "https" {
+		return d.set(DIAGNOSTIC_SKIP, "back-end URL is not https")
+	}
+	address := u.Host
+	if u.Port() == "" {
+		address = net.JoinHostPort(u.Hostname(), "443")
+	}
+
+	// certificates are checked below so chain can be printed even if it is not trusted
+	start := time.Now()
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: timeout}, "tcp", address, &tls.Config{InsecureSkipVerify: true, ServerName: u.Hostname()})
+	if err != nil {
+		return d.set(DIAGNOSTIC_FAIL, "cannot connect to %s: %v", address, err)
+	}
+	defer conn.Close()
+	handshake := time.Since(start)
+
+	state := conn.ConnectionState()
+	d.detail("%s, cipher suite %s, handshake in %s", tls.VersionName(state.Version), tls.CipherSuiteName(state.CipherSuite), handshake.Round(time.Millisecond))
+	for i, cert := range state.PeerCertificates {
+		d.detail("#%d subject: %s", i, cert.Subject)
+		d.detail("   issuer:  %s", cert.Issuer)
+		d.detail("   valid:   %s to %s", cert.NotBefore.Format(time.RFC3339), cert.NotAfter.Format(time.RFC3339))
+	}
+
+	var roots *x509.CertPool
+	if ca_file != "" {
+		// unreadable file is reported by configuration check
+		roots, _ = apiclient.LoadCAFile(ca_file)
+	}
+	if err := verifyChain(state.PeerCertificates, u.Hostname(), roots); err != nil {
+		if skip_verify {
+			return d.set(DIAGNOSTIC_WARN, "certificate is not trusted (%v) but ssl_skip_verify is set", err)
+		}
+		return d.set(DIAGNOSTIC_FAIL, "certificate is not trusted: %v", err)
+	}
+	return d.set(DIAGNOSTIC_PASS, "certificate of %s is trusted", u.Hostname())
+}
+
+// roots nil means system certificate authorities
+func verifyChain(certs []*x509.Certificate, hostname string, roots *x509.CertPool) error {
+	if len(certs) == 0 {
+		return errors.New("no certificate")
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := certs[0].Verify(x509.VerifyOptions{DNSName: hostname, Intermediates: intermediates, Roots: roots})
+	return err
+}
+
+// Send harmless authenticated requests (list of registered scripts) to check tOTP authentication and measure latency
+func diagnoseBackend(conf *mediatorscript.MediatorLegacyConfiguration) (*diagnostic, *diagnostic) {
+	auth := &diagnostic{name: "Authentication"}
+	latency := &diagnostic{name: "Latency"}
+
+	// every request is sent once so failures and latency are not hidden by retries
+	c := *conf
+	c.Configuration.Requests.Attempts = 1
+	c.Configuration.Requests.Breaker.Threshold = 0
+	client := newBackendClient(&c)
+
+	durations := []time.Duration{}
+	for i := 0; i < DIAGNOSE_LATENCY_SAMPLES; i++ {
+		var scripts []*mediatorscript.Script
+		if err := client.SetToken(); err != nil {
+			auth.set(DIAGNOSTIC_FAIL, "TOTP error: %v", err)
+			break
+		}
+		r, err := client.NewGETwithToken("", "json")
+		if err != nil {
+			auth.set(DIAGNOSTIC_FAIL, "%v", err)
+			break
+		}
+		start := time.Now()
+		if err := r.Run(&scripts); err != nil {
+			auth.set(DIAGNOSTIC_FAIL, "back-end rejected request (HTTP %d): %v", r.StatusCode, err)
+			auth.detail("request ID: %s", r.GetRequestID())
+			break
+		}
+		durations = append(durations, time.Since(start))
+		if i == 0 {
+			auth.set(DIAGNOSTIC_PASS, "back-end accepted tOTP authentication")
+			auth.detail("request ID: %s", r.GetRequestID())
+			auth.detail("%d script(s) registered on back-end", len(scripts))
+		}
+	}
+
+	if len(durations) == 0 {
+		return auth, latency.set(DIAGNOSTIC_SKIP, "no request was accepted by back-end")
+	}
+	var total, worst time.Duration
+	best := durations[0]
+	for _, d := range durations {
+		total += d
+		best, worst = min(best, d), max(worst, d)
+	}
+	avg := total / time.Duration(len(durations))
+	latency.set(DIAGNOSTIC_PASS, "%d request(s): min %s, avg %s, max %s", len(durations), best.Round(time.Millisecond), avg.Round(time.Millisecond), worst.Round(time.Millisecond))
+	if timeout := conf.Configuration.Requests.Timeout; timeout > 0 && worst > time.Duration(timeout)*time.Second/2 {
+		latency.set(DIAGNOSTIC_WARN, "max latency %s is close to request timeout (%ds)", worst.Round(time.Millisecond), timeout)
+	}
+	return auth, latency
+}
+
+func dialTimeout(conf *mediatorscript.MediatorLegacyConfiguration) time.Duration {
+	if conf.Configuration.Requests.DialTimeout == 0 {
+		return 10 * time.Second
+	}
+	return time.Duration(conf.Configuration.Requests.DialTimeout) * time.Second
+}
+
+// Check directory exists, or can be created, and files can be written in it
+func checkWritableDir(dir string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, ".diagnose-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
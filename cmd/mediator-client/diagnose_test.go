@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"mediator/mediatorscript"
+)
+
+func TestDiagnoseSettings(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name     string
+		content  string
+		want     diagnosticStatus
+		wantLine string
+	}{
+		{
+			name:     "valid",
+			content:  `{"wf": {"wf_name": "wf", "wf_id": 1, "settings": [{"trigger": "Advance", "script": "a.sh", "step": "Review"}]}}`,
+			want:     DIAGNOSTIC_PASS,
+			wantLine: "Trigger Advance on step Review fires script a.sh.",
+		},
+		{
+			name:     "missing step",
+			content:  `{"wf": {"wf_name": "wf", "wf_id": 1, "settings": [{"trigger": "Advance", "script": "a.sh"}]}}`,
+			want:     DIAGNOSTIC_FAIL,
+			wantLine: "INVALID: trigger 'Advance' requires a step",
+		},
+		{
+			name:    "not JSON",
+			content: `{`,
+			want:    DIAGNOSTIC_FAIL,
+		},
+		{
+			name:    "no workflow",
+			content: `{}`,
+			want:    DIAGNOSTIC_WARN,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(dir, tt.name+".json")
+			if err := os.WriteFile(filename, []byte(tt.content), 0600); err != nil {
+				t.Fatal(err)
+			}
+			d := diagnoseSettings(filename)
+			if d.status != tt.want {
+				t.Errorf("diagnoseSettings() = %s (%s), want %s", d.status, d.summary, tt.want)
+			}
+			if tt.wantLine != "" && !strings.Contains(strings.Join(d.details, "\n"), tt.wantLine) {
+				t.Errorf("diagnoseSettings() details %v do not contain %q", d.details, tt.wantLine)
+			}
+		})
+	}
+	if d := diagnoseSettings(filepath.Join(dir, "missing.json")); d.status != DIAGNOSTIC_WARN {
+		t.Errorf("missing file: got %s, want %s", d.status, DIAGNOSTIC_WARN)
+	}
+}
+
+func TestDiagnoseBackend(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"name": "a.sh"}]`))
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+
+	// test server certificate is self-signed
+	if d := diagnoseTLS(u, "", false, time.Second); d.status != DIAGNOSTIC_FAIL || len(d.details) < 2 {
+		t.Errorf("diagnoseTLS() = %s (%s) %v, want %s with certificate chain", d.status, d.summary, d.details, DIAGNOSTIC_FAIL)
+	}
+	if d := diagnoseTLS(u, "", true, time.Second); d.status != DIAGNOSTIC_WARN {
+		t.Errorf("diagnoseTLS() with ssl_skip_verify = %s (%s), want %s", d.status, d.summary, DIAGNOSTIC_WARN)
+	}
+	ca_file := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(ca_file, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if d := diagnoseTLS(u, ca_file, false, time.Second); d.status != DIAGNOSTIC_PASS {
+		t.Errorf("diagnoseTLS() with ca_file = %s (%s), want %s", d.status, d.summary, DIAGNOSTIC_PASS)
+	}
+
+	conf := mediatorscript.MediatorLegacyConfiguration{Configuration: mediatorscript.MediatorBasicConfiguration{BackendURL: srv.URL, SSLSkipVerify: true}}
+	auth, latency := diagnoseBackend(&conf)
+	if auth.status != DIAGNOSTIC_PASS || latency.status != DIAGNOSTIC_PASS {
+		t.Fatalf("diagnoseBackend() = %s (%s), %s (%s)", auth.status, auth.summary, latency.status, latency.summary)
+	}
+	if !strings.Contains(strings.Join(auth.details, "\n"), "1 script(s)") {
+		t.Errorf("unexpected authentication details: %v", auth.details)
+	}
+
+	var out bytes.Buffer
+	if ok := (diagnostics{auth, latency}).report(&out); !ok || !strings.Contains(out.String(), "[PASS] Authentication") {
+		t.Errorf("unexpected report: %s", out.String())
+	}
+	if ok := (diagnostics{auth, (&diagnostic{name: "TLS"}).set(DIAGNOSTIC_FAIL, "untrusted")}).report(&out); ok {
+		t.Error("report() should fail when a check failed")
+	}
+}
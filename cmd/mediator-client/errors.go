@@ -5,4 +5,7 @@ import "errors"
 var (
 	ErrSeveralInteractiveFlags error = errors.New("only one of the --scripted-condition --pre-assignment --scripted-task and --risk-analysis flags can be used at a time")
 	ErrNoInteractiveFlags      error = errors.New("one of the --scripted-condition --pre-assignment --scripted-task or --risk-analysis flags must be selected")
+	ErrBackendUnavailable      error = errors.New("back-end is unavailable")
+	ErrNoSpoolDir              error = errors.New("spool directory is not configured")
+	ErrExplainInteractive      error = errors.New("--explain only applies to trigger scripts: it cannot be used with interactive script flags")
 )
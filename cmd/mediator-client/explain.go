@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+
+	"mediator/mediatorscript"
+	"mediator/mediatorsettings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Handle --explain flag: ask back-end which rules match ticket and trigger, and why.
+// Nothing is run. Exit code is 1 if back-end could not explain.
+func explainAndExit(args arguments, conf *mediatorscript.MediatorLegacyConfiguration) {
+	if e, err := explainTrigger(args, conf); err != nil {
+		logrus.Errorf("mediator-client could not explain trigger: %v", err)
+		fmt.Fprintf(os.Stderr, "could not explain trigger: %v\n", err)
+		logrus.Exit(1)
+	} else {
+		e.Print(os.Stdout)
+		logrus.Exit(0)
+	}
+}
+
+func explainTrigger(args arguments, conf *mediatorscript.MediatorLegacyConfiguration) (*mediatorsettings.Explanation, error) {
+	var (
+		data mediatorscript.TicketInfo
+		e    mediatorsettings.Explanation
+	)
+	source, err := getInputSource(args.data_filename)
+	if err != nil {
+		return nil, err
+	}
+	xmlData, err := io.ReadAll(source)
+	if err != nil {
+		return nil, err
+	} else if err := xml.Unmarshal(xmlData, &data); err != nil {
+		return nil, fmt.Errorf("cannot parse XML input: %w", err)
+	}
+
+	// same request as a real trigger event, sent to an entry point that runs nothing
+	req := newTriggerRequest(trigger, args.positional[0], &data, xmlData, conf.Configuration.RawXML)
+	client := newBackendClient(conf)
+	if err := client.SetToken(); err != nil {
+		return nil, fmt.Errorf("TOTP error: %w", err)
+	}
+	if body, err := json.Marshal(req.Batch); err != nil {
+		return nil, err
+	} else if r, err := client.NewPOSTwithToken("explain-trigger", bytes.NewReader(body), "json"); err != nil {
+		return nil, err
+	} else if err := r.Run(&e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
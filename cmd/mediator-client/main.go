@@ -2,7 +2,6 @@ package main
 
 import (
 	"bytes"
-	"encoding/json"
 	"encoding/xml"
 	"errors"
 	"flag"
@@ -15,9 +14,6 @@ import (
 	"mediator/mediatorscript"
 	"mediator/mediatorsettings"
 	"mediator/scworkflow"
-	"mediator/totp"
-
-	"mediator/configparser"
 
 	"mediator/apiclient"
 	"mediator/logger"
@@ -28,17 +24,23 @@ import (
 var (
 	Version = "develop"
 	trigger scworkflow.SecurechangeTrigger
+	// sent to back-end with every request so client and server logs can be correlated
+	requestID = apiclient.NewRequestID()
 )
 
 func main() {
 	args := arguments{}
 	flag.StringVar(&args.data_filename, "file", "", "Read data from file instead of stdin.")
-	flag.StringVar(&args.trigger, "trigger", "", "Specify Securechange trigger for current processing")
-	flag.StringVar(&args.settings_filename, "settings-filename", "mediator-client.json", "Specify the name of workflow settings file.")
+	flag.StringVar(&args.trigger, "trigger", "", "Specify Securechange trigger for current processing. Inferred from program name when called through a symlink such as mediator-client-advance.")
+	flag.StringVar(&args.settings_filename, "settings-filename", "mediator-client.json", "Specify the name of workflow settings file. Relative names are resolved from configuration file folder. Only used in test mode: back-end finds scripts otherwise.")
+	flag.StringVar(&args.config_filename, "config", "", "Path of configuration file. Default is MEDIATOR_CONFIG environment variable, then mediator-client.yml in executable folder. MEDIATOR_* environment variables override its values.")
 	flag.BoolVar(&args.scriptedCondition, "scripted-condition", false, "Tell mediator-client to request back-end to run special 'Scripted Condition' script.")
 	flag.BoolVar(&args.preAssignment, "pre-assignment", false, "Tell mediator-client to request back-end to run special 'Pre-Assignment' script.")
 	flag.BoolVar(&args.scriptedTask, "scripted-task", false, "Tell mediator-client to request back-end to run special 'Scripted Task' script.")
 	flag.BoolVar(&args.riskAnalysis, "risk-analysis", false, "Tell mediator-client to request back-end to run special 'Risk Analysis' script.")
+	flag.BoolVar(&args.flushSpool, "flush-spool", false, "Send trigger requests kept in spool directory to back-end and exit.")
+	flag.BoolVar(&args.diagnose, "diagnose", false, "Check configuration, settings and connection to back-end, print a report and exit.")
+	flag.BoolVar(&args.explain, "explain", false, "Print which rules match ticket and trigger, and why, then exit. No script is run.")
 
 	// version
 	versionPtr := flag.Bool("version", false, "Print version number and exit.")
@@ -49,6 +51,7 @@ func main() {
 		os.Exit(0)
 	}
 	args.positional = flag.Args()
+	args.inferFromProgramName(os.Args[0])
 	if t, err := checkArgumentsAndGetTrigger(args); err != nil {
 		logrus.Fatal(err)
 	} else {
@@ -58,27 +61,43 @@ func main() {
 	var conf mediatorscript.MediatorLegacyConfiguration
 
 	// get current executable so we can figure out what is current path
-	// conf file is in the same folder as configuration file
+	// conf file is in the same folder as executable by default
 	ex, err := os.Executable()
 	if err != nil {
 		logrus.Fatal(err)
 	}
 	// get current folder from executable absolute path
 	currPath := filepath.Dir(ex)
+	conf_filename := configurationPath(args, currPath)
+	settings_filename := settingsPath(args, conf_filename)
 
-	if err := configparser.ReadConfAbsolutePath(fmt.Sprintf("%s/mediator-client.yml", currPath), &conf, nil); err != nil {
+	if args.diagnose {
+		// this function will terminate current process
+		diagnoseAndExit(conf_filename, settings_filename)
+	}
+
+	if err := readConfiguration(conf_filename, &conf); err != nil {
 		logrus.Fatal(err)
 	}
 
-	if _, err := logger.InitAppLogger(
+	if l, err := logger.InitAppLoggerWithRotation(
 		true, // use default logger
 		GetLogLevel(conf.Configuration.Log.Level),
 		true, true, true, true, true, true, "",
-		conf.Configuration.Log.File); err != nil {
+		conf.Configuration.Log.File,
+		conf.Configuration.Log.Rotation); err != nil {
 
 		logrus.Fatal(err)
+	} else if err := logger.SetLogFormat(l, conf.Configuration.Log.Format); err != nil {
+		logrus.Fatal(err)
+	} else if err := logger.AddSinks(l, conf.Configuration.Log.Sinks); err != nil {
+		// an unreachable sink must not prevent trigger from being sent or spooled
+		logrus.Warning(err)
 	}
+	// from now on, exit with logrus.Exit rather than os.Exit: log file is closed by exit handler
 	defer logger.CloseLogFile()
+	defer logger.CloseSinks()
+	logger.AddDefaultField(logger.FIELD_REQUEST_ID, requestID)
 
 	if err := mediatorscript.Init(""); err != nil {
 		// mediator-client does not need to care about this error
@@ -101,6 +120,16 @@ func main() {
 		logrus.Warningf("SSL verification will be skipped! Connection to backend is insecure!")
 	}
 
+	if args.flushSpool {
+		// this function will terminate current process
+		flushSpoolAndExit(&conf)
+	}
+
+	if args.explain {
+		// this function will terminate current process
+		explainAndExit(args, &conf)
+	}
+
 	if args.isInteractiveScript() {
 		logrus.Infof("Starting mediator-client for Interactive scripts")
 
@@ -113,10 +142,14 @@ func main() {
 
 	logrus.Infof("Starting mediator-client in normal mode. Trigger is %s", trigger)
 
-	if wf_settings, err := mediatorsettings.ReadWorkflowsSettings(fmt.Sprintf("%s/%s", currPath, args.settings_filename)); err != nil {
-		logrus.Fatal(err)
+	// replay requests that could not be sent by previous runs before sending new ones
+	if conf.Configuration.SpoolDir != "" {
+		if _, _, err := flushSpool(&conf); err != nil {
+			logrus.Warningf("mediator-client could not flush spool: %v", err)
+		}
+	}
 
-	} else if source, err := getInputSource(args.data_filename); err != nil {
+	if source, err := getInputSource(args.data_filename); err != nil {
 		logrus.Fatal(err)
 
 	} else if xmlData, err := io.ReadAll(source); err != nil {
@@ -125,13 +158,7 @@ func main() {
 	} else {
 		// get workflow
 		current_workflow := flag.Args()[0]
-
-		settings, err := wf_settings.GetWorkflowSettings(current_workflow)
-		if err != nil {
-			logrus.Fatal(err)
-		} else {
-			logrus.Infof("mediator-client called on worflow '%s'", current_workflow)
-		}
+		logrus.Infof("mediator-client called on worflow '%s'", current_workflow)
 
 		// check for test run
 		// we need to check the system is working fine when user clicks on "Test" button in SC
@@ -140,6 +167,15 @@ func main() {
 			logrus.Infof("mediator-client is in test mode")
 
 			// we will send a test request for all scripts attached to workflow
+			// workflow settings file is only needed here: in real mode, back-end finds scripts
+			wf_settings, err := mediatorsettings.ReadWorkflowsSettings(settings_filename)
+			if err != nil {
+				logrus.Fatal(err)
+			}
+			settings, err := wf_settings.GetWorkflowSettings(current_workflow)
+			if err != nil {
+				logrus.Fatal(err)
+			}
 			scripts := settings.GetAllScripts(trigger)
 
 			run_results := mediatorscript.SyncRunResponsesMap{}
@@ -147,12 +183,12 @@ func main() {
 			// forward test request to backend:
 			// send a test request for every scripts
 			// dump summary at the end
-			client := apiclient.NewClient(conf.Configuration.BackendURL, "", "", conf.Configuration.SSLSkipVerify)
+			client := newBackendClient(&conf)
 			for _, s := range scripts {
 				var err error
 
 				// get a new tOTP password for every request
-				if client.Token, err = totp.GetKey(); err != nil {
+				if err = client.SetToken(); err != nil {
 					// this shoud never happens
 					// if it does, useless to keep trying. Stop here.
 					logrus.Fatalf("TOTP error: %v. Stop!", err)
@@ -210,10 +246,10 @@ func main() {
 			}
 			if nberrors == 0 {
 				logrus.Infof("All tests passed")
-				os.Exit(0)
+				logrus.Exit(0)
 			} else {
 				logrus.Warningf("%d/%d test(s) failed", nberrors, len(run_results))
-				os.Exit(1)
+				logrus.Exit(1)
 			}
 
 		}
@@ -225,65 +261,52 @@ func main() {
 		if err := xml.Unmarshal(xmlData, &data); err != nil {
 			logrus.Fatalf("cannot parse XML input #%s#: %v", string(xmlData), err)
 		}
+		logger.AddDefaultField(logger.FIELD_TICKET_ID, data.ID)
 
-		// work out what is current step
-		// we look for step name in completion_data and in current_stage.
-		// they can't be both null neither both non null
-		// if completion data is provided, get from there. ignore next step
-		// otherwise, get from current status. In that case, check next-step flag as we may need to get next step.
-		currentStep := ""
-		switch {
-		case data.CompletionData != nil && data.CurrentStage == nil:
-			currentStep = data.CompletionData.Name
-			logrus.Infof("Info from XML: ticket is '%s' (ID=%d), current step is %s", data.Subject, data.ID, currentStep)
-		case data.CompletionData == nil && data.CurrentStage != nil:
-			currentStep = data.CurrentStage.Name
-			logrus.Infof("Info from XML: ticket is '%s' (ID=%d), current step is %s", data.Subject, data.ID, currentStep)
-		default:
+		if step, err := data.CurrentStep(); err != nil {
+			// back-end would reject request anyway
 			logrus.Warningf("mediator-client received unexpected XML data: %s", string(xmlData))
-			logrus.Fatalf("unexpected XML data: both 'current_stage' and 'completion_data' tags are missing. Cannot get ticket step. Stop.")
+			logrus.Fatalf("unexpected XML data: %v. Stop.", err)
+		} else {
+			logrus.Infof("Info from XML: ticket is '%s' (ID=%d), current step is %s", data.Subject, data.ID, step)
 		}
 
-		if scripts := settings.GetScriptsForTriggerAndStep(trigger, currentStep); len(scripts) > 0 {
-			logrus.Infof("mediator-client found %d script(s) for ticket '%s' (ID=%d) in step %s: %v. Trigger action.", len(scripts), data.Subject, data.ID, currentStep, scripts)
+		// back-end finds the scripts attached to trigger and step in workflow settings, and runs them
+		client := newBackendClient(&conf)
+		if err := client.SetToken(); err != nil {
+			logrus.Fatalf("TOTP error: %v. Stop!", err)
+		}
 
-			if client, err := apiclient.NewClientWithOTP(conf.Configuration.BackendURL, conf.Configuration.SSLSkipVerify); err != nil {
-				logrus.Fatalf("cannot get API client: %v", err)
-			} else {
-				var err error
-				for _, script := range scripts {
-					if script == "" {
-						continue
-					}
-					if client.Token, err = totp.GetKey(); err != nil {
-						logrus.Fatalf("TOTP error: %v. Stop!", err)
-					}
+		req := newTriggerRequest(trigger, current_workflow, &data, xmlData, conf.Configuration.RawXML)
+		if res, err := req.send(client); err == nil {
+			logBatchResponse(req, res)
 
-					script_url := fmt.Sprintf("execute/%s", script)
-
-					if jsonData, err := json.Marshal(data); err != nil {
-						logrus.Fatal(err)
-					} else if r, err := client.NewPOSTwithToken(script_url, bytes.NewBuffer(jsonData), "json"); err != nil {
-						logrus.Warningf("mediator-client is sending resquest to entry point: %s", script_url)
-						logrus.Fatal(err)
-					} else if _, err := r.RunWithoutDecode(); err != nil { // returns 204 (no content) or an error
-						// something went wrong before script execution
-						// we don't need response body: error will be decoded into err
-						logrus.Warningf("mediator-client sent resquest to entry point '%s'  with json data: %s", script_url, string(jsonData))
-						logrus.Errorf("mediator-client received an error from backend: %v", err)
-					} else {
-						logrus.Infof("mediator-client received an empty OK response")
-					}
-				}
+		} else if errors.Is(err, ErrBackendUnavailable) && conf.Configuration.SpoolDir != "" {
+			// keep request so it is replayed by next mediator-client run
+			logrus.Warningf("mediator-client could not send request: %v", err)
+			if err := (spool{conf.Configuration.SpoolDir}).add(req); err != nil {
+				logrus.Errorf("mediator-client could not spool request: %v. Request is lost.", err)
+			} else {
+				logrus.Warningf("request has been spooled in %s", conf.Configuration.SpoolDir)
 			}
+
 		} else {
-			logrus.Infof("mediator-client found no script for ticket '%s' (ID=%d) in step '%s'. Do nothing.", data.Subject, data.ID, currentStep)
+			// something went wrong before script execution
+			logrus.Warningf("mediator-client sent resquest to entry point '%s' for workflow '%s'", req.endpoint(), current_workflow)
+			logrus.Errorf("mediator-client received an error from backend: %v", err)
 		}
 	}
 }
 
 func checkArgumentsAndGetTrigger(args arguments) (scworkflow.SecurechangeTrigger, error) {
+	if args.flushSpool || args.diagnose {
+		return scworkflow.NO_TRIGGER, nil
+	}
+
 	if args.isInteractiveScript() {
+		if args.explain {
+			return scworkflow.NO_TRIGGER, ErrExplainInteractive
+		}
 		if err := args.isUniqueInteractiveScriptFlag(); err != nil {
 			return scworkflow.NO_TRIGGER, err
 		}
@@ -338,3 +361,24 @@ func GetLogLevel(l string) logrus.Level {
 	}
 	return logrus.WarnLevel
 }
+
+// Return a client that sends requests to back-end according to configuration
+func newBackendClient(conf *mediatorscript.MediatorLegacyConfiguration) *apiclient.Client {
+	dial_timeout := conf.Configuration.Requests.DialTimeout
+	if dial_timeout == 0 {
+		dial_timeout = 10
+	}
+	// mediator-client runs once per trigger: breaker state is shared by all runs through spool directory
+	if err := apiclient.SetBreakerStateDir(conf.Configuration.SpoolDir); err != nil {
+		logrus.Warningf("circuit breaker state cannot be kept in %s: %v", conf.Configuration.SpoolDir, err)
+	}
+	client, err := apiclient.NewClientWithCAFile(conf.Configuration.BackendURL, "", "", conf.Configuration.CAFile, conf.Configuration.SSLSkipVerify, dial_timeout)
+	if err != nil {
+		// requests still get a chance with system certificate authorities, and are spooled if they fail
+		logrus.Errorf("certificate authorities cannot be read from %s: %v", conf.Configuration.CAFile, err)
+		client = apiclient.NewClientWithDialTimeout(conf.Configuration.BackendURL, "", "", conf.Configuration.SSLSkipVerify, dial_timeout)
+	}
+	client.SetRequestID(requestID)
+	client.SetRequestPolicy(conf.Configuration.Requests)
+	return client
+}
@@ -154,6 +154,41 @@ func Test_checkArgumentsAndGetTrigger(t *testing.T) {
 			want:    scworkflow.CREATE,
 			wantErr: true,
 		},
+		{
+			name: "flush spool",
+			args: arguments{
+				flushSpool: true,
+			},
+			want:    scworkflow.NO_TRIGGER,
+			wantErr: false,
+		},
+		{
+			name: "explain",
+			args: arguments{
+				positional: []string{"wf"},
+				trigger:    "Advance",
+				explain:    true,
+			},
+			want:    scworkflow.ADVANCE,
+			wantErr: false,
+		},
+		{
+			name: "explain interactive script",
+			args: arguments{
+				explain:      true,
+				scriptedTask: true,
+			},
+			want:    scworkflow.NO_TRIGGER,
+			wantErr: true,
+		},
+		{
+			name: "diagnose",
+			args: arguments{
+				diagnose: true,
+			},
+			want:    scworkflow.NO_TRIGGER,
+			wantErr: false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -168,3 +203,66 @@ func Test_checkArgumentsAndGetTrigger(t *testing.T) {
 		})
 	}
 }
+
+func Test_arguments_inferFromProgramName(t *testing.T) {
+	tests := []struct {
+		name    string
+		program string
+		args    arguments
+		want    arguments
+	}{
+		{
+			name:    "executable",
+			program: "/opt/tufin/data/securechange/scripts/mediator-client",
+			want:    arguments{},
+		},
+		{
+			name:    "trigger symlink",
+			program: "/opt/tufin/data/securechange/scripts/mediator-client-advance",
+			want:    arguments{trigger: "Advance"},
+		},
+		{
+			name:    "trigger with spaces",
+			program: "mediator-client-automatic-step-failed",
+			want:    arguments{trigger: "Automatic step failed"},
+		},
+		{
+			name:    "interactive symlink",
+			program: "./mediator-client-pre-assignment",
+			want:    arguments{preAssignment: true},
+		},
+		{
+			name:    "risk analysis",
+			program: "mediator-client-risk-analysis.sh",
+			want:    arguments{riskAnalysis: true},
+		},
+		{
+			name:    "trigger flag overrides",
+			program: "mediator-client-advance",
+			args:    arguments{trigger: "Create"},
+			want:    arguments{trigger: "Create"},
+		},
+		{
+			name:    "interactive flag overrides",
+			program: "mediator-client-advance",
+			args:    arguments{scriptedTask: true},
+			want:    arguments{scriptedTask: true},
+		},
+		{
+			name:    "unknown name",
+			program: "mediator-client-unknown",
+			want:    arguments{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.args.inferFromProgramName(tt.program)
+			if !reflect.DeepEqual(tt.args, tt.want) {
+				t.Errorf("inferFromProgramName() = %+v, want %+v", tt.args, tt.want)
+			}
+			if _, err := checkArgumentsAndGetTrigger(arguments{positional: []string{"wf"}, trigger: tt.want.trigger}); tt.want.trigger != "" && err != nil {
+				t.Errorf("inferred trigger is invalid: %v", err)
+			}
+		})
+	}
+}
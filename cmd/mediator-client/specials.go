@@ -1,12 +1,14 @@
 package main
 
 import (
+	"bytes"
+	"encoding/xml"
 	"fmt"
 	"io"
-	"mediator/apiclient"
+	"mediator/logger"
 	"mediator/mediatorscript"
-	"mediator/totp"
 	"os"
+	"strconv"
 
 	"github.com/sirupsen/logrus"
 )
@@ -31,6 +33,7 @@ func runScriptedConditionScript(args []string, datafilenameFlag string, conf *me
 		logrus.Fatalf("wrong number of positional arguments : one expected when using --scripted-condition flag, got %d: %v ", len(args), args)
 	}
 	endpoint := fmt.Sprintf("execute-scripted-condition/%s", args[0]) // arg can be a ticket ID or the "test" keyword
+	addTicketIDField(args[0])
 
 	if reqBody, err := getInputSource(datafilenameFlag); err != nil {
 		logrus.Fatal(err)
@@ -57,6 +60,7 @@ func runScriptedTaskScript(args []string, datafilenameFlag string, conf *mediato
 		logrus.Fatalf("wrong number of positional arguments : one expected when using --scripted-task flag, got %d: %v ", len(args), args)
 	}
 	endpoint := fmt.Sprintf("execute-scripted-task/%s", args[0]) // arg can be a ticket ID or the "test" keyword
+	addTicketIDField(args[0])
 
 	if reqBody, err := getInputSource(datafilenameFlag); err != nil {
 		logrus.Fatal(err)
@@ -81,8 +85,18 @@ func requestInteractiveScriptExecution(endpoint string, currScript string, reqBo
 		err error
 	)
 
-	client := apiclient.NewClient(conf.Configuration.BackendURL, "", "", conf.Configuration.SSLSkipVerify)
-	client.Token, err = totp.GetKey()
+	// read input so we can log ticket ID if we don't know it yet
+	body, err := io.ReadAll(reqBody)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	var ti mediatorscript.TicketInfo
+	if xml.Unmarshal(body, &ti) == nil && ti.ID != 0 {
+		addTicketIDField(strconv.Itoa(ti.ID))
+	}
+
+	client := newBackendClient(conf)
+	err = client.SetToken()
 	if err != nil {
 		logrus.Fatal(err)
 	}
@@ -90,7 +104,7 @@ func requestInteractiveScriptExecution(endpoint string, currScript string, reqBo
 	logrus.Debugf("mediator-client is sending request to backend end-point: %s", endpoint)
 	res := mediatorscript.RunResponse{}
 
-	if r, err := client.NewPOSTwithToken(endpoint, reqBody, "json"); err != nil {
+	if r, err := client.NewPOSTwithToken(endpoint, bytes.NewReader(body), "json"); err != nil {
 		logrus.Fatal(err)
 	} else if err := r.Run(&res); err != nil {
 
@@ -121,10 +135,23 @@ func requestInteractiveScriptExecution(endpoint string, currScript string, reqBo
 			logrus.Infof(" - execution error: %s", r.ScriptError)
 			logrus.Infof(" - exit code: %d", r.ExitCode)
 
+			stdout, stderr, exit_code := r.StdOut, r.StdErr, r.ExitCode
+			if r.ResultError != "" {
+				// script is registered with JSON result but its output is not valid
+				// make it visible in ticket history
+				logrus.Errorf("%s script returned an invalid result: %s", currScript, r.ResultError)
+				stdout, stderr, exit_code = "", fmt.Sprintf("mediator: %s script returned an invalid result: %s", currScript, r.ResultError), 1
+			} else if r.Result != nil {
+				// translate JSON result into what SecureChange expects
+				stdout, stderr, exit_code = r.Result.Contract(r.Type)
+				logrus.Infof(" - decision: %s", r.Result.Decision)
+				logrus.Infof(" - message: %s", r.Result.Message)
+			}
+
 			// send what we got back to SecureChange
-			os.Stdout.WriteString(r.StdOut)
-			os.Stderr.WriteString(r.StdErr)
-			os.Exit(r.ExitCode)
+			os.Stdout.WriteString(stdout)
+			os.Stderr.WriteString(stderr)
+			logrus.Exit(exit_code)
 
 		}
 
@@ -133,3 +160,10 @@ func requestInteractiveScriptExecution(endpoint string, currScript string, reqBo
 	}
 
 }
+
+// Add ticket ID to every log line unless we are in test mode
+func addTicketIDField(ticket_id string) {
+	if ticket_id != "" && ticket_id != "test" {
+		logger.AddDefaultField(logger.FIELD_TICKET_ID, ticket_id)
+	}
+}
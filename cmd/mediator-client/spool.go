@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"mediator/apiclient"
+	"mediator/mediatorscript"
+	"mediator/scworkflow"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+// Suffix of spooled requests that were rejected by back-end. They are kept for investigation but never replayed.
+const SPOOL_FAILED_SUFFIX = ".failed"
+
+// A trigger request sent to back-end /execute-trigger entry point: back-end finds and runs the scripts attached to the trigger event.
+// It is written in spool directory when back-end is unavailable so it can be replayed later.
+type triggerRequest struct {
+	// identifies the trigger event. Back-end runs a script only once per key.
+	Key       string                      `json:"key"`
+	Time      time.Time                   `json:"time"`
+	TicketID  int                         `json:"ticket_id"`
+	RequestID string                      `json:"request_id"`
+	Batch     mediatorscript.BatchRequest `json:"batch"`
+}
+
+// Build the request for trigger event of ticket in workflow.
+// Raw XML is sent as is if required. Ticket info is sent otherwise.
+func newTriggerRequest(t scworkflow.SecurechangeTrigger, workflow string, data *mediatorscript.TicketInfo, xmlData []byte, raw bool) *triggerRequest {
+	req := triggerRequest{
+		Key:       idempotencyKey(t, xmlData),
+		Time:      time.Now(),
+		TicketID:  data.ID,
+		RequestID: requestID,
+		Batch: mediatorscript.BatchRequest{
+			Workflow: workflow,
+			Trigger:  t.String(),
+		},
+	}
+	if raw {
+		req.Batch.TicketXML = string(xmlData)
+	} else {
+		req.Batch.Ticket = data
+	}
+	return &req
+}
+
+// Back-end entry point of request.
+// Requests spooled by previous mediator-client versions name their scripts: they are sent to /execute-batch.
+func (req *triggerRequest) endpoint() string {
+	if len(req.Batch.Scripts) > 0 {
+		return "execute-batch"
+	}
+	return "execute-trigger"
+}
+
+// Same trigger and ticket data give the same key. Back-end adds script name to it.
+// Securechange ticket data includes update date so two different events never share a key.
+func idempotencyKey(t scworkflow.SecurechangeTrigger, xmlData []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n", t)
+	h.Write(xmlData)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Send request to back-end and return the result of every script.
+// Returned error wraps ErrBackendUnavailable if request can be sent again later.
+func (req *triggerRequest) send(client *apiclient.Client) (*mediatorscript.BatchResponse, error) {
+	var res mediatorscript.BatchResponse
+
+	if body, err := json.Marshal(req.Batch); err != nil {
+		return nil, err
+	} else if r, err := client.NewPOSTwithToken(req.endpoint(), bytes.NewReader(body), "json"); err != nil {
+		return nil, err
+	} else {
+		r.SetHeader(apiclient.HeaderIdempotencyKey, req.Key)
+
+		if err := r.Run(&res); err == nil {
+			return &res, nil
+		} else if r.StatusCode == 0 || r.StatusCode == http.StatusTooManyRequests || r.StatusCode >= 500 {
+			// no response, back-end (or proxy) failure: scripts were not run
+			return nil, fmt.Errorf("%w: %w", ErrBackendUnavailable, err)
+		} else {
+			return nil, err
+		}
+	}
+}
+
+// Log the result of every script. Returns false if a script was rejected.
+func logBatchResponse(req *triggerRequest, res *mediatorscript.BatchResponse) bool {
+	if len(res.Results) == 0 {
+		logrus.Infof("back-end found no script for ticket %d in step '%s'. Nothing was run.", req.TicketID, res.Step)
+		return true
+	}
+	logrus.Infof("mediator-client triggered %d script(s) for ticket %d in step '%s': %d accepted, %d rejected", len(res.Results), req.TicketID, res.Step, res.Accepted, res.Rejected)
+	for _, r := range res.Results {
+		if !r.Accepted {
+			logrus.Errorf("back-end rejected script '%s': %s", r.Script, r.Error)
+		} else if r.Duplicate {
+			logrus.Infof("script '%s' had already been run for this event", r.Script)
+		}
+	}
+	return res.Rejected == 0
+}
+
+// Directory where trigger requests are kept, one file per trigger event.
+// File names start with request time so they are replayed in order.
+type spool struct {
+	dir string
+}
+
+func (s spool) filename(req *triggerRequest) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%020d-%s.json", req.Time.UnixNano(), req.Key))
+}
+
+// Write request in spool directory unless a request with the same key is already there
+func (s spool) add(req *triggerRequest) error {
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return err
+	} else if found, err := filepath.Glob(filepath.Join(s.dir, fmt.Sprintf("*-%s.json", req.Key))); err != nil {
+		return err
+	} else if len(found) > 0 {
+		logrus.Infof("request for ticket %d is already in spool: %s", req.TicketID, found[0])
+		return nil
+	}
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	// write a temporary file first so an incomplete request is never replayed
+	name := s.filename(req)
+	tmp := name + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, name)
+}
+
+// Return spooled request files, oldest first
+func (s spool) list() ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// Send spooled requests to back-end in order.
+// Flush stops at the first request that cannot be sent because back-end is unavailable: next ones are kept for later.
+// Requests rejected by back-end are renamed with SPOOL_FAILED_SUFFIX.
+// Only one mediator-client flushes the spool at a time: others return immediately.
+// Returns the number of requests that were sent and the number of requests left in spool.
+func (s spool) flush(client *apiclient.Client) (int, int, error) {
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return 0, 0, err
+	}
+	lock, err := os.OpenFile(filepath.Join(s.dir, ".lock"), os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer lock.Close()
+	if err := unix.Flock(int(lock.Fd()), unix.LOCK_EX|unix.LOCK_NB); err != nil {
+		if errors.Is(err, unix.EWOULDBLOCK) {
+			logrus.Infof("spool %s is being flushed by another mediator-client", s.dir)
+			return 0, 0, nil
+		}
+		return 0, 0, err
+	}
+	defer unix.Flock(int(lock.Fd()), unix.LOCK_UN)
+
+	files, err := s.list()
+	if err != nil {
+		return 0, 0, err
+	}
+	// replayed requests keep their own request ID so logs can be correlated with the original event
+	defer client.SetRequestID(requestID)
+
+	sent := 0
+	for i, file := range files {
+		var req triggerRequest
+		if data, err := os.ReadFile(file); err != nil {
+			return sent, len(files) - i, err
+		} else if err := json.Unmarshal(data, &req); err != nil {
+			logrus.Errorf("cannot read spooled request %s: %v", file, err)
+			s.fail(file)
+			continue
+		}
+
+		if err := client.SetToken(); err != nil {
+			return sent, len(files) - i, fmt.Errorf("TOTP error: %w", err)
+		}
+		client.SetRequestID(req.RequestID)
+		if res, err := req.send(client); errors.Is(err, ErrBackendUnavailable) {
+			return sent, len(files) - i, err
+		} else if err != nil {
+			logrus.Errorf("back-end rejected spooled request for ticket %d (%s): %v", req.TicketID, file, err)
+			s.fail(file)
+		} else {
+			logrus.Infof("spooled request for ticket %d triggered on %s has been sent", req.TicketID, req.Time.Format(time.RFC3339))
+			sent++
+			if !logBatchResponse(&req, res) {
+				// keep rejected request for investigation.
+				// Accepted scripts will not run again thanks to idempotency key
+				s.fail(file)
+			} else if err := os.Remove(file); err != nil {
+				// back-end will ignore it anyway thanks to idempotency key
+				logrus.Warningf("cannot remove spooled request %s: %v", file, err)
+			}
+		}
+	}
+	return sent, 0, nil
+}
+
+func (s spool) fail(file string) {
+	if err := os.Rename(file, file+SPOOL_FAILED_SUFFIX); err != nil {
+		logrus.Warningf("cannot rename spooled request %s: %v", file, err)
+	}
+}
+
+// Send spooled requests to back-end configured in conf
+func flushSpool(conf *mediatorscript.MediatorLegacyConfiguration) (int, int, error) {
+	if conf.Configuration.SpoolDir == "" {
+		return 0, 0, ErrNoSpoolDir
+	}
+	client := newBackendClient(conf)
+	sent, left, err := spool{conf.Configuration.SpoolDir}.flush(client)
+	if sent > 0 || left > 0 {
+		logrus.Infof("mediator-client sent %d spooled request(s). %d request(s) left in spool.", sent, left)
+	}
+	return sent, left, err
+}
+
+// Handle --flush-spool flag. Exit code is 1 if some requests are left in spool.
+func flushSpoolAndExit(conf *mediatorscript.MediatorLegacyConfiguration) {
+	sent, left, err := flushSpool(conf)
+	if err != nil {
+		logrus.Errorf("mediator-client could not flush spool: %v", err)
+		fmt.Fprintf(os.Stderr, "could not flush spool: %v\n", err)
+	}
+	fmt.Printf("%d request(s) sent, %d request(s) left in spool\n", sent, left)
+	if err != nil || left > 0 {
+		logrus.Exit(1)
+	}
+	logrus.Exit(0)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"mediator/apiclient"
+	"mediator/mediatorscript"
+	"mediator/scworkflow"
+)
+
+func TestSpool(t *testing.T) {
+	var (
+		status   = http.StatusServiceUnavailable
+		received []mediatorscript.BatchRequest
+		keys     []string
+		// scripts found by back-end for each workflow
+		rules = map[string][]string{"wf": {"a.sh", "b.sh"}, "wf2": {"a.sh"}}
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if status != http.StatusOK || (r.URL.Path != "/execute-trigger" && r.URL.Path != "/execute-batch") {
+			w.WriteHeader(status)
+			return
+		}
+		var (
+			br  mediatorscript.BatchRequest
+			res mediatorscript.BatchResponse
+		)
+		json.NewDecoder(r.Body).Decode(&br)
+		received = append(received, br)
+		keys = append(keys, r.Header.Get(apiclient.HeaderIdempotencyKey))
+		scripts := br.Scripts
+		if r.URL.Path == "/execute-trigger" {
+			scripts = rules[br.Workflow]
+		} else if len(br.Scripts) == 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		for _, script := range scripts {
+			// unknown script
+			if script == "bad.sh" {
+				res.Results = append(res.Results, &mediatorscript.BatchResult{Script: script, Error: "not found"})
+				res.Rejected++
+			} else {
+				res.Results = append(res.Results, &mediatorscript.BatchResult{Script: script, Accepted: true})
+				res.Accepted++
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(res)
+	}))
+	defer srv.Close()
+
+	client, err := apiclient.NewClientWithOTP(srv.URL, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := spool{filepath.Join(t.TempDir(), "spool")}
+
+	xml1 := []byte(`<ticket_info><id>1</id></ticket_info>`)
+	xml2 := []byte(`<ticket_info><id>2</id></ticket_info>`)
+	req1 := newTriggerRequest(scworkflow.CREATE, "wf", &mediatorscript.TicketInfo{ID: 1}, xml1, true)
+	req2 := newTriggerRequest(scworkflow.CREATE, "wf2", &mediatorscript.TicketInfo{ID: 2}, xml2, false)
+	if _, err := req1.send(client); !errors.Is(err, ErrBackendUnavailable) {
+		t.Fatalf("expected %v, got %v", ErrBackendUnavailable, err)
+	}
+
+	// same request is spooled once
+	for _, req := range []*triggerRequest{req1, req1, req2} {
+		if err := s.add(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if files, _ := s.list(); len(files) != 2 {
+		t.Fatalf("expected 2 spooled requests, got %v", files)
+	}
+
+	// back-end is still down: everything is kept
+	if sent, left, err := s.flush(client); !errors.Is(err, ErrBackendUnavailable) || sent != 0 || left != 2 {
+		t.Fatalf("unexpected flush result: %d sent, %d left, %v", sent, left, err)
+	}
+
+	status = http.StatusOK
+	if sent, left, err := s.flush(client); err != nil || sent != 2 || left != 0 {
+		t.Fatalf("unexpected flush result: %d sent, %d left, %v", sent, left, err)
+	}
+	if len(received) != 2 || keys[0] != req1.Key || keys[1] != req2.Key {
+		t.Fatalf("unexpected requests: %+v with keys %v", received, keys)
+	}
+	if received[0].TicketXML != string(xml1) || received[0].Ticket != nil || len(received[0].Scripts) != 0 || received[0].Workflow != "wf" {
+		t.Errorf("unexpected raw XML request: %+v", received[0])
+	}
+	if received[1].TicketXML != "" || received[1].Ticket == nil || received[1].Ticket.ID != 2 {
+		t.Errorf("unexpected ticket info request: %+v", received[1])
+	}
+	if files, _ := s.list(); len(files) != 0 {
+		t.Errorf("spool should be empty, got %v", files)
+	}
+
+	// rejected requests are not replayed
+	status = http.StatusBadRequest
+	for _, req := range []*triggerRequest{req1, req2} {
+		if err := s.add(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if sent, left, err := s.flush(client); err != nil || sent != 0 || left != 0 {
+		t.Fatalf("unexpected flush result: %d sent, %d left, %v", sent, left, err)
+	}
+	// as well as requests with a rejected script.
+	// Request names its scripts like the ones spooled by previous versions: it is sent to /execute-batch
+	status = http.StatusOK
+	req3 := newTriggerRequest(scworkflow.CREATE, "wf", &mediatorscript.TicketInfo{ID: 3}, []byte(`<ticket_info><id>3</id></ticket_info>`), true)
+	req3.Batch.Scripts = []string{"a.sh", "bad.sh"}
+	if err := s.add(req3); err != nil {
+		t.Fatal(err)
+	}
+	if sent, left, err := s.flush(client); err != nil || sent != 1 || left != 0 {
+		t.Fatalf("unexpected flush result: %d sent, %d left, %v", sent, left, err)
+	}
+	if files, _ := filepath.Glob(filepath.Join(s.dir, "*"+SPOOL_FAILED_SUFFIX)); len(files) != 3 {
+		t.Errorf("expected 3 failed requests, got %v", files)
+	}
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	data := []byte(`<ticket_info><id>1</id></ticket_info>`)
+	key := idempotencyKey(scworkflow.CREATE, data)
+	if key != idempotencyKey(scworkflow.CREATE, data) {
+		t.Error("same event should give the same key")
+	}
+	if key == idempotencyKey(scworkflow.CLOSE, data) || key == idempotencyKey(scworkflow.CREATE, []byte(`<ticket_info><id>2</id></ticket_info>`)) {
+		t.Error("different events should give different keys")
+	}
+}
@@ -2,6 +2,9 @@ package main
 
 import (
 	"mediator/configparser"
+	"mediator/logger"
+	"mediator/mediatorscript"
+	"mediator/notify"
 
 	"github.com/sirupsen/logrus"
 )
@@ -9,6 +12,8 @@ import (
 type Configurations struct {
 	Server         ServerConfigurations   `json:"server"`
 	Mediatorscript MediatorConfigurations `json:"mediatorscript"`
+	// Securechange API used to post trigger script output to tickets
+	Securechange mediatorscript.SecurechangeAPIConfiguration `json:"securechange"`
 }
 type MediatorscriptClientConfigurations struct {
 	// full path of the generated configuration file (JSON format)
@@ -18,21 +23,57 @@ type MediatorscriptClientConfigurations struct {
 }
 
 type MediatorConfigurations struct {
-	ScriptStorage       string                             `json:"scriptstorage"`
+	ScriptStorage string `json:"scriptstorage"`
+	// max duration of a script execution in seconds. 0 means no timeout
+	Timeout             uint                               `json:"timeout"`
 	ClientConfiguration MediatorscriptClientConfigurations `json:"clientconfiguration"`
 }
 
 type ServerConfigurations struct {
-	Port   uint              `json:"port"`
-	Host   string            `json:"host"`
-	Log    LogConfigurations `json:"log"`
-	Secret string            `json:"secret"`
-	Ssl    SslConfigurations `json:"ssl"`
+	Port   uint               `json:"port"`
+	Host   string             `json:"host"`
+	Log    LogConfigurations  `json:"log"`
+	Secret string             `json:"secret"`
+	Ssl    SslConfigurations  `json:"ssl"`
+	Auth   AuthConfigurations `json:"auth"`
+	// max size of request bodies sent to authenticated entry points, in megabytes
+	MaxBodySize int64 `json:"maxbodysize"`
+	// expose Prometheus metrics on /metrics. Disabled by default: /metrics is not authenticated
+	Metrics bool `json:"metrics"`
+	// webhooks and email sent when something goes wrong
+	Notifications notify.Configuration `json:"notifications"`
 }
 
 type LogConfigurations struct {
 	Access string `json:"access"`
 	Error  string `json:"error"`
+	Audit  string `json:"audit"`
+	// text or json
+	Format string `json:"format"`
+	// rotation of access and execution logs
+	Rotation logger.RotationConfiguration `json:"rotation"`
+	// level of execution log: error, warn, info, debug
+	Level string `json:"level"`
+	// syslog and journald destinations of each log stream
+	Sinks SinksConfigurations `json:"sinks"`
+}
+
+type SinksConfigurations struct {
+	Access    logger.SinksConfiguration `json:"access"`
+	Execution logger.SinksConfiguration `json:"execution"`
+}
+
+type AuthConfigurations struct {
+	// max number of requests per second and per client IP
+	RateLimit float64 `json:"ratelimit"`
+	Burst     int     `json:"burst"`
+	// number of consecutive failures before a client IP is locked out
+	MaxFailures int `json:"maxfailures"`
+	// lockout durations in seconds
+	Lockout    uint `json:"lockout"`
+	MaxLockout uint `json:"maxlockout"`
+	// reverse proxies allowed to set X-Forwarded-For header: IP addresses or CIDR ranges
+	TrustedProxies []string `json:"trustedproxies"`
 }
 
 type SslConfigurations struct {
@@ -41,18 +82,34 @@ type SslConfigurations struct {
 	Enabled     bool   `json:"enabled"`
 }
 
-var Configuration Configurations
+var (
+	Configuration Configurations
+
+	defaultConfiguration = map[string]any{
+		"server.auth.ratelimit":   5,
+		"server.auth.burst":       10,
+		"server.auth.maxfailures": 5,
+		"server.auth.lockout":     30,
+		"server.auth.maxlockout":  3600,
+		"server.maxbodysize":      10,
+		"server.metrics":          false,
+		"server.log.format":       "text",
+		"server.log.level":        "warn",
+		"securechange.timeout":    10,
+		"securechange.cachettl":   60,
+	}
+)
 
 func ReadConf(config_name string, verbose bool) {
 	configparser.Verbose = verbose
-	if err := configparser.ReadConf(config_name, &Configuration, nil); err != nil {
+	if err := configparser.ReadConf(config_name, &Configuration, defaultConfiguration); err != nil {
 		logrus.Fatalf("Unable to decode into struct, %v", err)
 	}
 
 }
 
 func ReadConfFromFile(abs_path string) error {
-	if err := configparser.ReadConfAbsolutePath(abs_path, &Configuration, nil); err != nil {
+	if err := configparser.ReadConfAbsolutePath(abs_path, &Configuration, defaultConfiguration); err != nil {
 		return err
 	}
 	return nil
@@ -1,21 +1,33 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"regexp"
+	"time"
 
+	"mediator/audit"
+	"mediator/health"
 	"mediator/logger"
 	"mediator/mediatorscript"
 	"mediator/mediatorsettings"
+	"mediator/metrics"
+	"mediator/notify"
+	"mediator/openapi"
+	"mediator/signature"
 	"mediator/totp"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
 )
 
+// API is documented in openapi/openapi.json and served on /v1/openapi.json
+
 // @title Mediator Back-end API
 // @version 1.0
 // @description UquidIT.co back-end server
@@ -57,45 +69,97 @@ func main() {
 	}
 
 	// init traditional logger
+	var (
+		l   *logrus.Logger
+		err error
+	)
+	level, err := logrus.ParseLevel(Configuration.Server.Log.Level)
+	if err != nil {
+		logrus.Fatalf("invalid log level '%s': %v", Configuration.Server.Log.Level, err)
+	}
 	if Configuration.Server.Log.Error == "" || Configuration.Server.Log.Error == "-" {
-		if _, err := logger.InitAppLogger(true, logrus.WarnLevel, true, false, false, true, false, true, "", ""); err != nil {
+		if l, err = logger.InitAppLogger(true, level, true, false, false, true, false, true, "", ""); err != nil {
 			logrus.Fatalf("error while initializing logger: %v", err)
 		}
 	} else {
-		if _, err := logger.InitAppLogger(true, logrus.WarnLevel, false, true, false, true, true, true, "", Configuration.Server.Log.Error); err != nil {
+		if l, err = logger.InitAppLoggerWithRotation(true, level, false, true, false, true, true, true, "", Configuration.Server.Log.Error, Configuration.Server.Log.Rotation); err != nil {
 			logrus.Fatalf("error while initializing logger: %v", err)
 		}
 	}
 	defer logger.CloseLogFile()
+	if err := logger.SetLogFormat(l, Configuration.Server.Log.Format); err != nil {
+		logrus.Fatal(err)
+	}
+	if err := logger.AddSinks(l, Configuration.Server.Log.Sinks.Execution); err != nil {
+		logrus.Warningf("error while initializing execution log sinks: %v", err)
+	}
+	defer logger.CloseSinks()
+
+	// administrative actions are recorded in audit log
+	// it is disabled when not configured, but a configured audit log must work
+	if err := audit.Init(Configuration.Server.Log.Audit); errors.Is(err, audit.ErrNoAuditFile) {
+		logrus.Warning(err)
+	} else if err != nil {
+		logrus.Fatal(err)
+	}
+	defer audit.Close()
+
+	// script failures, timeouts and integrity violations are notified
+	if err := notify.Init(Configuration.Server.Notifications); err != nil {
+		logrus.Fatalf("error while initializing notifications: %v", err)
+	}
+	defer notify.Close()
 
 	// initialize mediatorscript package
 	if err := mediatorscript.Init(Configuration.Mediatorscript.ScriptStorage); err != nil {
 		logrus.Warningf("error while loading scripts for mediator list: %v", err)
 	}
+	mediatorscript.SetExecutionTimeout(time.Duration(Configuration.Mediatorscript.Timeout) * time.Second)
+	mediatorscript.SetSecurechangeAPI(Configuration.Securechange)
 
 	// Echo instance
 	e := echo.New()
 	e.HideBanner = true
+	// rate limiter, lockout and audit log identify clients by IP: do not let them choose it
+	if extractor, err := totp.IPExtractor(Configuration.Server.Auth.TrustedProxies); err != nil {
+		logrus.Fatalf("error in server.auth.trustedproxies: %v", err)
+	} else {
+		e.IPExtractor = extractor
+	}
 	e.Pre(middleware.RemoveTrailingSlash())
 	e.Pre(RemoveMultipleSlash())
 
-	logformat := "${time_rfc3339} ${remote_ip} ${method} ${path} ${status} ${latency_human} ${bytes_in} ${bytes_out}\n"
-	if Configuration.Server.Log.Access == "" || Configuration.Server.Log.Access == "-" {
-		e.Use(middleware.LoggerWithConfig(middleware.LoggerConfig{
-			Format: logformat,
-		}))
-	} else {
-		logfile, err := os.OpenFile(Configuration.Server.Log.Access, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	// echo X-Request-ID header sent by mediator-client or generate one
+	// so access log, execution log and script output can be correlated
+	e.Use(SanitizeRequestID)
+	e.Use(middleware.RequestID())
+
+	logformat := "${time_rfc3339} ${id} ${remote_ip} ${method} ${path} ${status} ${latency_human} ${bytes_in} ${bytes_out}\n"
+	if Configuration.Server.Log.Format == logger.FORMAT_JSON {
+		logformat = middleware.DefaultLoggerConfig.Format
+	}
+	var access io.Writer = os.Stdout
+	if Configuration.Server.Log.Access != "" && Configuration.Server.Log.Access != "-" {
+		logfile, err := logger.NewRotatingFile(Configuration.Server.Log.Access, Configuration.Server.Log.Rotation, true)
 		if err != nil {
 			logrus.Fatalf("error while opening logfile '%s': %v", Configuration.Server.Log.Access, err)
 		}
 		defer logfile.Close()
-
-		e.Use(middleware.LoggerWithConfig(middleware.LoggerConfig{
-			Format: logformat,
-			Output: logfile,
-		}))
+		// server stops with logrus.Fatal: deferred functions are not run
+		logrus.RegisterExitHandler(func() { logfile.Close() })
+		access = logfile
+	}
+	sinks, err := logger.NewSinksWriter(Configuration.Server.Log.Sinks.Access)
+	if err != nil {
+		logrus.Warningf("error while initializing access log sinks: %v", err)
 	}
+	if sinks != nil {
+		access = io.MultiWriter(access, sinks)
+	}
+	e.Use(middleware.LoggerWithConfig(middleware.LoggerConfig{
+		Format: logformat,
+		Output: access,
+	}))
 
 	if errs := mediatorsettings.Init(
 		Configuration.Mediatorscript.ClientConfiguration.SettingsFile,
@@ -108,6 +172,9 @@ func main() {
 	}
 
 	// Middleware
+	if Configuration.Server.Metrics {
+		e.Use(metrics.Middleware)
+	}
 	e.Use(middleware.Recover())
 	// CORS default
 	// Allows requests from any origin wth GET, HEAD, PUT, POST or DELETE method.
@@ -115,20 +182,55 @@ func main() {
 
 	// Routes
 
+	// liveness and readiness probes
+	health.AddReadinessCheck("registry", mediatorscript.CheckRegistry)
+	health.AddReadinessCheck("storage", mediatorscript.CheckStorage)
+	health.AddReadinessCheck("integrity", mediatorscript.CheckIntegrity)
+	health.AddReadinessCheck("settings-scripts", mediatorsettings.CheckScripts)
+	health.AddReadinessCheck("settings", mediatorsettings.CheckSettings)
+	health.AddReadinessCheck("audit", audit.Check)
+	health.AddHealthAPI(e)
+
+	// Prometheus metrics
+	if Configuration.Server.Metrics {
+		e.GET("/metrics", metrics.Handler)
+	}
+
 	// API current version: all entry point must be behind a version number
 	v1 := e.Group("/v1")
 	v1.Use(NoCacheHeader)
 
+	// API documentation
+	v1.GET("/openapi.json", openapi.Handler)
+
 	otp := v1.Group("/otp")
-	otp.Use(middleware.KeyAuth(totp.CheckKey))
+	// throttle clients sending invalid keys so brute force attempts are slowed down.
+	// Requests with a valid key are not throttled: all triggers of a Securechange pod come from the same IP address
+	otp.Use(middleware.RateLimiterWithConfig(middleware.RateLimiterConfig{
+		Skipper: totp.HasValidKey,
+		Store: middleware.NewRateLimiterMemoryStoreWithConfig(middleware.RateLimiterMemoryStoreConfig{
+			Rate:  rate.Limit(Configuration.Server.Auth.RateLimit),
+			Burst: Configuration.Server.Auth.Burst,
+		}),
+	}))
+	otp.Use(totp.KeyAuthWithLockout(totp.NewLockout(
+		Configuration.Server.Auth.MaxFailures,
+		time.Duration(Configuration.Server.Auth.Lockout)*time.Second,
+		time.Duration(Configuration.Server.Auth.MaxLockout)*time.Second,
+	)))
+	// every request must be signed and can only be received once.
+	// Body is read in memory to be verified: larger ones are rejected with 413
+	signature.SetMaxBodySize(Configuration.Server.MaxBodySize << 20)
+	otp.Use(signature.Verify)
 
 	// mediator-client entry points protected by otp
 	mediatorscript.AddMediatorscriptAPI(otp)
 
 	// upload and download settings
-	otp.GET("/settings", mediatorsettings.GetSettings)
-	otp.POST("/settings", mediatorsettings.SetSettings)
-	otp.POST("/settings/workflows", mediatorsettings.SetWorkflowSettings)
+	mediatorsettings.AddMediatorsettingsAPI(otp)
+
+	// query audit log
+	audit.AddAuditAPI(otp)
 
 	// auth := v1.Group("/-")
 	// auth.Use(echojwt.JWT([]byte(Configuration.Server.Secret)))
@@ -159,6 +261,20 @@ func NoCacheHeader(next echo.HandlerFunc) echo.HandlerFunc {
 	}
 }
 
+var reRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// SanitizeRequestID drops X-Request-ID headers that could mess logs up
+// so a new ID is generated instead
+func SanitizeRequestID(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		h := c.Request().Header
+		if id := h.Get(echo.HeaderXRequestID); id != "" && !reRequestID.MatchString(id) {
+			h.Del(echo.HeaderXRequestID)
+		}
+		return next(c)
+	}
+}
+
 // RemoveMultipleSlash returns a root level (before router) middleware which replaces
 // multiple slashes from the request URI by a unique slash
 //
@@ -1,2 +0,0 @@
-#!/bin/bash
-/opt/tufin/data/securechange/scripts/mediator-client  --trigger CANCEL "$1"
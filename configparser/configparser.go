@@ -67,3 +67,15 @@ func ReadConfAbsolutePath(config_name string, config any, defaults map[string]an
 	}
 	return nil
 }
+
+// Same as ReadConfAbsolutePath, but environment variables override configuration file values.
+// env maps environment variable names to configuration keys, e.g. MEDIATOR_BACKEND_URL => configuration.backend_url.
+// Precedence, highest first: environment variables that are set and not empty, configuration file, defaults.
+func ReadConfAbsolutePathWithEnv(config_name string, config any, defaults map[string]any, env map[string]string) error {
+	for variable, key := range env {
+		if err := viper.BindEnv(key, variable); err != nil {
+			return fmt.Errorf("cannot bind environment variable %s: %w", variable, err)
+		}
+	}
+	return ReadConfAbsolutePath(config_name, config, defaults)
+}
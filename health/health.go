@@ -0,0 +1,117 @@
+package health
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+)
+
+// Status values
+const (
+	STATUS_OK   = "ok"
+	STATUS_FAIL = "fail"
+)
+
+type CheckFunc func() error
+
+type check struct {
+	name string
+	run  CheckFunc
+}
+
+type CheckResult struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	// not exposed: readiness entry point is not authenticated and errors may show file paths.
+	// Errors are logged instead.
+	Error string `json:"-"`
+	// duration of the check in milliseconds
+	Duration int64 `json:"duration_ms"`
+}
+
+type Response struct {
+	Status string        `json:"status"`
+	Checks []CheckResult `json:"checks,omitempty"`
+}
+
+var (
+	mutex  sync.Mutex
+	checks []check
+	// last error of every check, to log only changes and not every probe
+	lastErrors = map[string]string{}
+)
+
+// Add a check run by readiness probe.
+// Checks are run in the order they were added.
+func AddReadinessCheck(name string, f CheckFunc) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	checks = append(checks, check{name: name, run: f})
+}
+
+// Run all readiness checks
+func Ready() *Response {
+	mutex.Lock()
+	l := append([]check{}, checks...)
+	mutex.Unlock()
+
+	res := &Response{
+		Status: STATUS_OK,
+		Checks: []CheckResult{},
+	}
+	for _, c := range l {
+		start := time.Now()
+		err := c.run()
+		r := CheckResult{
+			Name:     c.name,
+			Status:   STATUS_OK,
+			Duration: time.Since(start).Milliseconds(),
+		}
+		if err != nil {
+			r.Status = STATUS_FAIL
+			r.Error = err.Error()
+			res.Status = STATUS_FAIL
+		}
+		logChange(r)
+		res.Checks = append(res.Checks, r)
+	}
+	return res
+}
+
+// Log check result if it changed since last run
+func logChange(r CheckResult) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if previous := lastErrors[r.Name]; previous == r.Error {
+		return
+	} else if r.Error != "" {
+		logrus.Warningf("Readiness check '%s' failed: %s", r.Name, r.Error)
+	} else {
+		logrus.Infof("Readiness check '%s' is ok again", r.Name)
+	}
+	lastErrors[r.Name] = r.Error
+}
+
+// Register /healthz and /readyz entry points.
+// They are not authenticated so systemd, load balancers or monitoring can use them.
+func AddHealthAPI(e *echo.Echo) {
+	e.GET("/healthz", Liveness)
+	e.GET("/readyz", Readiness)
+}
+
+// Liveness always succeeds as long as the server answers
+func Liveness(c echo.Context) error {
+	return c.JSON(http.StatusOK, &Response{Status: STATUS_OK})
+}
+
+// Readiness returns 503 if any check fails
+func Readiness(c echo.Context) error {
+	res := Ready()
+	if res.Status != STATUS_OK {
+		return c.JSON(http.StatusServiceUnavailable, res)
+	}
+	return c.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,83 @@
+package health
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestReadiness(t *testing.T) {
+	e := echo.New()
+	AddHealthAPI(e)
+
+	tests := []struct {
+		name   string
+		checks []check
+		code   int
+		status string
+	}{
+		{"no check", nil, http.StatusOK, STATUS_OK},
+		{"all ok", []check{
+			{"first", func() error { return nil }},
+			{"second", func() error { return nil }},
+		}, http.StatusOK, STATUS_OK},
+		{"one failure", []check{
+			{"first", func() error { return nil }},
+			{"second", func() error { return errors.New("broken") }},
+		}, http.StatusServiceUnavailable, STATUS_FAIL},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checks = nil
+			for _, c := range tt.checks {
+				AddReadinessCheck(c.name, c.run)
+			}
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			if strings.Contains(rec.Body.String(), "broken") {
+				t.Errorf("check error is exposed: %s", rec.Body.String())
+			}
+			if rec.Code != tt.code {
+				t.Errorf("status code = %d, want %d", rec.Code, tt.code)
+			}
+			var res Response
+			if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+				t.Fatalf("invalid JSON: %v", err)
+			}
+			if res.Status != tt.status {
+				t.Errorf("status = %s, want %s", res.Status, tt.status)
+			}
+			if len(res.Checks) != len(tt.checks) {
+				t.Fatalf("%d check results, want %d", len(res.Checks), len(tt.checks))
+			}
+			for i, r := range res.Checks {
+				if r.Name != tt.checks[i].name {
+					t.Errorf("check %d name = %s, want %s", i, r.Name, tt.checks[i].name)
+				}
+			}
+			for i, r := range Ready().Checks {
+				if (r.Status == STATUS_FAIL) != (r.Error != "") {
+					t.Errorf("check %d: status %s with error '%s'", i, r.Status, r.Error)
+				}
+			}
+		})
+	}
+}
+
+func TestLiveness(t *testing.T) {
+	e := echo.New()
+	AddHealthAPI(e)
+	checks = []check{{"failing", func() error { return errors.New("broken") }}}
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("liveness must not depend on readiness checks: got %d", rec.Code)
+	}
+}
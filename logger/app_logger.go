@@ -13,18 +13,17 @@ import (
 )
 
 var currentPid int
-var logFile *os.File
+var logFile *RotatingFile
 var pidFlag bool
 var functionFlag bool
 var nanoSecondsFlag bool
 
-func InitAppLoggerWithConfigFile(configFilePath string) error {
-	//TODO define configuration file format
-	//TODO parse configuration and call InitAppLogger(logInStdOut bool, logInFile bool, withPid bool, withFunction bool, appendFile bool, folder string, filename string)
-	return nil
+func InitAppLogger(defaultLogger bool, logLevel logrus.Level, logInStdOut bool, logInFile bool, withPid bool, withFunction bool, appendFile bool, withNanoSeconds bool, folder string, filename string) (*logrus.Logger, error) {
+	return InitAppLoggerWithRotation(defaultLogger, logLevel, logInStdOut, logInFile, withPid, withFunction, appendFile, withNanoSeconds, folder, filename, RotationConfiguration{})
 }
 
-func InitAppLogger(defaultLogger bool, logLevel logrus.Level, logInStdOut bool, logInFile bool, withPid bool, withFunction bool, appendFile bool, withNanoSeconds bool, folder string, filename string) (*logrus.Logger, error) {
+// Same as InitAppLogger. Log file is rotated according to provided settings.
+func InitAppLoggerWithRotation(defaultLogger bool, logLevel logrus.Level, logInStdOut bool, logInFile bool, withPid bool, withFunction bool, appendFile bool, withNanoSeconds bool, folder string, filename string, rotation RotationConfiguration) (*logrus.Logger, error) {
 	if !logInFile && !logInStdOut {
 		return nil, fmt.Errorf("cannot init logger: choose log in Stdout or log in file or both")
 	}
@@ -60,10 +59,13 @@ func InitAppLogger(defaultLogger bool, logLevel logrus.Level, logInStdOut bool,
 		} else {
 			logfolder = logf
 		}
-		file, err := getLogfile(logfolder, filename, appendFile)
+		file, err := getLogfile(logfolder, filename, appendFile, rotation)
 		if err != nil {
 			return nil, fmt.Errorf("cannot init logger: invalid file, %w", err)
 		}
+		logFile = file
+		// logrus.Fatal does not run deferred functions: close file so rotated files are compressed
+		logrus.RegisterExitHandler(CloseLogFile)
 		if logInStdOut {
 			logger.SetOutput(io.MultiWriter(os.Stderr, file))
 		} else {
@@ -94,7 +96,7 @@ func (f *DefaultLogFormatter) Format(entry *logrus.Entry) ([]byte, error) {
 	if functionFlag && entry.Caller != nil {
 		logMessage += "[" + entry.Caller.Function + "." + strconv.Itoa(entry.Caller.Line) + "]"
 	}
-	logMessage += " " + entry.Message + "\n"
+	logMessage += " " + entry.Message + formatFields(entry.Data) + "\n"
 	return []byte(logMessage), nil
 }
 
@@ -102,26 +104,16 @@ func CloseLogFile() {
 	if logFile != nil {
 		logFile.Sync()
 		logFile.Close()
+		logFile = nil
 	}
 }
 
-func getLogfile(folder string, filename string, flag_append bool) (*os.File, error) {
+func getLogfile(folder string, filename string, flag_append bool, rotation RotationConfiguration) (*RotatingFile, error) {
 	name := ""
 	if !filepath.IsAbs(filename) {
 		name = filepath.Clean(filepath.Join(folder + "/" + filename))
 	} else {
 		name = filepath.Clean(filename)
 	}
-	flags := os.O_CREATE | os.O_WRONLY
-	if flag_append {
-		flags |= os.O_APPEND
-	} else {
-		flags |= os.O_TRUNC
-	}
-
-	file, err := os.OpenFile(name, flags, 0666)
-	if err != nil {
-		return nil, err
-	}
-	return file, nil
+	return NewRotatingFile(name, rotation, flag_append)
 }
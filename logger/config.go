@@ -0,0 +1,59 @@
+package logger
+
+import (
+	"fmt"
+
+	"mediator/configparser"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Logging settings as found in configuration files
+type LogConfiguration struct {
+	// full path of log file. Log to stderr if empty or "-"
+	File string `json:"file" mapstructure:"file"`
+	// panic, fatal, error, warn, info, debug or trace
+	Level string `json:"level" mapstructure:"level"`
+	// text or json
+	Format   string                `json:"format" mapstructure:"format"`
+	Rotation RotationConfiguration `json:"rotation" mapstructure:"rotation"`
+}
+
+// Initialize standard logger using settings read from a YAML file:
+//
+//	file: /var/log/mediator.log
+//	level: info
+//	format: text
+//	rotation:
+//	  maxsize: 10
+//	  maxbackups: 5
+func InitAppLoggerWithConfigFile(configFilePath string) error {
+	var conf LogConfiguration
+	if err := configparser.ReadConfAbsolutePath(configFilePath, &conf, nil); err != nil {
+		return fmt.Errorf("cannot init logger: %w", err)
+	}
+	_, err := InitAppLoggerWithConfig(&conf)
+	return err
+}
+
+// Initialize standard logger using provided settings
+func InitAppLoggerWithConfig(conf *LogConfiguration) (*logrus.Logger, error) {
+	level, err := logrus.ParseLevel(conf.Level)
+	if err != nil {
+		level = logrus.WarnLevel
+	}
+
+	var l *logrus.Logger
+	if conf.File == "" || conf.File == "-" {
+		l, err = InitAppLogger(true, level, true, false, false, true, false, true, "", "")
+	} else {
+		l, err = InitAppLoggerWithRotation(true, level, false, true, true, true, true, true, "", conf.File, conf.Rotation)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := SetLogFormat(l, conf.Format); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
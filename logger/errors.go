@@ -0,0 +1,10 @@
+package logger
+
+import "errors"
+
+var (
+	ErrUnknownSyslogFacility = errors.New("unknown syslog facility")
+	ErrUnknownSyslogNetwork  = errors.New("unknown syslog network: use udp, tcp or unix")
+	ErrNoSyslogAddress       = errors.New("no address")
+	ErrNoJournald            = errors.New("journald socket not found")
+)
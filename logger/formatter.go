@@ -0,0 +1,127 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Supported log formats
+const (
+	FORMAT_TEXT = "text"
+	FORMAT_JSON = "json"
+)
+
+// Field names used to correlate log lines
+const (
+	FIELD_REQUEST_ID  = "request_id"
+	FIELD_TICKET_ID   = "ticket_id"
+	FIELD_SCRIPT      = "script"
+	FIELD_SCRIPT_TYPE = "script_type"
+	FIELD_OUTCOME     = "outcome"
+	FIELD_EXIT_CODE   = "exit_code"
+	FIELD_DURATION    = "duration_ms"
+)
+
+// Set log format of given logger: text (default) or json
+func SetLogFormat(logger *logrus.Logger, format string) error {
+	switch strings.ToLower(format) {
+	case "", FORMAT_TEXT:
+		logger.SetFormatter(new(DefaultLogFormatter))
+	case FORMAT_JSON:
+		logger.SetFormatter(new(JSONLogFormatter))
+	default:
+		return fmt.Errorf("unknown log format '%s': use %s or %s", format, FORMAT_TEXT, FORMAT_JSON)
+	}
+	return nil
+}
+
+// JSONLogFormatter writes one JSON object per line.
+// Entry fields (request_id, ticket_id, etc) are added as top level keys.
+type JSONLogFormatter struct{}
+
+func (f *JSONLogFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	data := make(map[string]any, len(entry.Data)+5)
+	for k, v := range entry.Data {
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		data[k] = v
+	}
+	if nanoSecondsFlag {
+		data["time"] = entry.Time.Format(time.RFC3339Nano)
+	} else {
+		data["time"] = entry.Time.Format(time.RFC3339)
+	}
+	data["level"] = entry.Level.String()
+	data["msg"] = entry.Message
+	if pidFlag {
+		data["pid"] = currentPid
+	}
+	if functionFlag && entry.Caller != nil {
+		data["function"] = entry.Caller.Function + "." + strconv.Itoa(entry.Caller.Line)
+	}
+
+	b, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal log entry: %w", err)
+	}
+	return append(b, '\n'), nil
+}
+
+// format entry fields as " key=value" sorted by key
+func formatFields(data logrus.Fields) string {
+	if len(data) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	s := ""
+	for _, k := range keys {
+		s += fmt.Sprintf(" %s=%v", k, data[k])
+	}
+	return s
+}
+
+// fieldsHook adds fields to every entry of a logger
+type fieldsHook struct {
+	mutex  sync.RWMutex
+	fields logrus.Fields
+}
+
+func (h *fieldsHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *fieldsHook) Fire(entry *logrus.Entry) error {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	for k, v := range h.fields {
+		if _, exist := entry.Data[k]; !exist {
+			entry.Data[k] = v
+		}
+	}
+	return nil
+}
+
+var defaultFields = &fieldsHook{fields: logrus.Fields{}}
+
+// Add a field to every line logged by the standard logger from now on.
+// mediator-client uses it so that all its log lines carry the request and ticket IDs.
+func AddDefaultField(key string, value any) {
+	defaultFields.mutex.Lock()
+	defer defaultFields.mutex.Unlock()
+	if len(defaultFields.fields) == 0 {
+		logrus.AddHook(defaultFields)
+	}
+	defaultFields.fields[key] = value
+}
@@ -0,0 +1,79 @@
+package logger
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestJSONLogFormatter(t *testing.T) {
+	entry := logrus.NewEntry(logrus.New()).WithFields(logrus.Fields{
+		FIELD_REQUEST_ID: "abc",
+		FIELD_TICKET_ID:  42,
+		"error":          errors.New("boom"),
+	})
+	entry.Time = time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	entry.Level = logrus.WarnLevel
+	entry.Message = "script failed"
+
+	b, err := new(JSONLogFormatter).Format(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(string(b), "}\n") {
+		t.Errorf("one JSON object per line expected, got %q", b)
+	}
+	var data map[string]any
+	if err := json.Unmarshal(b, &data); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	for k, want := range map[string]any{
+		"msg":            "script failed",
+		"level":          "warning",
+		"time":           "2024-05-01T10:00:00Z",
+		FIELD_REQUEST_ID: "abc",
+		FIELD_TICKET_ID:  float64(42),
+		"error":          "boom",
+	} {
+		if data[k] != want {
+			t.Errorf("%s = %v, want %v", k, data[k], want)
+		}
+	}
+}
+
+func TestDefaultLogFormatter_fields(t *testing.T) {
+	entry := logrus.NewEntry(logrus.New()).WithFields(logrus.Fields{
+		FIELD_TICKET_ID:  42,
+		FIELD_REQUEST_ID: "abc",
+	})
+	entry.Message = "script failed"
+
+	b, err := new(DefaultLogFormatter).Format(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(string(b), " script failed request_id=abc ticket_id=42\n") {
+		t.Errorf("fields must be sorted after message, got %q", b)
+	}
+}
+
+func TestSetLogFormat(t *testing.T) {
+	l := logrus.New()
+	if err := SetLogFormat(l, "JSON"); err != nil {
+		t.Errorf("SetLogFormat(json) error = %v", err)
+	} else if _, ok := l.Formatter.(*JSONLogFormatter); !ok {
+		t.Errorf("JSON formatter expected")
+	}
+	if err := SetLogFormat(l, ""); err != nil {
+		t.Errorf("SetLogFormat('') error = %v", err)
+	} else if _, ok := l.Formatter.(*DefaultLogFormatter); !ok {
+		t.Errorf("default formatter expected")
+	}
+	if err := SetLogFormat(l, "xml"); err == nil {
+		t.Errorf("SetLogFormat(xml) expected an error")
+	}
+}
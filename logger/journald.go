@@ -0,0 +1,145 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// journald destination of a log stream.
+// Messages are sent using the native protocol so entry fields become journal fields
+// (i.e. request_id is sent as REQUEST_ID and can be queried with journalctl REQUEST_ID=...).
+type JournaldConfiguration struct {
+	Enabled bool `json:"enabled" mapstructure:"enabled"`
+	// SYSLOG_IDENTIFIER field (default executable name)
+	Identifier string `json:"identifier" mapstructure:"identifier"`
+}
+
+const JOURNALD_SOCKET = "/run/systemd/journal/socket"
+
+// JournaldWriter sends messages to journald
+type JournaldWriter struct {
+	mutex      sync.Mutex
+	conn       *net.UnixConn
+	identifier string
+}
+
+func NewJournaldWriter(conf JournaldConfiguration) (*JournaldWriter, error) {
+	return newJournaldWriter(conf, JOURNALD_SOCKET)
+}
+
+func newJournaldWriter(conf JournaldConfiguration, socket string) (*JournaldWriter, error) {
+	if _, err := os.Stat(socket); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoJournald, socket)
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to journald: %w", err)
+	}
+	w := &JournaldWriter{
+		conn:       conn,
+		identifier: conf.Identifier,
+	}
+	if w.identifier == "" {
+		w.identifier = filepath.Base(os.Args[0])
+	}
+	return w, nil
+}
+
+// Send a message with given priority and fields
+func (w *JournaldWriter) Send(priority int, msg string, fields logrus.Fields) error {
+	data := journaldMessage(priority, w.identifier, msg, fields)
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	_, err := w.conn.Write(data)
+	return err
+}
+
+// Serialize a message according to journald native protocol.
+// cf https://systemd.io/JOURNAL_NATIVE_PROTOCOL/
+func journaldMessage(priority int, identifier string, msg string, fields logrus.Fields) []byte {
+	var buf bytes.Buffer
+	writeJournaldField(&buf, "MESSAGE", msg)
+	writeJournaldField(&buf, "PRIORITY", fmt.Sprint(priority))
+	writeJournaldField(&buf, "SYSLOG_IDENTIFIER", identifier)
+
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := fields[k]
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		if name := journaldFieldName(k); name != "" {
+			writeJournaldField(&buf, name, fmt.Sprint(v))
+		}
+	}
+	return buf.Bytes()
+}
+
+func writeJournaldField(buf *bytes.Buffer, name, value string) {
+	if !strings.Contains(value, "\n") {
+		buf.WriteString(name + "=" + value + "\n")
+		return
+	}
+	// values containing a new line are sent as: name, new line, little endian 64 bits length, value, new line
+	buf.WriteString(name + "\n")
+	binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	buf.WriteString(value + "\n")
+}
+
+// Field names are made of uppercase letters, digits and underscores and cannot start with an underscore
+func journaldFieldName(s string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+	name = strings.TrimLeft(name, "_")
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
+
+// Write sends p as an informational message. It is used for access logs.
+func (w *JournaldWriter) Write(p []byte) (int, error) {
+	if err := w.Send(severityInfo, strings.TrimRight(string(p), "\n"), nil); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *JournaldWriter) Close() error {
+	return w.conn.Close()
+}
+
+// JournaldHook sends log entries to journald. Entry fields are sent as journal fields.
+type JournaldHook struct {
+	writer *JournaldWriter
+}
+
+func (h *JournaldHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *JournaldHook) Fire(entry *logrus.Entry) error {
+	return h.writer.Send(severity(entry.Level), entry.Message, entry.Data)
+}
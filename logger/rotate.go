@@ -0,0 +1,412 @@
+package logger
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// Rotation settings of a log file.
+// Zero values disable the related feature: by default, files are never rotated.
+type RotationConfiguration struct {
+	// rotate when file size would exceed this many megabytes
+	MaxSize uint `json:"maxsize" mapstructure:"maxsize"`
+	// rotate when a new period of this many hours starts (periods are aligned on UTC, i.e. 24 rotates at midnight UTC)
+	RotateEvery uint `json:"rotateevery" mapstructure:"rotateevery"`
+	// max number of rotated files to keep
+	MaxBackups uint `json:"maxbackups" mapstructure:"maxbackups"`
+	// remove rotated files older than this many days
+	MaxAge uint `json:"maxage" mapstructure:"maxage"`
+	// compress rotated files with gzip
+	Compress bool `json:"compress" mapstructure:"compress"`
+}
+
+func (c RotationConfiguration) IsEnabled() bool {
+	return c.MaxSize > 0 || c.RotateEvery > 0
+}
+
+const (
+	megabyte = 1024 * 1024
+	// suffix added to rotated files
+	backupTimeFormat = "20060102T150405.000"
+	compressSuffix   = ".gz"
+)
+
+// RotatingFile is a log file that is rotated according to its size and age.
+// Rotated files are renamed <filename>.<timestamp>, optionally compressed, and removed
+// according to retention settings.
+// Several processes can write to the same file: rotation is serialized with a lock
+// on <filename>.lock, and processes that did not rotate reopen the new file.
+// Lock is only taken when rotation may be needed or every ROTATION_CHECK_WRITES writes or ROTATION_CHECK_INTERVAL,
+// so a shared file may grow a little over MaxSize.
+type RotatingFile struct {
+	mutex     sync.Mutex
+	filename  string
+	conf      RotationConfiguration
+	file      *os.File
+	size      int64
+	lastWrite time.Time
+	// last time size written by other processes and rotation by them were checked, and writes since then
+	lastCheck time.Time
+	writes    int
+	// locked by the process that checks size and rotates. Nil if rotation is disabled
+	lock *os.File
+	// rotated files are compressed and cleaned up in background
+	wg  sync.WaitGroup
+	now func() time.Time
+}
+
+// suffix of the file used to serialize rotation between processes
+const lockSuffix = ".lock"
+
+// How often a process checks whether others rotated or grew the file.
+// Until then, it may still write to a file rotated by another process: rotated files are only compressed
+// once they are older than ROTATION_CHECK_INTERVAL twice.
+const (
+	ROTATION_CHECK_WRITES   = 100
+	ROTATION_CHECK_INTERVAL = time.Second
+)
+
+// Open log file. Data is appended to existing file if flag_append is true, otherwise it is truncated.
+// A file left over by a previous run is rotated straight away if it is too big or too old.
+func NewRotatingFile(filename string, conf RotationConfiguration, flag_append bool) (*RotatingFile, error) {
+	f := &RotatingFile{
+		filename: filepath.Clean(filename),
+		conf:     conf,
+		now:      time.Now,
+	}
+	if conf.IsEnabled() {
+		lock, err := os.OpenFile(f.filename+lockSuffix, os.O_CREATE|os.O_RDWR, 0666)
+		if err != nil {
+			return nil, err
+		}
+		f.lock = lock
+	}
+	if err := f.open(!flag_append); err != nil {
+		if f.lock != nil {
+			f.lock.Close()
+		}
+		return nil, err
+	}
+	return f, nil
+}
+
+// Open log file. It is always opened in append mode as other processes may write to it.
+func (f *RotatingFile) open(truncate bool) error {
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if truncate {
+		flags |= os.O_TRUNC
+	}
+	file, err := os.OpenFile(f.filename, flags, 0666)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file = file
+	f.size = info.Size()
+	f.lastWrite = info.ModTime()
+	return nil
+}
+
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	now := f.now()
+	if f.lock != nil {
+		f.writes++
+		if f.shouldRotate(len(p), now) || f.writes >= ROTATION_CHECK_WRITES || now.Sub(f.lastCheck) >= ROTATION_CHECK_INTERVAL {
+			if err := f.checkRotation(len(p), now); err != nil {
+				return 0, err
+			}
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	f.lastWrite = now
+	return n, err
+}
+
+// Rotate file if needed, once other processes are taken into account
+func (f *RotatingFile) checkRotation(length int, now time.Time) error {
+	if err := f.lockFile(); err != nil {
+		return err
+	}
+	defer f.unlockFile()
+
+	f.lastCheck, f.writes = now, 0
+	if err := f.reopenIfRotated(); err != nil {
+		return err
+	}
+	if f.shouldRotate(length, now) {
+		return f.rotate(now)
+	}
+	return nil
+}
+
+func (f *RotatingFile) lockFile() error {
+	if err := unix.Flock(int(f.lock.Fd()), unix.LOCK_EX); err != nil {
+		return fmt.Errorf("cannot lock log file '%s': %w", f.filename, err)
+	}
+	return nil
+}
+
+func (f *RotatingFile) unlockFile() {
+	unix.Flock(int(f.lock.Fd()), unix.LOCK_UN)
+}
+
+// Reopen log file if another process rotated it, otherwise update its size with
+// what other processes wrote. Caller must hold file lock.
+func (f *RotatingFile) reopenIfRotated() error {
+	current, err := f.file.Stat()
+	if err != nil {
+		return err
+	}
+	if info, err := os.Stat(f.filename); err == nil && os.SameFile(info, current) {
+		f.size = current.Size()
+		return nil
+	} else if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+	return f.open(false)
+}
+
+func (f *RotatingFile) shouldRotate(length int, now time.Time) bool {
+	if f.size == 0 {
+		// never rotate an empty file
+		return false
+	}
+	if f.conf.MaxSize > 0 && f.size+int64(length) > int64(f.conf.MaxSize)*megabyte {
+		return true
+	}
+	if f.conf.RotateEvery > 0 {
+		period := time.Duration(f.conf.RotateEvery) * time.Hour
+		return now.UTC().Truncate(period).After(f.lastWrite.UTC().Truncate(period))
+	}
+	return false
+}
+
+// Rotate closes current file, renames it and opens a new one
+func (f *RotatingFile) Rotate() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.lock != nil {
+		if err := f.lockFile(); err != nil {
+			return err
+		}
+		defer f.unlockFile()
+	}
+	return f.rotate(f.now())
+}
+
+// Caller must hold file lock
+func (f *RotatingFile) rotate(now time.Time) error {
+	if f.file != nil {
+		if err := f.file.Close(); err != nil {
+			return err
+		}
+		f.file = nil
+	}
+	backup := f.backupName(now)
+	if err := os.Rename(f.filename, backup); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("cannot rotate log file '%s': %w", f.filename, err)
+	}
+	// never truncate: another process may have created the new file already
+	if err := f.open(false); err != nil {
+		return err
+	}
+
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		f.postRotate(now)
+	}()
+	return nil
+}
+
+// Name of rotated file. Another process may have rotated the file during the same millisecond:
+// existing backups are not overwritten. Caller must hold file lock.
+func (f *RotatingFile) backupName(now time.Time) string {
+	for {
+		backup := f.filename + "." + now.Format(backupTimeFormat)
+		if _, err := os.Lstat(backup); os.IsNotExist(err) {
+			if _, err := os.Lstat(backup + compressSuffix); os.IsNotExist(err) {
+				return backup
+			}
+		}
+		now = now.Add(time.Millisecond)
+	}
+}
+
+// compress rotated files and remove old ones.
+// Files rotated less than 2*ROTATION_CHECK_INTERVAL ago may still be written by other processes:
+// they are compressed on next rotation.
+// errors are written to stderr as the logger may be the one that failed.
+var postRotateMutex sync.Mutex
+
+func (f *RotatingFile) postRotate(now time.Time) {
+	postRotateMutex.Lock()
+	defer postRotateMutex.Unlock()
+
+	if f.conf.Compress {
+		if err := f.compressBackups(now.Add(-2 * ROTATION_CHECK_INTERVAL)); err != nil {
+			fmt.Fprintf(os.Stderr, "cannot compress rotated log files: %v\n", err)
+		}
+	}
+	if err := f.removeOldBackups(); err != nil {
+		fmt.Fprintf(os.Stderr, "cannot remove old log files: %v\n", err)
+	}
+}
+
+type backupFile struct {
+	path string
+	time time.Time
+}
+
+// Return rotated files, most recent first
+func (f *RotatingFile) listBackups() ([]backupFile, error) {
+	dir := filepath.Dir(f.filename)
+	prefix := filepath.Base(f.filename) + "."
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	backups := []backupFile{}
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		ts := strings.TrimSuffix(strings.TrimPrefix(name, prefix), compressSuffix)
+		if t, err := time.ParseInLocation(backupTimeFormat, ts, time.Local); err == nil {
+			backups = append(backups, backupFile{path: filepath.Join(dir, name), time: t})
+		}
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].time.After(backups[j].time)
+	})
+	return backups, nil
+}
+
+// Compress rotated files rotated before given time.
+// Other processes compress the same files: they are listed and compressed under file lock,
+// taken on a file descriptor of its own so the lock of writes is left alone.
+func (f *RotatingFile) compressBackups(before time.Time) error {
+	lock, err := os.OpenFile(f.filename+lockSuffix, os.O_CREATE|os.O_RDWR, 0666)
+	if err != nil {
+		return err
+	}
+	defer lock.Close()
+	if err := unix.Flock(int(lock.Fd()), unix.LOCK_EX); err != nil {
+		return err
+	}
+	defer unix.Flock(int(lock.Fd()), unix.LOCK_UN)
+
+	backups, err := f.listBackups()
+	if err != nil {
+		return err
+	}
+	for _, b := range backups {
+		if !strings.HasSuffix(b.path, compressSuffix) && !b.time.After(before) {
+			if err := compressFile(b.path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (f *RotatingFile) removeOldBackups() error {
+	if f.conf.MaxBackups == 0 && f.conf.MaxAge == 0 {
+		return nil
+	}
+	backups, err := f.listBackups()
+	if err != nil {
+		return err
+	}
+	cutoff := f.now().Add(-time.Duration(f.conf.MaxAge) * 24 * time.Hour)
+	for i, b := range backups {
+		if (f.conf.MaxBackups > 0 && uint(i) >= f.conf.MaxBackups) ||
+			(f.conf.MaxAge > 0 && b.time.Before(cutoff)) {
+			if err := os.Remove(b.path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func compressFile(filename string) error {
+	src, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(filename+compressSuffix, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0666)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		gz.Close()
+		dst.Close()
+		os.Remove(filename + compressSuffix)
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		dst.Close()
+		os.Remove(filename + compressSuffix)
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(filename + compressSuffix)
+		return err
+	}
+	return os.Remove(filename)
+}
+
+// Close file and wait for background compression and cleanup
+func (f *RotatingFile) Close() error {
+	f.mutex.Lock()
+	var err error
+	if f.file != nil {
+		err = f.file.Close()
+		f.file = nil
+	}
+	if f.lock != nil {
+		f.lock.Close()
+		f.lock = nil
+	}
+	f.mutex.Unlock()
+	f.wg.Wait()
+	return err
+}
+
+func (f *RotatingFile) Sync() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	return f.file.Sync()
+}
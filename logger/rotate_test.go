@@ -0,0 +1,208 @@
+package logger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestRotatingFile(t *testing.T, conf RotationConfiguration, now *time.Time) *RotatingFile {
+	t.Helper()
+	f, err := NewRotatingFile(filepath.Join(t.TempDir(), "test.log"), conf, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.now = func() time.Time { return *now }
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func listFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, e := range entries {
+		if !strings.HasSuffix(e.Name(), lockSuffix) {
+			names = append(names, e.Name())
+		}
+	}
+	return names
+}
+
+func TestRotatingFile_size(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.Local)
+	f := newTestRotatingFile(t, RotationConfiguration{MaxSize: 1}, &now)
+	line := []byte(strings.Repeat("x", 400*1024))
+
+	for i := 0; i < 3; i++ {
+		now = now.Add(time.Second)
+		if _, err := f.Write(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f.wg.Wait()
+	if files := listFiles(t, filepath.Dir(f.filename)); len(files) != 2 {
+		t.Errorf("1 rotated file expected after 1.2 MB, got %v", files)
+	}
+	if f.size != int64(len(line)) {
+		t.Errorf("current file size = %d, want %d", f.size, len(line))
+	}
+}
+
+func TestRotatingFile_period(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	f := newTestRotatingFile(t, RotationConfiguration{RotateEvery: 24}, &now)
+
+	steps := []struct {
+		name  string
+		at    time.Time
+		files int
+	}{
+		{"first line", time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), 1},
+		{"same day", time.Date(2024, 5, 1, 23, 59, 59, 0, time.UTC), 1},
+		{"next day", time.Date(2024, 5, 2, 0, 0, 1, 0, time.UTC), 2},
+		{"same day again", time.Date(2024, 5, 2, 18, 0, 0, 0, time.UTC), 2},
+		{"several days later", time.Date(2024, 5, 5, 9, 0, 0, 0, time.UTC), 3},
+		{"clock goes back", time.Date(2024, 5, 4, 9, 0, 0, 0, time.UTC), 3},
+	}
+	for _, step := range steps {
+		now = step.at
+		if _, err := f.Write([]byte("line\n")); err != nil {
+			t.Fatal(err)
+		}
+		f.wg.Wait()
+		if files := listFiles(t, filepath.Dir(f.filename)); len(files) != step.files {
+			t.Errorf("%s: %d files expected, got %v", step.name, step.files, files)
+		}
+	}
+}
+
+func TestRotatingFile_retention(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.Local)
+	f := newTestRotatingFile(t, RotationConfiguration{MaxSize: 1, MaxBackups: 2, MaxAge: 3, Compress: true}, &now)
+	dir := filepath.Dir(f.filename)
+
+	for i := 0; i < 4; i++ {
+		now = now.Add(time.Hour)
+		f.Write([]byte("line\n"))
+		if err := f.Rotate(); err != nil {
+			t.Fatal(err)
+		}
+		f.wg.Wait()
+	}
+	files := listFiles(t, dir)
+	if len(files) != 3 {
+		t.Fatalf("current file and 2 backups expected, got %v", files)
+	}
+	// last rotated file may still be written by other processes: it is compressed on next rotation
+	for _, name := range files {
+		last := name == fmt.Sprintf("test.log.%s", now.Format(backupTimeFormat))
+		if name != "test.log" && strings.HasSuffix(name, compressSuffix) == last {
+			t.Errorf("rotated file %s: compressed = %v, want %v", name, !last, last)
+		}
+	}
+
+	// backups older than 3 days are removed
+	now = now.Add(4 * 24 * time.Hour)
+	f.Write([]byte("line\n"))
+	if err := f.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	f.wg.Wait()
+	if files := listFiles(t, dir); len(files) != 2 {
+		t.Errorf("current file and last backup expected, got %v", files)
+	}
+}
+
+// Two processes writing to the same file: every line is kept once, in current or rotated files
+func TestRotatingFile_shared(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "test.log")
+	conf := RotationConfiguration{MaxSize: 1, Compress: true}
+	files := []*RotatingFile{}
+	for range 2 {
+		f, err := NewRotatingFile(filename, conf, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, f)
+	}
+
+	padding := strings.Repeat("x", 100*1024)
+	nb_lines := 30
+	for i := range nb_lines {
+		if _, err := fmt.Fprintf(files[i%2], "%02d %s\n", i, padding); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, f := range files {
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var content bytes.Buffer
+	for _, name := range listFiles(t, dir) {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.HasSuffix(name, compressSuffix) {
+			gz, err := gzip.NewReader(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if data, err = io.ReadAll(gz); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// every process rotates once its own writes exceed max size, so files may grow up to twice as big
+		if len(data) > 2*megabyte {
+			t.Errorf("%s is %d bytes long", name, len(data))
+		}
+		content.Write(data)
+	}
+	for i := range nb_lines {
+		if n := strings.Count(content.String(), fmt.Sprintf("%02d %s\n", i, padding)); n != 1 {
+			t.Errorf("line %d found %d times", i, n)
+		}
+	}
+}
+
+// Rotation by another process is only noticed every ROTATION_CHECK_INTERVAL: lines written meanwhile go to rotated file
+func TestRotatingFile_checkInterval(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.Local)
+	f := newTestRotatingFile(t, RotationConfiguration{MaxSize: 1}, &now)
+	other, err := NewRotatingFile(f.filename, f.conf, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other.now = func() time.Time { return now }
+	t.Cleanup(func() { other.Close() })
+
+	f.Write([]byte("first\n"))
+	if err := other.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("before check\n"))
+	now = now.Add(ROTATION_CHECK_INTERVAL)
+	f.Write([]byte("after check\n"))
+
+	backup, _ := os.ReadFile(f.filename + "." + time.Date(2024, 5, 1, 10, 0, 0, 0, time.Local).Format(backupTimeFormat))
+	current, _ := os.ReadFile(f.filename)
+	if string(backup) != "first\nbefore check\n" {
+		t.Errorf("rotated file = %q", backup)
+	}
+	if string(current) != "after check\n" {
+		t.Errorf("current file = %q", current)
+	}
+}
@@ -0,0 +1,78 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Extra destinations of a log stream, on top of stderr or log file
+type SinksConfiguration struct {
+	Syslog   SyslogConfiguration   `json:"syslog" mapstructure:"syslog"`
+	Journald JournaldConfiguration `json:"journald" mapstructure:"journald"`
+}
+
+var sinks []io.Closer
+
+// Send entries of given logger to configured sinks.
+// A sink that cannot be opened is dropped: other sinks are still added and the error
+// lists the dropped ones, so callers can go on logging to stderr or log file.
+func AddSinks(l *logrus.Logger, conf SinksConfiguration) error {
+	errs := []error{}
+	if conf.Syslog.IsEnabled() {
+		if w, err := NewSyslogWriter(conf.Syslog); err != nil {
+			errs = append(errs, fmt.Errorf("syslog sink dropped: %w", err))
+		} else {
+			sinks = append(sinks, w)
+			l.AddHook(&SyslogHook{writer: w})
+		}
+	}
+	if conf.Journald.Enabled {
+		if w, err := NewJournaldWriter(conf.Journald); err != nil {
+			errs = append(errs, fmt.Errorf("journald sink dropped: %w", err))
+		} else {
+			sinks = append(sinks, w)
+			l.AddHook(&JournaldHook{writer: w})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Return a writer that sends every line written to configured sinks.
+// It is used for streams that are not written by logrus such as access logs.
+// Return nil if no sink is configured or could be opened. As in AddSinks, sinks
+// that cannot be opened are dropped and listed in returned error.
+func NewSinksWriter(conf SinksConfiguration) (io.Writer, error) {
+	writers := []io.Writer{}
+	errs := []error{}
+	if conf.Syslog.IsEnabled() {
+		if w, err := NewSyslogWriter(conf.Syslog); err != nil {
+			errs = append(errs, fmt.Errorf("syslog sink dropped: %w", err))
+		} else {
+			sinks = append(sinks, w)
+			writers = append(writers, w)
+		}
+	}
+	if conf.Journald.Enabled {
+		if w, err := NewJournaldWriter(conf.Journald); err != nil {
+			errs = append(errs, fmt.Errorf("journald sink dropped: %w", err))
+		} else {
+			sinks = append(sinks, w)
+			writers = append(writers, w)
+		}
+	}
+	if len(writers) == 0 {
+		return nil, errors.Join(errs...)
+	}
+	return io.MultiWriter(writers...), errors.Join(errs...)
+}
+
+// Close connections to all sinks
+func CloseSinks() {
+	for _, s := range sinks {
+		s.Close()
+	}
+	sinks = nil
+}
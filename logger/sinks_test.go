@@ -0,0 +1,170 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestSyslogWriter(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen on udp: %v", err)
+	}
+	defer pc.Close()
+
+	w, err := NewSyslogWriter(SyslogConfiguration{
+		Network:  "udp",
+		Address:  pc.LocalAddr().String(),
+		Facility: "local3",
+		Tag:      "mediator-test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	l := logrus.New()
+	l.SetOutput(new(bytes.Buffer))
+	l.AddHook(&SyslogHook{writer: w})
+	l.WithFields(logrus.Fields{
+		FIELD_REQUEST_ID: "abc",
+		FIELD_EXIT_CODE:  2,
+		"error":          errors.New(`bad "quote"]`),
+	}).Warn("script failed")
+
+	buf := make([]byte, 2048)
+	pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := string(buf[:n])
+
+	// local3 (19) * 8 + warning (4)
+	if !strings.HasPrefix(msg, "<156>1 ") {
+		t.Errorf("wrong PRI/VERSION: %q", msg)
+	}
+	for _, want := range []string{
+		fmt.Sprintf(" mediator-test %d - ", os.Getpid()),
+		`[mediator@32473 error="bad \"quote\"\]" exit_code="2" request_id="abc"]`,
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("%q not found in %q", want, msg)
+		}
+	}
+	if !strings.HasSuffix(msg, "] script failed") {
+		t.Errorf("wrong message: %q", msg)
+	}
+}
+
+func TestNewSyslogWriterErrors(t *testing.T) {
+	testCases := []struct {
+		conf SyslogConfiguration
+		err  error
+	}{
+		{SyslogConfiguration{Network: "udp", Address: "127.0.0.1:514", Facility: "nope"}, ErrUnknownSyslogFacility},
+		{SyslogConfiguration{Network: "sctp", Address: "127.0.0.1:514"}, ErrUnknownSyslogNetwork},
+		{SyslogConfiguration{Network: "tcp"}, ErrNoSyslogAddress},
+	}
+	for _, tc := range testCases {
+		if _, err := NewSyslogWriter(tc.conf); !errors.Is(err, tc.err) {
+			t.Errorf("%+v: expected %v, got %v", tc.conf, tc.err, err)
+		}
+	}
+}
+
+// Sinks that cannot be opened are dropped instead of failing
+func TestAddSinksDropsFailingSink(t *testing.T) {
+	conf := SinksConfiguration{
+		Syslog: SyslogConfiguration{Network: "unix", Address: filepath.Join(t.TempDir(), "missing.sock")},
+	}
+	t.Cleanup(CloseSinks)
+
+	l := logrus.New()
+	l.SetOutput(new(bytes.Buffer))
+	if err := AddSinks(l, conf); err == nil {
+		t.Error("AddSinks() must report unreachable syslog")
+	}
+	if len(l.Hooks) != 0 || len(sinks) != 0 {
+		t.Errorf("unreachable syslog was added: %v", l.Hooks)
+	}
+	l.Info("still logging")
+
+	if w, err := NewSinksWriter(conf); err == nil || w != nil {
+		t.Errorf("NewSinksWriter() = %v, %v", w, err)
+	}
+}
+
+func TestJournaldMessage(t *testing.T) {
+	got := journaldMessage(severityError, "mediator", "line1\nline2", logrus.Fields{
+		FIELD_TICKET_ID: 42,
+		"_hidden":       "x",
+	})
+
+	var want bytes.Buffer
+	want.WriteString("MESSAGE\n")
+	binary.Write(&want, binary.LittleEndian, uint64(len("line1\nline2")))
+	want.WriteString("line1\nline2\n")
+	want.WriteString("PRIORITY=3\nSYSLOG_IDENTIFIER=mediator\nHIDDEN=x\nTICKET_ID=42\n")
+
+	if !bytes.Equal(got, want.Bytes()) {
+		t.Errorf("expected %q, got %q", want.Bytes(), got)
+	}
+}
+
+func TestJournaldFieldName(t *testing.T) {
+	testCases := map[string]string{
+		"request_id":  "REQUEST_ID",
+		"duration-ms": "DURATION_MS",
+		"__private":   "PRIVATE",
+		"é":           "",
+	}
+	for in, want := range testCases {
+		if got := journaldFieldName(in); got != want {
+			t.Errorf("%q: expected %q, got %q", in, want, got)
+		}
+	}
+}
+
+func TestJournaldWriter(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "journal.sock")
+	server, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Skipf("cannot listen on unixgram socket: %v", err)
+	}
+	defer server.Close()
+
+	w, err := newJournaldWriter(JournaldConfiguration{Identifier: "mediator-test"}, socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	if _, err := w.Write([]byte("GET /v1/script 200\n")); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 2048)
+	server.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, err := server.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "MESSAGE=GET /v1/script 200\nPRIORITY=6\nSYSLOG_IDENTIFIER=mediator-test\n"
+	if string(buf[:n]) != want {
+		t.Errorf("expected %q, got %q", want, buf[:n])
+	}
+
+	if _, err := newJournaldWriter(JournaldConfiguration{}, filepath.Join(t.TempDir(), "none")); !errors.Is(err, ErrNoJournald) {
+		t.Errorf("expected %v, got %v", ErrNoJournald, err)
+	}
+}
@@ -0,0 +1,244 @@
+package logger
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Syslog destination of a log stream. Messages are formatted according to RFC 5424.
+type SyslogConfiguration struct {
+	// udp, tcp or unix. Syslog is disabled if empty
+	Network string `json:"network" mapstructure:"network"`
+	// host:port for udp and tcp, socket path for unix (default /dev/log)
+	Address string `json:"address" mapstructure:"address"`
+	// kern, user, mail, daemon, auth, syslog, lpr, news, uucp, cron, authpriv, ftp or local0 to local7 (default daemon)
+	Facility string `json:"facility" mapstructure:"facility"`
+	// APP-NAME field (default executable name)
+	Tag string `json:"tag" mapstructure:"tag"`
+}
+
+func (c SyslogConfiguration) IsEnabled() bool {
+	return c.Network != ""
+}
+
+const (
+	DEFAULT_SYSLOG_SOCKET = "/dev/log"
+	// private enterprise number used for structured data ID
+	// cf https://www.iana.org/assignments/enterprise-numbers/ (32473 is reserved for documentation)
+	syslogSDID = "mediator@32473"
+)
+
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19, "local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// Syslog and journald severities
+const (
+	severityCritical = 2
+	severityError    = 3
+	severityWarning  = 4
+	severityInfo     = 6
+	severityDebug    = 7
+)
+
+func severity(level logrus.Level) int {
+	switch level {
+	case logrus.PanicLevel, logrus.FatalLevel:
+		return severityCritical
+	case logrus.ErrorLevel:
+		return severityError
+	case logrus.WarnLevel:
+		return severityWarning
+	case logrus.InfoLevel:
+		return severityInfo
+	default:
+		return severityDebug
+	}
+}
+
+// SyslogWriter sends messages to a syslog server.
+// Connection is re-established once if sending a message fails.
+type SyslogWriter struct {
+	mutex    sync.Mutex
+	conf     SyslogConfiguration
+	facility int
+	hostname string
+	conn     net.Conn
+	network  string
+}
+
+func NewSyslogWriter(conf SyslogConfiguration) (*SyslogWriter, error) {
+	w := &SyslogWriter{conf: conf}
+
+	if conf.Facility == "" {
+		w.facility = syslogFacilities["daemon"]
+	} else if f, ok := syslogFacilities[strings.ToLower(conf.Facility)]; ok {
+		w.facility = f
+	} else {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownSyslogFacility, conf.Facility)
+	}
+	if w.conf.Tag == "" {
+		w.conf.Tag = filepath.Base(os.Args[0])
+	}
+	if h, err := os.Hostname(); err == nil {
+		w.hostname = h
+	} else {
+		w.hostname = "-"
+	}
+
+	switch strings.ToLower(conf.Network) {
+	case "udp", "tcp":
+		if conf.Address == "" {
+			return nil, fmt.Errorf("%w for %s syslog", ErrNoSyslogAddress, conf.Network)
+		}
+		w.network = strings.ToLower(conf.Network)
+	case "unix":
+		if w.conf.Address == "" {
+			w.conf.Address = DEFAULT_SYSLOG_SOCKET
+		}
+		w.network = "unixgram"
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownSyslogNetwork, conf.Network)
+	}
+
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *SyslogWriter) connect() error {
+	if w.conn != nil {
+		w.conn.Close()
+		w.conn = nil
+	}
+	conn, err := net.DialTimeout(w.network, w.conf.Address, 5*time.Second)
+	if err != nil && w.network == "unixgram" {
+		// some syslog daemons listen on a stream socket
+		if conn, err = net.DialTimeout("unix", w.conf.Address, 5*time.Second); err == nil {
+			w.network = "unix"
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("cannot connect to syslog %s: %w", w.conf.Address, err)
+	}
+	w.conn = conn
+	return nil
+}
+
+// Send a message with given severity and structured data
+func (w *SyslogWriter) Send(sev int, t time.Time, msg string, fields logrus.Fields) error {
+	line := w.format(sev, t, msg, fields)
+	// stream transports need framing: use octet counting (RFC 6587)
+	if w.network == "tcp" || w.network == "unix" {
+		line = fmt.Sprintf("%d %s", len(line), line)
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.conn != nil {
+		if _, err := w.conn.Write([]byte(line)); err == nil {
+			return nil
+		}
+	}
+	if err := w.connect(); err != nil {
+		return err
+	}
+	_, err := w.conn.Write([]byte(line))
+	return err
+}
+
+// <PRI>VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+func (w *SyslogWriter) format(sev int, t time.Time, msg string, fields logrus.Fields) string {
+	return fmt.Sprintf("<%d>1 %s %s %s %d - %s %s",
+		w.facility*8+sev,
+		t.UTC().Format("2006-01-02T15:04:05.000000Z"),
+		w.hostname,
+		w.conf.Tag,
+		os.Getpid(),
+		structuredData(fields),
+		msg)
+}
+
+// Format fields as RFC 5424 structured data
+func structuredData(fields logrus.Fields) string {
+	if len(fields) == 0 {
+		return "-"
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	sb.WriteString("[" + syslogSDID)
+	for _, k := range keys {
+		v := fields[k]
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		fmt.Fprintf(&sb, ` %s="%s"`, sdName(k), sdValueReplacer.Replace(fmt.Sprint(v)))
+	}
+	sb.WriteString("]")
+	return sb.String()
+}
+
+var sdValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+// PARAM-NAME: up to 32 printable characters except '=', ' ', ']' and '"'
+func sdName(s string) string {
+	name := strings.Map(func(r rune) rune {
+		if r <= 32 || r >= 127 || r == '=' || r == ']' || r == '"' {
+			return '_'
+		}
+		return r
+	}, s)
+	if len(name) > 32 {
+		name = name[:32]
+	}
+	return name
+}
+
+// Write sends p as an informational message. It is used for access logs.
+func (w *SyslogWriter) Write(p []byte) (int, error) {
+	if err := w.Send(severityInfo, time.Now(), strings.TrimRight(string(p), "\n"), nil); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *SyslogWriter) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+// SyslogHook sends log entries to syslog. Entry fields are sent as structured data.
+type SyslogHook struct {
+	writer *SyslogWriter
+}
+
+func (h *SyslogHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *SyslogHook) Fire(entry *logrus.Entry) error {
+	return h.writer.Send(severity(entry.Level), entry.Time, entry.Message, entry.Data)
+}
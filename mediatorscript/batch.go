@@ -0,0 +1,63 @@
+package mediatorscript
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+// Body of /execute-batch requests: scripts to run for a trigger event.
+// Ticket is provided either as ticket info or as the XML received from Securechange.
+// Also body of /execute-trigger requests: scripts and step are then found by back-end from workflow settings.
+type BatchRequest struct {
+	// workflow, trigger and step the scripts were found for. Only used in logs by /execute-batch.
+	Workflow string `json:"workflow,omitempty"`
+	Trigger  string `json:"trigger,omitempty"`
+	Step     string `json:"step,omitempty"`
+	// names of the trigger scripts to run
+	Scripts []string    `json:"scripts,omitempty"`
+	Ticket  *TicketInfo `json:"ticket,omitempty"`
+	// ticket XML received from Securechange, given untouched to scripts
+	TicketXML string `json:"ticket_xml,omitempty"`
+}
+
+// Response of /execute-batch requests: one result per script, in request order
+type BatchResponse struct {
+	// step of the ticket, as found by /execute-trigger
+	Step     string         `json:"step,omitempty"`
+	Results  []*BatchResult `json:"results"`
+	Accepted int            `json:"accepted"`
+	Rejected int            `json:"rejected"`
+}
+
+type BatchResult struct {
+	Script string `json:"script"`
+	// script was started or had already been started for the same event
+	Accepted bool `json:"accepted"`
+	// script had already been started for the same event: it was not run again
+	Duplicate bool   `json:"duplicate,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Return ticket info and raw XML, if any
+func (br *BatchRequest) TicketInfo() (*TicketInfo, []byte, error) {
+	if br.TicketXML != "" {
+		var ti TicketInfo
+		raw := []byte(br.TicketXML)
+		if err := xml.Unmarshal(raw, &ti); err != nil {
+			return nil, nil, fmt.Errorf("cannot decode ticket XML: %w", err)
+		}
+		return &ti, raw, nil
+	} else if br.Ticket != nil {
+		return br.Ticket, nil, nil
+	}
+	return nil, nil, ErrNoBatchTicket
+}
+
+func (br *BatchResponse) add(res *BatchResult) {
+	br.Results = append(br.Results, res)
+	if res.Accepted {
+		br.Accepted++
+	} else {
+		br.Rejected++
+	}
+}
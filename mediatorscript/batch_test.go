@@ -0,0 +1,82 @@
+package mediatorscript
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"mediator/apiclient"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestExecuteBatch(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "stdin.xml")
+	path := filepath.Join(dir, "a.sh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\ncat >> "+out+"\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	s := &Script{Name: "a.sh", Fullpath: path, Type: ScriptTrigger}
+	if hash, err := s.computeHash(); err != nil {
+		t.Fatal(err)
+	} else {
+		s.Hash = hash
+	}
+	allScripts[s.Name] = s
+	t.Cleanup(func() { delete(allScripts, s.Name) })
+
+	run := func(body string) (int, BatchResponse) {
+		var res BatchResponse
+		req := httptest.NewRequest(http.MethodPost, "/execute-batch", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(apiclient.HeaderIdempotencyKey, "event-1")
+		rec := httptest.NewRecorder()
+		if err := ExecuteBatch(echo.New().NewContext(req, rec)); err != nil {
+			t.Fatal(err)
+		}
+		json.Unmarshal(rec.Body.Bytes(), &res)
+		return rec.Code, res
+	}
+
+	ticket := `<ticket_info><id>42</id><custom>kept</custom></ticket_info>`
+	body, _ := json.Marshal(BatchRequest{Scripts: []string{"a.sh", "missing.sh", "a.sh"}, TicketXML: ticket})
+	code, res := run(string(body))
+	if code != http.StatusOK || res.Accepted != 2 || res.Rejected != 1 || len(res.Results) != 3 {
+		t.Fatalf("unexpected response %d: %+v", code, res)
+	}
+	if !res.Results[0].Accepted || res.Results[0].Duplicate || res.Results[1].Accepted || res.Results[1].Error == "" || !res.Results[2].Duplicate {
+		t.Errorf("unexpected results: %+v %+v %+v", res.Results[0], res.Results[1], res.Results[2])
+	}
+
+	// replayed request does not run script again
+	if code, res := run(string(body)); code != http.StatusOK || !res.Results[0].Duplicate {
+		t.Errorf("unexpected response %d: %+v", code, res.Results[0])
+	}
+
+	// script gets ticket XML untouched, once
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if data, _ := os.ReadFile(out); string(data) == ticket {
+			break
+		} else if time.Now().After(deadline) {
+			t.Fatalf("unexpected script input: %q", data)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	for name, body := range map[string]string{
+		"no script": `{"ticket_xml": "<ticket_info/>"}`,
+		"no ticket": `{"scripts": ["a.sh"]}`,
+		"bad XML":   `{"scripts": ["a.sh"], "ticket_xml": "<ticket"}`,
+	} {
+		if code, _ := run(body); code != http.StatusBadRequest {
+			t.Errorf("%s: expected %d, got %d", name, http.StatusBadRequest, code)
+		}
+	}
+}
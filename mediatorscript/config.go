@@ -1,14 +1,32 @@
 package mediatorscript
 
+import (
+	"mediator/apiclient"
+	"mediator/logger"
+)
+
 type MediatorBasicConfiguration struct {
 	BackendURL    string                       `json:"backend_url,omitempty" mapstructure:"backend_url"` // we need maptructure annotation so we can read yaml files
 	Log           MediatorLoggingConfiguration `json:"log,omitempty"  mapstructure:"log"`
 	SSLSkipVerify bool                         `json:"ssl_skip_verify,omitempty"  mapstructure:"ssl_skip_verify"`
+	// PEM file of certificate authorities trusted in addition to system ones
+	CAFile string `json:"ca_file,omitempty"  mapstructure:"ca_file"`
+	// forward ticket XML received from Securechange untouched to trigger scripts
+	// instead of the fields known by TicketInfo
+	RawXML bool `json:"raw_xml,omitempty"  mapstructure:"raw_xml"`
+	// directory where trigger requests are kept when back-end is unavailable
+	SpoolDir string `json:"spool_dir,omitempty"  mapstructure:"spool_dir"`
+	// retries, deadline and circuit breaker of requests sent to back-end
+	Requests apiclient.RequestPolicy `json:"requests,omitempty"  mapstructure:"requests"`
 }
 
 type MediatorLoggingConfiguration struct {
 	File  string `json:"file,omitempty"  mapstructure:"file"`
 	Level string `json:"level,omitempty"  mapstructure:"level"`
+	// text (default) or json
+	Format   string                       `json:"format,omitempty"  mapstructure:"format"`
+	Rotation logger.RotationConfiguration `json:"rotation,omitempty"  mapstructure:"rotation"`
+	Sinks    logger.SinksConfiguration    `json:"sinks,omitempty"  mapstructure:"sinks"`
 }
 
 type MediatorLegacyConfiguration struct {
@@ -37,9 +37,13 @@ func init() {
 }
 
 func (s *Script) computeHash() ([]byte, error) {
-	if file, err := os.Open(s.Fullpath); err != nil {
+	file, err := os.Open(s.Fullpath)
+	if err != nil {
 		return nil, err
-	} else if content, err := io.ReadAll(file); err != nil {
+	}
+	defer file.Close()
+
+	if content, err := io.ReadAll(file); err != nil {
 		return nil, err
 	} else {
 		h := hmac.New(sha512.New, []byte(secretKey))
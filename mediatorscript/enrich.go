@@ -0,0 +1,103 @@
+package mediatorscript
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"time"
+
+	"mediator/scworkflow"
+	"mediator/ttlcache"
+
+	"github.com/sirupsen/logrus"
+)
+
+// default max duration of ticket requests
+const DEFAULT_TICKET_TIMEOUT = 10 * time.Second
+
+// default duration tickets are kept in cache
+const DEFAULT_TICKET_CACHE_TTL = 60 * time.Second
+
+// Tickets fetched from Securechange, by ticket ID and update date.
+// A ticket updated since it was fetched is fetched again.
+var ticketCache = ttlcache.New[string, []byte](DEFAULT_TICKET_CACHE_TTL)
+
+// Set how long tickets are kept in cache, in seconds. Default duration is used if ttl is not positive.
+func setTicketCacheTTL(ttl int) {
+	if ttl > 0 {
+		ticketCache = ttlcache.New[string, []byte](time.Duration(ttl) * time.Second)
+	} else {
+		ticketCache = ttlcache.New[string, []byte](DEFAULT_TICKET_CACHE_TTL)
+	}
+}
+
+// Complete ticket as returned by Securechange API, in JSON format
+var getTicket = func(ticket_id int) ([]byte, error) {
+	timeout := DEFAULT_TICKET_TIMEOUT
+	if securechangeAPI.Timeout > 0 {
+		timeout = time.Duration(securechangeAPI.Timeout) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return scworkflow.GetTicket(ctx, ticket_id, securechangeAPI.Username, securechangeAPI.Password, securechangeAPI.Host)
+}
+
+func fetchTicket(ti *TicketInfo) ([]byte, error) {
+	key := fmt.Sprintf("%d/%d", ti.ID, ti.UpdateDate)
+	if ticket, ok := ticketCache.Get(key); ok {
+		return ticket, nil
+	}
+	if !securechangeAPI.IsEnabled() {
+		return nil, ErrNoSecurechangeAPI
+	}
+	ticket, err := getTicket(ti.ID)
+	if err != nil {
+		return nil, err
+	}
+	ticketCache.Set(key, ticket)
+	return ticket, nil
+}
+
+// Fetch complete ticket from Securechange for scripts registered with enrich option.
+// Ticket is written in a temporary file whose name is given in MEDIATOR_TICKET_DETAILS environment variable.
+// Errors are logged and the script runs without the variable: it still gets ticket info on stdin.
+// Returned function removes the temporary file and must be called once script has run.
+func (s *Script) enrich(input []byte, log *logrus.Entry) ([]string, func()) {
+	var ti TicketInfo
+	nothing := func() {}
+
+	if !s.Options.Enrich {
+		return nil, nothing
+	} else if err := xml.Unmarshal(input, &ti); err != nil {
+		log.Warningf("cannot enrich %s input: cannot decode ticket info: %v", s, err)
+		return nil, nothing
+	} else if ti.ID == 0 {
+		// test mode
+		return nil, nothing
+	}
+
+	ticket, err := fetchTicket(&ti)
+	if err != nil {
+		log.Warningf("cannot enrich %s input: cannot get ticket %d from Securechange: %v", s, ti.ID, err)
+		return nil, nothing
+	}
+
+	f, err := os.CreateTemp("", "mediator-ticket-details-*.json")
+	if err != nil {
+		log.Warningf("cannot enrich %s input: %v", s, err)
+		return nil, nothing
+	}
+	cleanup := func() { os.Remove(f.Name()) }
+	if _, err := f.Write(ticket); err != nil {
+		f.Close()
+		cleanup()
+		log.Warningf("cannot enrich %s input: %v", s, err)
+		return nil, nothing
+	} else if err := f.Close(); err != nil {
+		cleanup()
+		log.Warningf("cannot enrich %s input: %v", s, err)
+		return nil, nothing
+	}
+	return []string{fmt.Sprintf("%s=%s", ENV_TICKET_DETAILS, f.Name())}, cleanup
+}
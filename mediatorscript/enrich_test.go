@@ -0,0 +1,95 @@
+package mediatorscript
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestEnrich(t *testing.T) {
+	calls := 0
+	getTicket = func(ticket_id int) ([]byte, error) {
+		calls++
+		if ticket_id == 13 {
+			return nil, errors.New("not found")
+		}
+		return []byte(`{"ticket":{"id":12}}`), nil
+	}
+	SetSecurechangeAPI(SecurechangeAPIConfiguration{Host: "sc", CacheTTL: 60})
+	t.Cleanup(func() { SetSecurechangeAPI(SecurechangeAPIConfiguration{}) })
+
+	log := logrus.NewEntry(logrus.New())
+	s := &Script{Name: "test.sh", Type: ScriptTrigger, Options: ScriptOptions{Enrich: true}}
+	input := []byte(`<ticket_info><id>12</id><updateDate>1</updateDate></ticket_info>`)
+
+	for range 2 {
+		env, cleanup := s.enrich(input, log)
+		if len(env) != 1 || !strings.HasPrefix(env[0], ENV_TICKET_DETAILS+"=") {
+			t.Fatalf("unexpected environment: %v", env)
+		}
+		path := strings.TrimPrefix(env[0], ENV_TICKET_DETAILS+"=")
+		if data, err := os.ReadFile(path); err != nil {
+			t.Fatal(err)
+		} else if string(data) != `{"ticket":{"id":12}}` {
+			t.Errorf("unexpected ticket: %s", data)
+		}
+		cleanup()
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s was not removed", path)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("ticket should be fetched once, got %d calls", calls)
+	}
+
+	// updated ticket is fetched again
+	s.enrich([]byte(`<ticket_info><id>12</id><updateDate>2</updateDate></ticket_info>`), log)
+	if calls != 2 {
+		t.Errorf("updated ticket should be fetched again, got %d calls", calls)
+	}
+
+	testCases := map[string]struct {
+		options ScriptOptions
+		input   string
+	}{
+		"no option":   {ScriptOptions{}, `<ticket_info><id>12</id></ticket_info>`},
+		"test mode":   {ScriptOptions{Enrich: true}, `<ticket_info/>`},
+		"fetch error": {ScriptOptions{Enrich: true}, `<ticket_info><id>13</id></ticket_info>`},
+		"bad input":   {ScriptOptions{Enrich: true}, `not xml`},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			s := &Script{Name: "test.sh", Type: ScriptTrigger, Options: tc.options}
+			if env, cleanup := s.enrich([]byte(tc.input), log); env != nil {
+				cleanup()
+				t.Errorf("expected no environment, got %v", env)
+			}
+		})
+	}
+}
+
+// Tickets are cached even if cache duration is not configured
+func TestFetchTicketDefaultCache(t *testing.T) {
+	calls := 0
+	getTicket = func(ticket_id int) ([]byte, error) {
+		calls++
+		return []byte(`{"ticket":{"id":12}}`), nil
+	}
+	SetSecurechangeAPI(SecurechangeAPIConfiguration{Host: "sc"})
+	t.Cleanup(func() { SetSecurechangeAPI(SecurechangeAPIConfiguration{}) })
+
+	ti := &TicketInfo{ID: 12, UpdateDate: 1}
+	for range 2 {
+		if ticket, err := fetchTicket(ti); err != nil {
+			t.Fatal(err)
+		} else if string(ticket) != `{"ticket":{"id":12}}` {
+			t.Errorf("unexpected ticket: %s", ticket)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("second lookup should hit cache, got %d calls", calls)
+	}
+}
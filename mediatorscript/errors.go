@@ -25,15 +25,33 @@ var (
 	ErrScriptFileIsNotNormal                 = errors.New("script file is not a normal file or symlink")
 	ErrScriptFileIsNotExecutable             = errors.New("script file is not executable")
 	ErrScriptFileIsNotExecutableByBack       = errors.New("script file cannot be executed by back-end")
+	ErrRegistryNotLoaded                     = errors.New("script registry has not been loaded")
+	ErrStorageNotWritable                    = errors.New("script storage file is not writable")
+	ErrScriptTimeout                         = errors.New("script execution timed out")
+	ErrUnknownFeedback                       = errors.New("unknown feedback mode. Expected comment or field")
+	ErrNoFeedbackField                       = errors.New("feedback field name is missing")
+	ErrFeedbackNotSupported                  = errors.New("feedback is only supported by trigger scripts")
+	ErrStderrWithoutFeedback                 = errors.New("stderr can only be posted to tickets with feedback")
+	ErrNoSecurechangeAPI                     = errors.New("Securechange API is not configured")
+	ErrNoTicketTask                          = errors.New("ticket has no current task")
+	ErrUnknownResult                         = errors.New("unknown result format. Expected text or json")
+	ErrResultNotSupported                    = errors.New("JSON result is only supported by Scripted Condition, Scripted Task and Pre-Assignment scripts")
+	ErrInvalidResult                         = errors.New("invalid script result")
+	ErrUnknownInput                          = errors.New("unknown input format. Expected xml, json or both")
+	ErrNoBatchTicket                         = errors.New("batch request has no ticket information")
+	ErrNoBatchScript                         = errors.New("batch request has no script")
+	ErrNoTicketStep                          = errors.New("both 'current_stage' and 'completion_data' are missing or set in ticket info. Cannot get ticket step")
 )
 
+// Script returned a non-zero exit code or was killed after timeout
 func errorIsScriptFailure(err error) bool {
-	_, ok := err.(*exec.ExitError)
-	return ok
+	var exiterr *exec.ExitError
+	return errors.As(err, &exiterr)
 }
 
 func getExitCodeFromError(err error) int {
-	if exiterr, ok := err.(*exec.ExitError); ok {
+	var exiterr *exec.ExitError
+	if errors.As(err, &exiterr) {
 		return exiterr.ExitCode()
 	} else {
 		return 0
@@ -47,5 +65,12 @@ func registerErrorIsBadRequest(err error) bool {
 		errors.Is(err, ErrScriptExistForType) ||
 		errors.Is(err, ErrScriptFileIsNotNormal) ||
 		errors.Is(err, ErrScriptFileIsNotExecutable) ||
-		errors.Is(err, ErrScriptFileIsNotExecutableByBack)
+		errors.Is(err, ErrScriptFileIsNotExecutableByBack) ||
+		errors.Is(err, ErrUnknownFeedback) ||
+		errors.Is(err, ErrNoFeedbackField) ||
+		errors.Is(err, ErrFeedbackNotSupported) ||
+		errors.Is(err, ErrStderrWithoutFeedback) ||
+		errors.Is(err, ErrUnknownResult) ||
+		errors.Is(err, ErrResultNotSupported) ||
+		errors.Is(err, ErrUnknownInput)
 }
@@ -0,0 +1,196 @@
+package mediatorscript
+
+import (
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"mediator/logger"
+	"mediator/notify"
+	"mediator/scworkflow"
+
+	"github.com/sirupsen/logrus"
+	"go.yaml.in/yaml/v3"
+)
+
+// Securechange REST API used by the back-end to update tickets
+type SecurechangeAPIConfiguration struct {
+	// Securechange host name or API URL
+	Host     string `json:"host" mapstructure:"host"`
+	Username string `json:"username" mapstructure:"username"`
+	Password string `json:"password" mapstructure:"password"`
+	// max duration of ticket requests made for scripts registered with enrich option, in seconds
+	Timeout int `json:"timeout" mapstructure:"timeout"`
+	// how long tickets fetched for scripts registered with enrich option are kept in cache, in seconds (default 60)
+	CacheTTL int `json:"cachettl" mapstructure:"cachettl"`
+}
+
+func (c SecurechangeAPIConfiguration) IsEnabled() bool {
+	return c.Host != ""
+}
+
+// max number of characters posted to a ticket
+const MAX_FEEDBACK_LENGTH = 4000
+
+var securechangeAPI SecurechangeAPIConfiguration
+
+func SetSecurechangeAPI(conf SecurechangeAPIConfiguration) {
+	securechangeAPI = conf
+	setTicketCacheTTL(conf.CacheTTL)
+}
+
+// Structured feedback a trigger script can print on stdout:
+//
+//	mediator:
+//	  comment: Rule created on fw01
+//	  fields:
+//	    Implementation status: done
+type FeedbackBlock struct {
+	Comment string            `yaml:"comment"`
+	Fields  map[string]string `yaml:"fields"`
+}
+
+// Get the 'mediator:' block from script output.
+// Block starts with a 'mediator:' line and ends with the first line that is not indented.
+func parseFeedbackBlock(stdout string) (*FeedbackBlock, error) {
+	var (
+		lines   []string
+		inblock bool
+	)
+	scanner := bufio.NewScanner(strings.NewReader(stdout))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !inblock {
+			inblock = strings.TrimRight(line, " \t\r") == "mediator:"
+		} else if line != "" && line[0] != ' ' && line[0] != '\t' {
+			break
+		}
+		if inblock {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return nil, nil
+	}
+
+	var doc struct {
+		Mediator *FeedbackBlock `yaml:"mediator"`
+	}
+	if err := yaml.Unmarshal([]byte(strings.Join(lines, "\n")), &doc); err != nil {
+		return nil, fmt.Errorf("invalid mediator block: %w", err)
+	}
+	return doc.Mediator, nil
+}
+
+// Post trigger script output back to the ticket according to script options.
+// Errors are logged: the script has already run.
+func (s *Script) sendFeedback(ti *TicketInfo, stdout, stderr string, err error, log *logrus.Entry) {
+	if s.Options.Feedback == FEEDBACK_NONE {
+		return
+	}
+	if ferr := s.postFeedback(ti, stdout, stderr, err, log); ferr != nil {
+		log.Warningf("cannot post %s output to ticket %d: %v", s, ti.ID, ferr)
+	}
+}
+
+func (s *Script) postFeedback(ti *TicketInfo, stdout, stderr string, err error, log *logrus.Entry) error {
+	if !securechangeAPI.IsEnabled() {
+		return ErrNoSecurechangeAPI
+	}
+	stage := ti.CurrentStage
+	if stage == nil || stage.TaskID == 0 {
+		return ErrNoTicketTask
+	}
+
+	// failures are reported the same way as output
+	if err != nil {
+		return s.postText(ti.ID, stage, s.failureFeedback(err, stderr, log))
+	}
+
+	block, berr := parseFeedbackBlock(stdout)
+	if berr != nil {
+		log.Warningf("%s: %v. Posting whole output", s, berr)
+	}
+	if block == nil {
+		if stdout == "" {
+			return nil
+		}
+		return s.postText(ti.ID, stage, stdout)
+	}
+
+	if block.Comment != "" {
+		if err := scworkflow.AddTicketTaskComment(ti.ID, stage.ID, stage.TaskID, truncate(block.Comment),
+			securechangeAPI.Username, securechangeAPI.Password, securechangeAPI.Host); err != nil {
+			return err
+		}
+	}
+	return setFields(ti.ID, stage, block.Fields)
+}
+
+// Text posted to the ticket when script fails.
+// Error is not posted as is: it shows script path. Details are in the execution log, found with request ID.
+// The end of stderr is only posted if script options allow it.
+func (s *Script) failureFeedback(err error, stderr string, log *logrus.Entry) string {
+	msg := fmt.Sprintf("%s failed", s)
+	if errors.Is(err, ErrScriptTimeout) {
+		msg = fmt.Sprintf("%s timed out", s)
+	}
+	if id, ok := log.Data[logger.FIELD_REQUEST_ID]; ok {
+		msg = fmt.Sprintf("%s (request ID %v)", msg, id)
+	}
+	if stderr = notify.Excerpt(stderr, notify.DEFAULT_STDERR_EXCERPT); s.Options.FeedbackStderr && stderr != "" {
+		msg = fmt.Sprintf("%s\n%s", msg, stderr)
+	}
+	return msg
+}
+
+// Write values in task text fields, by field name
+func setFields(ticket_id int, stage *TicketStage, fields map[string]string) error {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := scworkflow.SetTicketTaskTextField(ticket_id, stage.ID, stage.TaskID, name, truncate(fields[name]),
+			securechangeAPI.Username, securechangeAPI.Password, securechangeAPI.Host); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write field values returned by an interactive script in the ticket it was run for
+func (s *Script) postFields(input []byte, fields map[string]string) error {
+	var ti TicketInfo
+	if !securechangeAPI.IsEnabled() {
+		return ErrNoSecurechangeAPI
+	} else if err := xml.Unmarshal(input, &ti); err != nil {
+		return fmt.Errorf("cannot get ticket from script input: %w", err)
+	} else if ti.CurrentStage == nil || ti.CurrentStage.TaskID == 0 {
+		return ErrNoTicketTask
+	}
+	return setFields(ti.ID, ti.CurrentStage, fields)
+}
+
+// Post text as a comment or in feedback field according to script options
+func (s *Script) postText(ticket_id int, stage *TicketStage, text string) error {
+	text = truncate(text)
+	if s.Options.Feedback == FEEDBACK_FIELD {
+		return scworkflow.SetTicketTaskTextField(ticket_id, stage.ID, stage.TaskID, s.Options.FeedbackField, text,
+			securechangeAPI.Username, securechangeAPI.Password, securechangeAPI.Host)
+	}
+	return scworkflow.AddTicketTaskComment(ticket_id, stage.ID, stage.TaskID, text,
+		securechangeAPI.Username, securechangeAPI.Password, securechangeAPI.Host)
+}
+
+func truncate(s string) string {
+	r := []rune(s)
+	if len(r) <= MAX_FEEDBACK_LENGTH {
+		return s
+	}
+	return string(r[:MAX_FEEDBACK_LENGTH]) + "..."
+}
@@ -0,0 +1,76 @@
+package mediatorscript
+
+import (
+	"fmt"
+	"os/exec"
+	"reflect"
+	"testing"
+
+	"mediator/logger"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestParseFeedbackBlock(t *testing.T) {
+	tests := []struct {
+		name    string
+		stdout  string
+		want    *FeedbackBlock
+		wantErr bool
+	}{
+		{
+			name:   "no block",
+			stdout: "rule created\nmediator is great",
+			want:   nil,
+		},
+		{
+			name:   "block at the end",
+			stdout: "working...\nmediator:\n  comment: rule created\n  fields:\n    Result: done\n",
+			want:   &FeedbackBlock{Comment: "rule created", Fields: map[string]string{"Result": "done"}},
+		},
+		{
+			name:   "block followed by output",
+			stdout: "mediator:\n  comment: |\n    line 1\n\n    line 2\nbye",
+			want:   &FeedbackBlock{Comment: "line 1\n\nline 2"},
+		},
+		{
+			name:    "invalid block",
+			stdout:  "mediator:\n  comment: [oops\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseFeedbackBlock(tt.stdout)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseFeedbackBlock() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseFeedbackBlock() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFailureFeedback(t *testing.T) {
+	log := logrus.WithField(logger.FIELD_REQUEST_ID, "req-1")
+	err := fmt.Errorf("error while running /opt/scripts/create.sh: %w", &exec.ExitError{})
+	tests := []struct {
+		name   string
+		stderr bool
+		err    error
+		want   string
+	}{
+		{name: "failure", err: err, want: "Trigger script 'create.sh' failed (request ID req-1)"},
+		{name: "timeout", err: fmt.Errorf("%w after 1m0s: %w", ErrScriptTimeout, err), want: "Trigger script 'create.sh' timed out (request ID req-1)"},
+		{name: "stderr", stderr: true, err: err, want: "Trigger script 'create.sh' failed (request ID req-1)\ncannot connect to fw01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Script{Name: "create.sh", Type: ScriptTrigger, Fullpath: "/opt/scripts/create.sh", Options: ScriptOptions{Feedback: FEEDBACK_COMMENT, FeedbackStderr: tt.stderr}}
+			if got := s.failureFeedback(tt.err, "cannot connect to fw01\n", log); got != tt.want {
+				t.Errorf("failureFeedback() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -19,6 +19,7 @@ func AddMediatorscriptAPI(g *echo.Group) {
 	g.POST("/refresh/:slug", RefreshScript)
 
 	g.POST("/execute/:script", ExecuteScript)
+	g.POST("/execute-batch", ExecuteBatch)
 	g.POST("/execute-scripted-condition/:id", ExecuteScriptedCondition)
 	g.POST("/execute-scripted-task/:id", ExecuteScriptedTask)
 	g.POST("/execute-pre-assignment", ExecutePreAssignment)
@@ -1,9 +1,15 @@
 package mediatorscript
 
 import (
+	"encoding/xml"
 	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"strconv"
+
+	"mediator/apiclient"
 
 	"github.com/labstack/echo/v4"
 	"github.com/sirupsen/logrus"
@@ -11,7 +17,7 @@ import (
 
 func TestAllScripts(c echo.Context) error {
 	var res RunResponse
-	TestAllScriptsByTypeAndName(ScriptAll, "", &res)
+	TestAllScriptsByTypeAndName(ScriptAll, "", &res, RequestLogger(c, ""))
 	return res.SendResponse(c)
 }
 
@@ -52,13 +58,13 @@ func TestScript(c echo.Context) error {
 		} else {
 			// will execute script in test mode and populate res
 			// with execution results
-			TestAllScriptsByTypeAndName(t, scriptname, &rr)
+			TestAllScriptsByTypeAndName(t, scriptname, &rr, RequestLogger(c, ""))
 		}
 
 	} else {
 		// will execute scripts in test mode and populate res
 		// with execution results
-		TestAllScriptsByTypeAndName(t, "", &rr)
+		TestAllScriptsByTypeAndName(t, "", &rr, RequestLogger(c, ""))
 	}
 
 	return rr.SendResponse(c)
@@ -69,28 +75,104 @@ func ExecuteScript(c echo.Context) error {
 	var (
 		ti  TicketInfo
 		res RunResponse
+		raw []byte
+		err error
 	)
 	scriptname := c.Param("script")
 
-	if err := c.Bind(&ti); err != nil {
+	if isXMLRequest(c) {
+		// raw ticket XML forwarded by mediator-client: script gets it untouched
+		if raw, err = io.ReadAll(c.Request().Body); err == nil {
+			err = xml.Unmarshal(raw, &ti)
+		}
+	} else {
+		err = c.Bind(&ti)
+	}
+	if err != nil {
 		res.Error = fmt.Sprintf("error while processing parameters: %v", err)
 		return c.JSON(http.StatusBadRequest, res)
+	}
 
-	} else if script, err := GetScriptByName(scriptname); err != nil {
-		res.Error = fmt.Sprintf("cannot execute script '%s': %v", scriptname, err)
-		logrus.Error(res.Error)
-		return c.JSON(http.StatusBadRequest, res)
+	log := RequestLogger(c, strconv.Itoa(ti.ID))
+	key := c.Request().Header.Get(apiclient.HeaderIdempotencyKey)
 
-	} else if err := script.AsyncRun(&ti); err != nil {
-		res.Error = fmt.Sprintf("error while executing script '%s': %v", scriptname, err)
-		logrus.Error(res.Error)
+	if _, err := startScript(scriptname, &ti, raw, key, log); err != nil {
+		res.Error = err.Error()
+		log.Error(res.Error)
 		return c.JSON(http.StatusBadRequest, res)
-
 	} else {
 		return c.NoContent(http.StatusNoContent)
 	}
 }
 
+// Run several trigger scripts for the same ticket.
+// Every script is started independently: response gives the result of each of them.
+func ExecuteBatch(c echo.Context) error {
+	var br BatchRequest
+	if err := c.Bind(&br); err != nil {
+		return c.JSON(http.StatusBadRequest, RunResponse{Error: fmt.Sprintf("error while processing parameters: %v", err)})
+	} else if len(br.Scripts) == 0 {
+		return c.JSON(http.StatusBadRequest, RunResponse{Error: ErrNoBatchScript.Error()})
+	}
+	ti, raw, err := br.TicketInfo()
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, RunResponse{Error: err.Error()})
+	}
+	return RunBatch(c, &br, ti, raw)
+}
+
+// Start scripts of batch request and send the result of each of them
+func RunBatch(c echo.Context, br *BatchRequest, ti *TicketInfo, raw []byte) error {
+	var err error
+	res := BatchResponse{Step: br.Step}
+
+	log := RequestLogger(c, strconv.Itoa(ti.ID))
+	key := c.Request().Header.Get(apiclient.HeaderIdempotencyKey)
+	log.Infof("running %d script(s) for workflow '%s', trigger '%s' and step '%s': %v", len(br.Scripts), br.Workflow, br.Trigger, br.Step, br.Scripts)
+
+	started := map[string]bool{}
+	for _, scriptname := range br.Scripts {
+		result := BatchResult{Script: scriptname}
+		if started[scriptname] {
+			// same script listed twice: run it once
+			result.Accepted, result.Duplicate = true, true
+		} else if result.Duplicate, err = startScript(scriptname, ti, raw, key, log); err != nil {
+			result.Error = err.Error()
+			log.Error(result.Error)
+		} else {
+			result.Accepted = true
+			started[scriptname] = true
+		}
+		res.add(&result)
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+// Start a trigger script in background.
+// Script is not started again if it has already been started for the same idempotency key: duplicate is then true.
+func startScript(scriptname string, ti *TicketInfo, raw []byte, key string, log *logrus.Entry) (bool, error) {
+	if key != "" {
+		key = fmt.Sprintf("%s/%s", scriptname, key)
+	}
+
+	if script, err := GetScriptByName(scriptname); err != nil {
+		return false, fmt.Errorf("cannot execute script '%s': %w", scriptname, err)
+
+	} else if key != "" && !markExecuted(key) {
+		// request was replayed by mediator-client after script was started
+		log.Infof("script '%s' has already been run for idempotency key '%s'. Ignore request.", scriptname, key)
+		return true, nil
+
+	} else if err := script.asyncRun(ti, raw, log); err != nil {
+		// request can be replayed
+		if key != "" {
+			forgetExecuted(key)
+		}
+		return false, fmt.Errorf("error while executing script '%s': %w", scriptname, err)
+	}
+	return false, nil
+}
+
 func ExecuteScriptedCondition(c echo.Context) error {
 	var rr RunResponse
 	if ticket_id := c.Param("id"); ticket_id == "" {
@@ -125,3 +207,9 @@ func ExecuteRiskAnalysis(c echo.Context) error {
 
 	return rr.SendResponse(c)
 }
+
+// Request body is XML: ticket info was forwarded untouched by mediator-client
+func isXMLRequest(c echo.Context) bool {
+	mediatype, _, _ := mime.ParseMediaType(c.Request().Header.Get(echo.HeaderContentType))
+	return mediatype == echo.MIMEApplicationXML || mediatype == echo.MIMETextXML
+}
@@ -1,19 +1,29 @@
 package mediatorscript
 
 import (
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"net/http"
 
+	"mediator/audit"
+
 	"github.com/labstack/echo/v4"
 	"github.com/sirupsen/logrus"
 )
 
-func RegisterScript(c echo.Context) error {
+func RegisterScript(c echo.Context) (err error) {
 	var s Script
+	entry := audit.NewEntry(c, audit.ActionRegister)
+	defer func() { entry.Save(err) }()
+
 	if err := c.Bind(&s); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("error while processing parameters: %w", err))
 	}
+	entry.Target = s.Name
+	if existing, err := GetScriptByName(s.Name); err == nil {
+		entry.Before = *existing
+	}
 	if err := s.Save(); err != nil {
 		if registerErrorIsBadRequest(err) {
 			return echo.NewHTTPError(http.StatusBadRequest, err)
@@ -26,10 +36,15 @@ func RegisterScript(c echo.Context) error {
 		}
 
 	}
+	entry.After = s
 	return c.NoContent(http.StatusNoContent)
 }
 
-func UnregisterScript(c echo.Context) error {
+func UnregisterScript(c echo.Context) (err error) {
+	entry := audit.NewEntry(c, audit.ActionUnregister)
+	entry.Target = scriptTarget(c)
+	defer func() { entry.Save(err) }()
+
 	//check slug is valid, complain otherwise
 	if slug := c.Param("slug"); !IsScriptTypeSlug(slug) {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("%w: %s", ErrUnknownScriptType, slug))
@@ -54,6 +69,8 @@ func UnregisterScript(c echo.Context) error {
 		} else if script.Type != t {
 			// ok, we've got a script but it's not the expected type: complain
 			return echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("Script '%s' is not a %s", scriptname, t))
+		} else {
+			entry.Before = ScriptList{script}
 		}
 
 		if err := RemoveScriptByName(scriptname); err != nil {
@@ -62,15 +79,21 @@ func UnregisterScript(c echo.Context) error {
 			return c.NoContent(http.StatusNoContent)
 		}
 
-	} else if err := RemoveScriptByType(t); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("error while unregistering %s: %w", t, err))
-
 	} else {
+		entry.Before = GetScriptByType(t)
+		if err := RemoveScriptByType(t); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("error while unregistering %s: %w", t, err))
+		}
 		return c.NoContent(http.StatusNoContent)
 	}
 }
 
-func UnregisterAll(c echo.Context) error {
+func UnregisterAll(c echo.Context) (err error) {
+	entry := audit.NewEntry(c, audit.ActionUnregister)
+	entry.Target = "all"
+	entry.Before = GetScriptByType(ScriptAll)
+	defer func() { entry.Save(err) }()
+
 	if err := RemoveScriptByType(ScriptAll); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("error unregistering all scripts: %w", err))
 
@@ -102,7 +125,11 @@ func GetAllByType(c echo.Context) error {
 	}
 }
 
-func RefreshScript(c echo.Context) error {
+func RefreshScript(c echo.Context) (err error) {
+	entry := audit.NewEntry(c, audit.ActionRefresh)
+	entry.Target = scriptTarget(c)
+	defer func() { entry.Save(err) }()
+
 	//check slug is valid, complain otherwise
 	if slug := c.Param("slug"); !IsScriptTypeSlug(slug) {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("%w: %v", ErrUnknownScriptType, slug))
@@ -128,12 +155,20 @@ func RefreshScript(c echo.Context) error {
 			// ok, we've got a script but it's not the expected type: complain
 			return echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("Script '%s' is not a %s", scriptname, t))
 
-		} else if err := script.Refresh(); err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("error while refreshing %s: %w", script, err))
+		} else {
+			entry.Before = getHashes(ScriptList{script})
+			defer func() { entry.After = getHashes(ScriptList{script}) }()
+
+			if err := script.Refresh(); err != nil {
+				return echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("error while refreshing %s: %w", script, err))
+			}
 		}
 
 	} else {
 		l := GetScriptByType(t)
+		entry.Before = getHashes(l)
+		defer func() { entry.After = getHashes(l) }()
+
 		for _, s := range l {
 			if err := s.Refresh(); err != nil {
 				return echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("error while refreshing %s: %w", s, err))
@@ -143,11 +178,39 @@ func RefreshScript(c echo.Context) error {
 	return c.NoContent(http.StatusNoContent)
 }
 
-func RefreshAllScript(c echo.Context) error {
-	for _, s := range allScripts {
+func RefreshAllScript(c echo.Context) (err error) {
+	l := GetScriptByType(ScriptAll)
+	entry := audit.NewEntry(c, audit.ActionRefresh)
+	entry.Target = "all"
+	entry.Before = getHashes(l)
+	defer func() {
+		entry.After = getHashes(l)
+		entry.Save(err)
+	}()
+
+	for _, s := range l {
 		if err := s.Refresh(); err != nil {
 			return echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("error while refreshing %s: %w", s, err))
 		}
 	}
 	return c.NoContent(http.StatusNoContent)
 }
+
+// Audit target of management requests: script type slug and script name if any
+func scriptTarget(c echo.Context) string {
+	if name := c.Param("script"); name != "" {
+		return fmt.Sprintf("%s/%s", c.Param("slug"), name)
+	}
+	return c.Param("slug")
+}
+
+// Return hex encoded hashes of provided scripts indexed by script name
+func getHashes(l ScriptList) map[string]string {
+	h := make(map[string]string, len(l))
+	scriptsMutex.RLock()
+	defer scriptsMutex.RUnlock()
+	for _, s := range l {
+		h[s.Name] = hex.EncodeToString(s.Hash)
+	}
+	return h
+}
@@ -0,0 +1,86 @@
+package mediatorscript
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// Readiness checks used by health package
+
+// Check scripts were loaded from storage file
+func CheckRegistry() error {
+	if loadError != nil {
+		return fmt.Errorf("%w: %v", ErrRegistryNotLoaded, loadError)
+	}
+	return nil
+}
+
+// Check storage file can be written so script registration works.
+// If file does not exist yet, its folder must be writable.
+func CheckStorage() error {
+	if scriptStorageFilename == "" {
+		return ErrInitNoFileName
+	}
+	target := scriptStorageFilename
+	if _, err := os.Stat(target); errors.Is(err, fs.ErrNotExist) {
+		target = filepath.Dir(target)
+	} else if err != nil {
+		return err
+	}
+	if err := unix.Access(target, unix.W_OK); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrStorageNotWritable, target, err)
+	}
+	return nil
+}
+
+// Maximum age of integrity check result. Scripts may be modified on disk without any registry change.
+const INTEGRITY_CACHE_DURATION = time.Minute
+
+// Last integrity check result: it is computed again when registry changes or result is too old
+var integrity struct {
+	sync.Mutex
+	valid   bool
+	version uint64
+	checked time.Time
+	err     error
+}
+
+// Check every registered script still matches its checksum.
+// Result is cached so readiness probes do not hash every script on every call.
+func CheckIntegrity() error {
+	integrity.Lock()
+	defer integrity.Unlock()
+
+	scriptsMutex.RLock()
+	version := registryVersion
+	scriptsMutex.RUnlock()
+	if integrity.valid && integrity.version == version && time.Since(integrity.checked) < INTEGRITY_CACHE_DURATION {
+		return integrity.err
+	}
+
+	errs := []error{}
+	for _, s := range GetScriptByType(ScriptAll) {
+		if err := s.verifyHash(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	integrity.valid = true
+	integrity.version = version
+	integrity.checked = time.Now()
+	integrity.err = errors.Join(errs...)
+	return integrity.err
+}
+
+// Force next integrity check to hash scripts again
+func invalidateIntegrity() {
+	integrity.Lock()
+	defer integrity.Unlock()
+	integrity.valid = false
+}
@@ -0,0 +1,101 @@
+package mediatorscript
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// Use a temporary storage file. Registry is emptied when test ends
+func initTestStorage(t *testing.T) string {
+	dir := t.TempDir()
+	if err := Init(filepath.Join(dir, "scripts.json")); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(closeTestJournal)
+	t.Cleanup(func() {
+		scriptsMutex.Lock()
+		allScripts = make(map[string]*Script)
+		registryVersion++
+		scriptsMutex.Unlock()
+	})
+	return dir
+}
+
+func writeTestScript(t *testing.T, dir, name string) *Script {
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\nexit 0\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	return &Script{Name: name, Fullpath: path, Type: ScriptTrigger}
+}
+
+func TestCheckIntegrity(t *testing.T) {
+	dir := initTestStorage(t)
+	s := writeTestScript(t, dir, "a.sh")
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckIntegrity(); err != nil {
+		t.Fatalf("CheckIntegrity() = %v", err)
+	}
+
+	// modified script is not detected until registry changes: result is cached
+	if err := os.WriteFile(s.Fullpath, []byte("#!/bin/sh\nexit 1\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckIntegrity(); err != nil {
+		t.Fatalf("CheckIntegrity() = %v, want cached result", err)
+	}
+	if err := writeTestScript(t, dir, "b.sh").Save(); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckIntegrity(); !errors.Is(err, ErrHashMismatch) {
+		t.Fatalf("CheckIntegrity() = %v, want %v", err, ErrHashMismatch)
+	}
+
+	// refreshed script is valid again
+	if err := s.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckIntegrity(); err != nil {
+		t.Fatalf("CheckIntegrity() = %v", err)
+	}
+}
+
+// Run with -race: registry is changed while readiness probe and lookups run
+func TestRegistryConcurrency(t *testing.T) {
+	dir := initTestStorage(t)
+	var wg sync.WaitGroup
+	for i := range 10 {
+		s := writeTestScript(t, dir, fmt.Sprintf("s%d.sh", i))
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			if err := s.Save(); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			CheckIntegrity()
+			GetScriptByType(ScriptAll)
+			GetAllScriptNames()
+		}()
+		go func() {
+			defer wg.Done()
+			IsEmpty(ScriptTrigger)
+			GetScriptByName(s.Name)
+		}()
+	}
+	wg.Wait()
+	if got := len(GetScriptByType(ScriptTrigger)); got != 10 {
+		t.Errorf("%d scripts registered, want 10", got)
+	}
+	if err := CheckIntegrity(); err != nil {
+		t.Errorf("CheckIntegrity() = %v", err)
+	}
+}
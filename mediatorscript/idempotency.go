@@ -0,0 +1,159 @@
+package mediatorscript
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"mediator/ttlcache"
+
+	"github.com/sirupsen/logrus"
+)
+
+// How long idempotency keys of trigger requests are kept
+const IDEMPOTENCY_TTL = 24 * time.Hour
+
+// Journal of idempotency keys, in script storage folder. Keys are appended to it when scripts are started
+// and restored when server starts, so a request replayed after a restart does not run a script twice.
+// Journal is rotated once it is older than IDEMPOTENCY_TTL: previous journal is kept with .1 suffix.
+const IDEMPOTENCY_JOURNAL = "idempotency-keys.jsonl"
+
+// Idempotency keys of trigger requests that started a script, prefixed with script name.
+// mediator-client replays spooled requests with the same key: a script is never run twice for the same event.
+var executedRequests = ttlcache.New[string, struct{}](IDEMPOTENCY_TTL)
+
+var journal struct {
+	sync.Mutex
+	filename string
+	// nil if keys are kept in memory only
+	file    *os.File
+	started time.Time
+}
+
+// One line of journal
+type journalEntry struct {
+	Key  string    `json:"key"`
+	Time time.Time `json:"time"`
+	// key was removed because script could not be started
+	Deleted bool `json:"deleted,omitempty"`
+}
+
+// Record that a script was started for given key.
+// Returns false if it was already started for this key.
+func markExecuted(key string) bool {
+	if !executedRequests.Add(key, struct{}{}) {
+		return false
+	}
+	writeJournal(journalEntry{Key: key, Time: time.Now()})
+	return true
+}
+
+// Forget key of a script that could not be started so request can be replayed
+func forgetExecuted(key string) {
+	executedRequests.Delete(key)
+	writeJournal(journalEntry{Key: key, Time: time.Now(), Deleted: true})
+}
+
+// Append entry to journal. Errors are logged: key is still known until server restarts.
+func writeJournal(e journalEntry) {
+	journal.Lock()
+	defer journal.Unlock()
+	if journal.file == nil {
+		return
+	}
+	if e.Time.Sub(journal.started) > IDEMPOTENCY_TTL {
+		if err := rotateJournal(e.Time); err != nil {
+			logrus.Warningf("cannot rotate idempotency journal '%s': %v", journal.filename, err)
+			return
+		}
+	}
+	line, _ := json.Marshal(e)
+	if _, err := journal.file.Write(append(line, '\n')); err != nil {
+		logrus.Warningf("cannot write idempotency key to '%s': %v", journal.filename, err)
+	}
+}
+
+// Keep current journal as previous one and start a new one.
+// Caller must hold journal mutex.
+func rotateJournal(now time.Time) error {
+	journal.file.Close()
+	journal.file = nil
+	if err := os.Rename(journal.filename, journal.filename+".1"); err != nil {
+		return err
+	}
+	return openJournal(now)
+}
+
+// Caller must hold journal mutex
+func openJournal(started time.Time) error {
+	file, err := os.OpenFile(journal.filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	journal.file = file
+	journal.started = started
+	return nil
+}
+
+// Restore idempotency keys from journals in given folder and keep on writing new keys there
+func loadIdempotencyKeys(dir string) error {
+	journal.Lock()
+	defer journal.Unlock()
+	if journal.file != nil {
+		journal.file.Close()
+		journal.file = nil
+	}
+	journal.filename = filepath.Join(dir, IDEMPOTENCY_JOURNAL)
+	executedRequests = ttlcache.New[string, struct{}](IDEMPOTENCY_TTL)
+
+	now := time.Now()
+	if _, err := readJournal(journal.filename+".1", now); err != nil {
+		return err
+	}
+	started, err := readJournal(journal.filename, now)
+	if err != nil {
+		return err
+	}
+	return openJournal(started)
+}
+
+// Restore keys of a journal that have not expired yet.
+// Returns the time of first entry, now if journal is empty or does not exist.
+func readJournal(filename string, now time.Time) (time.Time, error) {
+	started := now
+	f, err := os.Open(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return started, nil
+	} else if err != nil {
+		return started, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for first := true; scanner.Scan(); {
+		var e journalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			// line may have been truncated by a crash
+			logrus.Warningf("ignore invalid line of idempotency journal '%s': %v", filename, err)
+			continue
+		}
+		if first {
+			started, first = e.Time, false
+		}
+		if e.Deleted {
+			executedRequests.Delete(e.Key)
+		} else if expires := e.Time.Add(IDEMPOTENCY_TTL); expires.After(now) {
+			executedRequests.SetUntil(e.Key, struct{}{}, expires)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return started, fmt.Errorf("cannot read idempotency journal '%s': %w", filename, err)
+	}
+	return started, nil
+}
@@ -0,0 +1,86 @@
+package mediatorscript
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Keep keys in memory only again
+func closeTestJournal() {
+	journal.Lock()
+	defer journal.Unlock()
+	if journal.file != nil {
+		journal.file.Close()
+		journal.file = nil
+	}
+}
+
+// Idempotency keys survive a restart
+func TestIdempotencyJournal(t *testing.T) {
+	dir := t.TempDir()
+	t.Cleanup(closeTestJournal)
+
+	// entries written before previous run: an expired key and a valid one
+	old := time.Now().Add(-IDEMPOTENCY_TTL - time.Hour)
+	var content []byte
+	for _, e := range []journalEntry{{Key: "a.sh/expired", Time: old}, {Key: "a.sh/recent", Time: time.Now().Add(-time.Hour)}} {
+		line, _ := json.Marshal(e)
+		content = append(content, append(line, '\n')...)
+	}
+	if err := os.WriteFile(filepath.Join(dir, IDEMPOTENCY_JOURNAL+".1"), content, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := loadIdempotencyKeys(dir); err != nil {
+		t.Fatal(err)
+	}
+	if !markExecuted("a.sh/event-1") || markExecuted("a.sh/event-1") {
+		t.Error("key must be accepted once")
+	}
+	markExecuted("a.sh/event-2")
+	forgetExecuted("a.sh/event-2")
+
+	// restart
+	if err := loadIdempotencyKeys(dir); err != nil {
+		t.Fatal(err)
+	}
+	testCases := map[string]bool{
+		"a.sh/event-1": false, // executed before restart
+		"a.sh/recent":  false, // executed before previous restart
+		"a.sh/event-2": true,  // forgotten: script could not be started
+		"a.sh/expired": true,
+		"b.sh/event-1": true,
+	}
+	for key, want := range testCases {
+		if got := markExecuted(key); got != want {
+			t.Errorf("markExecuted(%s) = %v after restart, want %v", key, got, want)
+		}
+	}
+}
+
+// Journal older than key TTL becomes previous journal
+func TestIdempotencyJournalRotation(t *testing.T) {
+	dir := t.TempDir()
+	if err := loadIdempotencyKeys(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(closeTestJournal)
+	markExecuted("a.sh/event-1")
+	journal.Lock()
+	journal.started = time.Now().Add(-IDEMPOTENCY_TTL - time.Minute)
+	journal.Unlock()
+	markExecuted("a.sh/event-2")
+
+	if _, err := os.Stat(filepath.Join(dir, IDEMPOTENCY_JOURNAL+".1")); err != nil {
+		t.Errorf("journal was not rotated: %v", err)
+	}
+	if err := loadIdempotencyKeys(dir); err != nil {
+		t.Fatal(err)
+	}
+	if markExecuted("a.sh/event-1") || markExecuted("a.sh/event-2") {
+		t.Error("keys of both journals must be restored")
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"io/fs"
 	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
@@ -14,12 +16,21 @@ func init() {
 	allScripts = make(map[string]*Script)
 }
 
-func Init(storage string) error {
+func Init(storage string) (err error) {
+	defer func() { loadError = err }()
+
 	if storage == "" {
 		return ErrInitNoFileName
 	} else {
 		scriptStorageFilename = storage
 		logrus.Infof("Mediatorscript package will use storage file '%s'", scriptStorageFilename)
+		// scripts can still run if keys cannot be restored: replays are then detected until next restart only
+		if err := loadIdempotencyKeys(filepath.Dir(scriptStorageFilename)); err != nil {
+			logrus.Warningf("cannot restore idempotency keys: %v", err)
+		}
+		scriptsMutex.Lock()
+		defer scriptsMutex.Unlock()
+		registryVersion++
 		allScripts = make(map[string]*Script)
 		if content, err := os.ReadFile(scriptStorageFilename); err != nil {
 			if !errors.Is(err, fs.ErrNotExist) {
@@ -33,3 +44,9 @@ func Init(storage string) error {
 	}
 	return nil
 }
+
+// Set maximum duration of a script execution. Scripts are killed when it is reached.
+// No timeout if d is 0.
+func SetExecutionTimeout(d time.Duration) {
+	executionTimeout = d
+}
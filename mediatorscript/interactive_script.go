@@ -7,7 +7,6 @@ import (
 	"net/http"
 
 	"github.com/labstack/echo/v4"
-	"github.com/sirupsen/logrus"
 )
 
 // execute special script
@@ -34,8 +33,6 @@ func genericHandler(t ScriptType, arg string, rr *RunResponse, c echo.Context) {
 	} else {
 		script := l[0]
 
-		logrus.Infof("Executing synchronously %s '%s' with arg '%s'", script.Type, script.Fullpath, arg)
-
 		if req := c.Request(); req == nil {
 			rr.statusCode = http.StatusInternalServerError
 			rr.err = ErrNoRequest
@@ -45,8 +42,19 @@ func genericHandler(t ScriptType, arg string, rr *RunResponse, c echo.Context) {
 			rr.err = fmt.Errorf("cannot read request body: %v", err)
 
 		} else {
+			// ticket ID is provided as argument for scripted conditions and tasks
+			// otherwise, try and get it from ticket info
+			ticket_id := arg
+			if ticket_id == "" {
+				ticket_id = ticketIDFromXML(b)
+			}
+			log := RequestLogger(c, ticket_id)
+			log.Infof("Executing synchronously %s '%s' with arg '%s'", script.Type, script.Fullpath, arg)
+
 			// execute script and store results in map
-			rr.RunResults[script.Name] = script.SyncRun(b, arg)
+			res := script.SyncRun(b, arg, log)
+			script.processResult(res, b, log)
+			rr.RunResults[script.Name] = res
 
 		}
 	}
@@ -0,0 +1,56 @@
+package mediatorscript
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+
+	"mediator/logger"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+)
+
+// Environment variables set when running scripts
+// so they can correlate their own logs with mediator ones
+const (
+	ENV_REQUEST_ID = "MEDIATOR_REQUEST_ID"
+	ENV_TICKET_ID  = "MEDIATOR_TICKET_ID"
+	// path of the JSON ticket document given to scripts registered with 'both' input
+	ENV_TICKET_JSON = "MEDIATOR_TICKET_JSON"
+	// path of the complete ticket fetched from Securechange for scripts registered with enrich option
+	ENV_TICKET_DETAILS = "MEDIATOR_TICKET_DETAILS"
+)
+
+// Return a log entry carrying the request ID and the ticket ID, if any.
+// Request ID is the one sent by mediator-client in X-Request-ID header or generated by server.
+func RequestLogger(c echo.Context, ticket_id string) *logrus.Entry {
+	fields := logrus.Fields{}
+	if id := c.Response().Header().Get(echo.HeaderXRequestID); id != "" {
+		fields[logger.FIELD_REQUEST_ID] = id
+	}
+	if ticket_id != "" {
+		fields[logger.FIELD_TICKET_ID] = ticket_id
+	}
+	return logrus.WithFields(fields)
+}
+
+// Get ticket ID from ticket_info XML data. Return an empty string if there is none.
+func ticketIDFromXML(data []byte) string {
+	var ti TicketInfo
+	if err := xml.Unmarshal(data, &ti); err != nil || ti.ID == 0 {
+		return ""
+	}
+	return strconv.Itoa(ti.ID)
+}
+
+func scriptEnv(log *logrus.Entry) []string {
+	env := []string{}
+	if id, ok := log.Data[logger.FIELD_REQUEST_ID]; ok {
+		env = append(env, fmt.Sprintf("%s=%v", ENV_REQUEST_ID, id))
+	}
+	if id, ok := log.Data[logger.FIELD_TICKET_ID]; ok {
+		env = append(env, fmt.Sprintf("%s=%v", ENV_TICKET_ID, id))
+	}
+	return env
+}
@@ -0,0 +1,32 @@
+package mediatorscript
+
+import (
+	"fmt"
+
+	"mediator/logger"
+	"mediator/notify"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Send a notification about this script.
+// Ticket and request IDs are taken from log entry fields.
+func (s *Script) notify(event string, log *logrus.Entry, stderr string, err error) {
+	e := notify.Event{
+		Type:       event,
+		Script:     s.Name,
+		ScriptType: s.Type.String(),
+		ExitCode:   getExitCodeFromError(err),
+		Stderr:     stderr,
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	if id, ok := log.Data[logger.FIELD_TICKET_ID]; ok {
+		e.TicketID = fmt.Sprint(id)
+	}
+	if id, ok := log.Data[logger.FIELD_REQUEST_ID]; ok {
+		e.RequestID = fmt.Sprint(id)
+	}
+	notify.Notify(e)
+}
@@ -0,0 +1,70 @@
+package mediatorscript
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"mediator/notify"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Only trigger executions are notified: test runs and interactive scripts report failures to their caller
+func TestScriptFailureNotification(t *testing.T) {
+	events := make(chan notify.Event, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e notify.Event
+		json.NewDecoder(r.Body).Decode(&e)
+		events <- e
+	}))
+	defer server.Close()
+	if err := notify.Init(notify.Configuration{Webhooks: []notify.WebhookConfiguration{{URL: server.URL}}}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { notify.Init(notify.Configuration{}) })
+
+	path := filepath.Join(t.TempDir(), "fail.sh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\ncat > /dev/null\nexit 3\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	log := logrus.NewEntry(logrus.StandardLogger())
+	newScript := func(st ScriptType) *Script {
+		s := &Script{Name: "fail.sh", Fullpath: path, Type: st}
+		if hash, err := s.computeHash(); err != nil {
+			t.Fatal(err)
+		} else {
+			s.Hash = hash
+		}
+		return s
+	}
+
+	if res := newScript(ScriptTrigger).Test(log); res.ExitCode != 3 {
+		t.Errorf("test run exit code = %d", res.ExitCode)
+	}
+	if res := newScript(ScriptCondition).SyncRun([]byte("<ticket/>"), "", log); res.ExitCode != 3 {
+		t.Errorf("interactive script exit code = %d", res.ExitCode)
+	}
+	notify.Close()
+	select {
+	case e := <-events:
+		t.Fatalf("unexpected notification: %+v", e)
+	default:
+	}
+
+	if err := newScript(ScriptTrigger).AsyncRunWithXML(&TicketInfo{}, []byte("<ticket_info/>"), log); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case e := <-events:
+		if e.Type != notify.EventScriptFailure || e.ExitCode != 3 {
+			t.Errorf("unexpected notification: %+v", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("trigger failure was not notified")
+	}
+}
@@ -22,6 +22,9 @@ type SyncRunResponse struct {
 	StdOut        string     `json:"stdout"`
 	StdErr        string     `json:"stderr"`
 	Type          ScriptType `json:"type"`
+	// decoded output of scripts registered with JSON result
+	Result      *ScriptResult `json:"result,omitempty"`
+	ResultError string        `json:"result_error,omitempty"`
 }
 
 type SyncRunResponsesMap map[string]*SyncRunResponse
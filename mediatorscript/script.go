@@ -1,35 +1,54 @@
 package mediatorscript
 
 import (
+	"context"
 	"crypto/hmac"
 	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"os"
 	"os/exec"
 	"strings"
+	"sync"
+	"time"
+
+	"mediator/logger"
+	"mediator/metrics"
+	"mediator/notify"
 
 	"github.com/sirupsen/logrus"
 	"golang.org/x/sys/unix"
 )
 
 type Script struct {
-	Fullpath string     `mapstructure:"fullpath" json:"fullpath"`
-	Name     string     `mapstructure:"name" json:"name"`
-	Hash     []byte     `mapstructure:"hash" json:"hash"`
-	Type     ScriptType `mapstructure:"type" json:"type"`
+	Fullpath string        `mapstructure:"fullpath" json:"fullpath"`
+	Name     string        `mapstructure:"name" json:"name"`
+	Hash     []byte        `mapstructure:"hash" json:"hash"`
+	Type     ScriptType    `mapstructure:"type" json:"type"`
+	Options  ScriptOptions `mapstructure:"options" json:"options"`
 }
 
 type ScriptList []*Script
 
 var (
-	allScripts            map[string]*Script
+	// guards allScripts and script hashes: scripts are registered while others run
+	scriptsMutex sync.RWMutex
+	allScripts   map[string]*Script
+	// incremented on every registry change
+	registryVersion       uint64
 	scriptStorageFilename string
+	// error returned by the last call to Init
+	loadError = ErrRegistryNotLoaded
+	// maximum duration of a script execution. 0 means no timeout
+	executionTimeout time.Duration
 )
 
 func GetScriptByName(name string) (*Script, error) {
+	scriptsMutex.RLock()
+	defer scriptsMutex.RUnlock()
 	if s, exist := allScripts[name]; !exist {
 		return nil, ErrScriptNotFound
 	} else {
@@ -40,6 +59,8 @@ func GetScriptByName(name string) (*Script, error) {
 // return all the scripts of the given type in a slice
 // if given type is ScriptAll, return all script
 func GetScriptByType(t ScriptType) ScriptList {
+	scriptsMutex.RLock()
+	defer scriptsMutex.RUnlock()
 	l := ScriptList{}
 	for _, s := range allScripts {
 		if s.Type == t || t == ScriptAll {
@@ -50,6 +71,13 @@ func GetScriptByType(t ScriptType) ScriptList {
 }
 
 func IsEmpty(t ScriptType) bool {
+	scriptsMutex.RLock()
+	defer scriptsMutex.RUnlock()
+	return isEmpty(t)
+}
+
+// Same as IsEmpty. Caller must hold scriptsMutex
+func isEmpty(t ScriptType) bool {
 	for _, s := range allScripts {
 		if s.Type == t {
 			return false
@@ -59,6 +87,8 @@ func IsEmpty(t ScriptType) bool {
 }
 
 func RemoveScriptByName(name string) error {
+	scriptsMutex.Lock()
+	defer scriptsMutex.Unlock()
 	if _, exist := allScripts[name]; !exist {
 		return fmt.Errorf("script '%s' does not exist", name)
 	} else {
@@ -68,6 +98,8 @@ func RemoveScriptByName(name string) error {
 }
 
 func RemoveScriptByType(t ScriptType) error {
+	scriptsMutex.Lock()
+	defer scriptsMutex.Unlock()
 	if t == ScriptAll && len(allScripts) > 0 {
 		allScripts = make(map[string]*Script)
 	} else {
@@ -81,6 +113,8 @@ func RemoveScriptByType(t ScriptType) error {
 }
 
 func GetAllScriptNames() []string {
+	scriptsMutex.RLock()
+	defer scriptsMutex.RUnlock()
 	keys := make([]string, 0, len(allScripts))
 	for k, s := range allScripts {
 		keys = append(keys, fmt.Sprintf("%s: %s", k, s.Fullpath))
@@ -104,6 +138,8 @@ func (s *Script) Save() error {
 		return err
 	}
 
+	scriptsMutex.Lock()
+	defer scriptsMutex.Unlock()
 	if err := safeAdd(s.Name, s); err != nil {
 		return err
 	}
@@ -123,6 +159,9 @@ func (s *Script) checkScript() error {
 	if s.Name == "test" {
 		return ErrRegisterNameNotAllowed
 	}
+	if err := s.checkOptions(); err != nil {
+		return err
+	}
 
 	// The following was copied from
 	// https://gitlab.com/StellarpowerGroupedProjects/tidbits/go/-/blob/main/CheckFileExecutable.go
@@ -150,12 +189,17 @@ func (s *Script) checkScript() error {
 func (s *Script) Refresh() error {
 	var err error
 	logrus.Infof("Refreshing %s", s)
-	if s.Hash, err = s.computeHash(); err != nil {
+	hash, err := s.computeHash()
+	if err != nil {
 		return err
 	}
+	scriptsMutex.Lock()
+	defer scriptsMutex.Unlock()
+	s.Hash = hash
 	return save()
 }
 
+// Add script to registry. Caller must hold scriptsMutex
 func safeAdd(name string, item *Script) error {
 	if s, exist := allScripts[name]; exist {
 		// script with same name already. Is it same type?
@@ -167,7 +211,7 @@ func safeAdd(name string, item *Script) error {
 			return fmt.Errorf("%w: %s as %s", ErrRegisterAlreadyExistWithDifferentType, name, s.Type)
 		}
 
-	} else if item.Type == ScriptTrigger || IsEmpty(item.Type) {
+	} else if item.Type == ScriptTrigger || isEmpty(item.Type) {
 		// we can have several trigger scripts but only one of the other type
 		// so now we know the name is not in use, just make sure the slot is empty if type is not trigger
 		// If so, append the new script
@@ -180,7 +224,9 @@ func safeAdd(name string, item *Script) error {
 	}
 }
 
+// Write registry to storage file. Caller must hold scriptsMutex
 func save() error {
+	registryVersion++
 
 	// marshall list into JSON
 	if content, err := json.MarshalIndent(allScripts, "", " "); err != nil {
@@ -191,27 +237,60 @@ func save() error {
 	return nil
 }
 
-func (s *Script) checkHash() error {
+// Check script file before execution
+func (s *Script) checkHash(log *logrus.Entry) error {
+	err := s.verifyHash()
+	if errors.Is(err, ErrHashMismatch) {
+		metrics.HashMismatches.Inc(s.Name)
+		invalidateIntegrity()
+		s.notify(notify.EventIntegrityViolation, log, "", err)
+	}
+	return err
+}
+
+func (s *Script) verifyHash() error {
+	scriptsMutex.RLock()
+	expected := s.Hash
+	scriptsMutex.RUnlock()
 	if hash, err := s.computeHash(); err != nil {
 		return err
-	} else if !hmac.Equal(hash, s.Hash) {
+	} else if !hmac.Equal(hash, expected) {
 		return fmt.Errorf("%w for %s script %s (%s)", ErrHashMismatch, s.Type, s.Name, s.Fullpath)
 	}
 	return nil
 }
 
-func (s *Script) AsyncRun(ti *TicketInfo) error {
-	if err := s.checkHash(); err != nil {
+func (s *Script) AsyncRun(ti *TicketInfo, log *logrus.Entry) error {
+	if data, err := xml.Marshal(ti); err != nil {
+		return err
+	} else {
+		return s.AsyncRunWithXML(ti, data, log)
+	}
+}
+
+// Run script asynchronously with raw ticket XML if any, ticket info otherwise
+func (s *Script) asyncRun(ti *TicketInfo, raw []byte, log *logrus.Entry) error {
+	if raw != nil {
+		return s.AsyncRunWithXML(ti, raw, log)
+	}
+	return s.AsyncRun(ti, log)
+}
+
+// Run script asynchronously with given ticket XML as input.
+// ti must be decoded from data: it is used to post feedback to the ticket.
+func (s *Script) AsyncRunWithXML(ti *TicketInfo, data []byte, log *logrus.Entry) error {
+	if err := s.checkHash(log); err != nil {
 		return err
 
 	} else {
-		f := s.getRunFunction()
-		if data, err := xml.Marshal(ti); err != nil {
-			return err
-		} else {
-			logrus.Infof("running script %s (%s) with data: %s", s.Name, s.Fullpath, data)
-			go f(data, "")
-		}
+		f := s.getRunFunction(log, true)
+		log.Infof("running script %s (%s) with data: %s", s.Name, s.Fullpath, data)
+		metrics.AsyncQueueDepth.Inc()
+		go func() {
+			defer metrics.AsyncQueueDepth.Dec()
+			stdout, stderr, err := f(data, "")
+			s.sendFeedback(ti, stdout, stderr, err, log)
+		}()
 
 		return nil
 	}
@@ -220,7 +299,7 @@ func (s *Script) AsyncRun(ti *TicketInfo) error {
 
 // Run script in test mode
 
-func (s *Script) Test() *SyncRunResponse {
+func (s *Script) Test(log *logrus.Entry) *SyncRunResponse {
 
 	input := []byte("<ticket_info/>")
 	arg := ""
@@ -228,13 +307,13 @@ func (s *Script) Test() *SyncRunResponse {
 		arg = "test"
 	}
 
-	return s.execute(input, arg)
+	return s.execute(input, arg, log)
 }
 
 // Execute a script synchronously with given arg.
 // Return a SyncRunResponse struct with outputs.
 // We make a difference between script errors and internal errors
-func (s *Script) execute(input []byte, arg string) *SyncRunResponse {
+func (s *Script) execute(input []byte, arg string, log *logrus.Entry) *SyncRunResponse {
 	var (
 		res SyncRunResponse
 		err error
@@ -242,12 +321,13 @@ func (s *Script) execute(input []byte, arg string) *SyncRunResponse {
 
 	res.Type = s.Type
 
-	if res.internalError = s.checkHash(); res.internalError != nil {
+	if res.internalError = s.checkHash(log); res.internalError != nil {
 		return &res
 	}
 
-	// get function to run
-	f := s.getRunFunction()
+	// get function to run. Test runs and interactive scripts report failures to their caller:
+	// they are not notified
+	f := s.getRunFunction(log, false)
 
 	// run script
 	res.StdOut, res.StdErr, err = f(input, arg)
@@ -267,7 +347,53 @@ func (s *Script) execute(input []byte, arg string) *SyncRunResponse {
 	return &res
 }
 
-func (s *Script) getRunFunction() func([]byte, string) (string, string, error) {
+// Return the function that runs the script.
+// Every execution is accounted in metrics. Failures and timeouts are notified if notify_failures is true.
+func (s *Script) getRunFunction(log *logrus.Entry, notify_failures bool) func([]byte, string) (string, string, error) {
+	run := s.getExecFunction(log)
+	return func(input []byte, arg string) (string, string, error) {
+		metrics.ScriptsRunning.Inc()
+		start := time.Now()
+
+		stdout, stderr, err := run(input, arg)
+
+		metrics.ScriptsRunning.Dec()
+		metrics.ScriptExecutionDuration.Observe(time.Since(start).Seconds(), s.Name, s.Type.Slug())
+		outcome := metrics.OUTCOME_SUCCESS
+		event := notify.EventScriptFailure
+		if errors.Is(err, ErrScriptTimeout) {
+			outcome = metrics.OUTCOME_TIMEOUT
+			event = notify.EventScriptTimeout
+		} else if err != nil && errorIsScriptFailure(err) {
+			outcome = metrics.OUTCOME_FAILURE
+		} else if err != nil {
+			outcome = metrics.OUTCOME_ERROR
+		}
+		if notify_failures && outcome != metrics.OUTCOME_SUCCESS {
+			s.notify(event, log, stderr, err)
+		}
+		metrics.ScriptExecutions.Inc(s.Name, s.Type.Slug(), outcome)
+
+		// summary event: fields are forwarded as structured data to syslog and journald sinks
+		entry := log.WithFields(logrus.Fields{
+			logger.FIELD_SCRIPT:      s.Name,
+			logger.FIELD_SCRIPT_TYPE: s.Type.Slug(),
+			logger.FIELD_OUTCOME:     outcome,
+			logger.FIELD_DURATION:    time.Since(start).Milliseconds(),
+		})
+		if outcome == metrics.OUTCOME_SUCCESS {
+			entry.WithField(logger.FIELD_EXIT_CODE, 0).Info("script execution completed")
+		} else if outcome == metrics.OUTCOME_FAILURE || outcome == metrics.OUTCOME_TIMEOUT {
+			entry.WithField(logger.FIELD_EXIT_CODE, getExitCodeFromError(err)).Warningf("script execution failed: %v", err)
+		} else {
+			entry.Warningf("script execution error: %v", err)
+		}
+
+		return stdout, stderr, err
+	}
+}
+
+func (s *Script) getExecFunction(log *logrus.Entry) func([]byte, string) (string, string, error) {
 	return func(input []byte, arg string) (string, string, error) {
 		var (
 			stdin          io.WriteCloser
@@ -275,14 +401,46 @@ func (s *Script) getRunFunction() func([]byte, string) (string, string, error) {
 			err            error
 			cmd            *exec.Cmd
 		)
+		ctx, cancel := context.Background(), context.CancelFunc(func() {})
+		if executionTimeout > 0 {
+			ctx, cancel = context.WithTimeout(ctx, executionTimeout)
+		}
+		defer cancel()
 		if arg == "" {
-			cmd = exec.Command(s.Fullpath)
+			cmd = exec.CommandContext(ctx, s.Fullpath)
 		} else {
-			cmd = exec.Command(s.Fullpath, arg)
+			cmd = exec.CommandContext(ctx, s.Fullpath, arg)
+		}
+		if executionTimeout > 0 {
+			// run script in its own process group so its children are killed with it on timeout
+			cmd.SysProcAttr = &unix.SysProcAttr{Setpgid: true}
+			cmd.Cancel = func() error {
+				return unix.Kill(-cmd.Process.Pid, unix.SIGKILL)
+			}
+			// do not wait forever for processes that escaped the group and keep stdout or stderr open
+			cmd.WaitDelay = 5 * time.Second
 		}
+		// scripts can use request and ticket IDs to correlate their own logs with mediator ones
+		cmd.Env = append(os.Environ(), scriptEnv(log)...)
 
 		// warm stdin up if we need to send data
 		if input != nil {
+			// convert ticket info according to script input format
+			var (
+				env     []string
+				cleanup func()
+			)
+			details, remove := s.enrich(input, log)
+			defer remove()
+			cmd.Env = append(cmd.Env, details...)
+
+			input, env, cleanup, err = s.prepareInput(input)
+			defer cleanup()
+			if err != nil {
+				return "", "", err
+			}
+			cmd.Env = append(cmd.Env, env...)
+
 			if stdin, err = cmd.StdinPipe(); err != nil {
 				return "", "", err
 			}
@@ -294,13 +452,13 @@ func (s *Script) getRunFunction() func([]byte, string) (string, string, error) {
 		cmd.Stderr = &stderr
 
 		// start the script
-		logrus.Infof("Starting %s", s)
+		log.Infof("Starting %s", s)
 		if err := cmd.Start(); err != nil {
 			out := strings.TrimSpace(stdout.String())
 			er := strings.TrimSpace(stderr.String())
-			logrus.Warningf("stdout: %s", out)
-			logrus.Warningf("stderr: %s", er)
-			logrus.Warningf("err: %v", err)
+			log.Warningf("stdout: %s", out)
+			log.Warningf("stderr: %s", er)
+			log.Warningf("err: %v", err)
 			return out, er, err
 		}
 
@@ -308,9 +466,9 @@ func (s *Script) getRunFunction() func([]byte, string) (string, string, error) {
 		if _, err := stdin.Write(input); err != nil {
 			out := strings.TrimSpace(stdout.String())
 			er := strings.TrimSpace(stderr.String())
-			logrus.Warningf("stdout: %s", stdout.String())
-			logrus.Warningf("stderr: %s", stderr.String())
-			logrus.Warningf("err: %v", err)
+			log.Warningf("stdout: %s", stdout.String())
+			log.Warningf("stderr: %s", stderr.String())
+			log.Warningf("err: %v", err)
 			return out, er, err
 		}
 
@@ -318,33 +476,36 @@ func (s *Script) getRunFunction() func([]byte, string) (string, string, error) {
 		if err := stdin.Close(); err != nil {
 			out := strings.TrimSpace(stdout.String())
 			er := strings.TrimSpace(stderr.String())
-			logrus.Warningf("stdout: %s", stdout.String())
-			logrus.Warningf("stderr: %s", stderr.String())
-			logrus.Warningf("err: %v", err)
+			log.Warningf("stdout: %s", stdout.String())
+			log.Warningf("stderr: %s", stderr.String())
+			log.Warningf("err: %v", err)
 			return out, er, err
 		}
 
 		if err := cmd.Wait(); err != nil {
+			if ctx.Err() == context.DeadlineExceeded {
+				err = fmt.Errorf("%w after %s: %w", ErrScriptTimeout, executionTimeout, err)
+			}
 			out := strings.TrimSpace(stdout.String())
 			er := strings.TrimSpace(stderr.String())
-			logrus.Warningf("stdout: %s", stdout.String())
-			logrus.Warningf("stderr: %s", stderr.String())
-			logrus.Warningf("err: %v", err)
+			log.Warningf("stdout: %s", stdout.String())
+			log.Warningf("stderr: %s", stderr.String())
+			log.Warningf("err: %v", err)
 			return out, er, err
 		}
 
 		out := strings.TrimSpace(stdout.String())
 		er := strings.TrimSpace(stderr.String())
-		logrus.Infof("script %s was run successfully", s.Fullpath)
-		logrus.Infof("stdout: %s", stdout.String())
-		logrus.Infof("stderr: %s", stderr.String())
+		log.Infof("script %s was run successfully", s.Fullpath)
+		log.Infof("stdout: %s", stdout.String())
+		log.Infof("stderr: %s", stderr.String())
 		return out, er, nil
 	}
 }
 
-func (s *Script) SyncRun(input []byte, arg string) *SyncRunResponse {
+func (s *Script) SyncRun(input []byte, arg string, log *logrus.Entry) *SyncRunResponse {
 	if s.Type == ScriptTrigger {
-		logrus.Warningf("Trigger Script '%s' is run synchronously. Such scripts are usually run asynchronously.", string(input))
+		log.Warningf("Trigger Script '%s' is run synchronously. Such scripts are usually run asynchronously.", string(input))
 	}
-	return s.execute(input, arg)
+	return s.execute(input, arg, log)
 }
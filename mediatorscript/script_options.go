@@ -0,0 +1,74 @@
+package mediatorscript
+
+import (
+	"fmt"
+)
+
+// Where trigger script output is posted in Securechange ticket
+const (
+	FEEDBACK_NONE    = ""
+	FEEDBACK_COMMENT = "comment"
+	FEEDBACK_FIELD   = "field"
+)
+
+// What scripts receive on stdin
+const (
+	INPUT_XML  = ""
+	INPUT_JSON = "json"
+	// XML on stdin and JSON in a temporary file. File name is in MEDIATOR_TICKET_JSON environment variable
+	INPUT_BOTH = "both"
+)
+
+// Per-script settings provided at registration
+type ScriptOptions struct {
+	// post trigger script output back to the ticket: none (default), comment or field
+	Feedback string `mapstructure:"feedback" json:"feedback,omitempty"`
+	// name of the task text field updated when Feedback is 'field'
+	FeedbackField string `mapstructure:"feedbackfield" json:"feedbackfield,omitempty"`
+	// post an excerpt of stderr with script failures. It may show paths, hosts or credentials
+	FeedbackStderr bool `mapstructure:"feedbackstderr" json:"feedbackstderr,omitempty"`
+	// interactive scripts: text (default, output is relayed as is) or json
+	Result string `mapstructure:"result" json:"result,omitempty"`
+	// xml (default), json or both
+	Input string `mapstructure:"input" json:"input,omitempty"`
+	// fetch complete ticket from Securechange before running the script
+	Enrich bool `mapstructure:"enrich" json:"enrich,omitempty"`
+}
+
+func (s *Script) checkOptions() error {
+	switch s.Options.Input {
+	case INPUT_XML, INPUT_JSON, INPUT_BOTH:
+	default:
+		return fmt.Errorf("%w: '%s'", ErrUnknownInput, s.Options.Input)
+	}
+
+	switch s.Options.Result {
+	case RESULT_TEXT:
+	case RESULT_JSON:
+		if !resultIsSupported(s.Type) {
+			return fmt.Errorf("%w: %s", ErrResultNotSupported, s.Type)
+		}
+	default:
+		return fmt.Errorf("%w: '%s'", ErrUnknownResult, s.Options.Result)
+	}
+
+	switch s.Options.Feedback {
+	case FEEDBACK_NONE:
+		if s.Options.FeedbackStderr {
+			return ErrStderrWithoutFeedback
+		}
+		return nil
+	case FEEDBACK_COMMENT:
+	case FEEDBACK_FIELD:
+		if s.Options.FeedbackField == "" {
+			return ErrNoFeedbackField
+		}
+	default:
+		return fmt.Errorf("%w: '%s'", ErrUnknownFeedback, s.Options.Feedback)
+	}
+	if s.Type != ScriptTrigger {
+		// other scripts already send their output to Securechange
+		return fmt.Errorf("%w: %s", ErrFeedbackNotSupported, s.Type)
+	}
+	return nil
+}
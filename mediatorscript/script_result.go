@@ -0,0 +1,118 @@
+package mediatorscript
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// How interactive scripts send their result
+const (
+	RESULT_TEXT = ""
+	RESULT_JSON = "json"
+)
+
+// Decisions of interactive scripts using JSON result
+const (
+	DECISION_TRUE    = "true"
+	DECISION_FALSE   = "false"
+	DECISION_SUCCESS = "success"
+	DECISION_FAILURE = "failure"
+)
+
+// Result printed on stdout by interactive scripts registered with JSON result:
+//
+//	{"decision": "success", "message": "Rule created", "fields": {"Result": "done"}}
+//
+// Decision depends on script type:
+//   - Scripted Condition: true or false
+//   - Scripted Task: success or failure
+//   - Pre-Assignment: name of the assignee
+type ScriptResult struct {
+	Decision string `json:"decision"`
+	// shown in ticket history
+	Message string `json:"message,omitempty"`
+	// task text fields to update, by name
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+func resultIsSupported(t ScriptType) bool {
+	return t == ScriptCondition || t == ScriptTask || t == ScriptAssignment
+}
+
+// Decode and validate the result printed by a script of given type
+func ParseScriptResult(t ScriptType, stdout string) (*ScriptResult, error) {
+	var r ScriptResult
+
+	dec := json.NewDecoder(bytes.NewReader([]byte(stdout)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&r); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidResult, err)
+	} else if dec.More() {
+		return nil, fmt.Errorf("%w: unexpected data after JSON object", ErrInvalidResult)
+	}
+
+	r.Decision = strings.TrimSpace(r.Decision)
+	switch t {
+	case ScriptCondition:
+		r.Decision = strings.ToLower(r.Decision)
+		if r.Decision != DECISION_TRUE && r.Decision != DECISION_FALSE {
+			return nil, fmt.Errorf("%w: decision must be '%s' or '%s', got '%s'", ErrInvalidResult, DECISION_TRUE, DECISION_FALSE, r.Decision)
+		}
+	case ScriptTask:
+		r.Decision = strings.ToLower(r.Decision)
+		if r.Decision != DECISION_SUCCESS && r.Decision != DECISION_FAILURE {
+			return nil, fmt.Errorf("%w: decision must be '%s' or '%s', got '%s'", ErrInvalidResult, DECISION_SUCCESS, DECISION_FAILURE, r.Decision)
+		}
+	case ScriptAssignment:
+		if r.Decision == "" || strings.ContainsAny(r.Decision, "\r\n") {
+			return nil, fmt.Errorf("%w: decision must be the name of the assignee", ErrInvalidResult)
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrResultNotSupported, t)
+	}
+	for name := range r.Fields {
+		if strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("%w: empty field name", ErrInvalidResult)
+		}
+	}
+	return &r, nil
+}
+
+// Translate result into what Securechange expects from a script of given type
+func (r *ScriptResult) Contract(t ScriptType) (stdout string, stderr string, exit_code int) {
+	switch t {
+	case ScriptCondition:
+		// condition is met if script prints 'true'
+		return r.Decision, r.Message, 0
+	case ScriptTask:
+		// task fails on non-zero exit code. Message is shown in ticket history
+		if r.Decision == DECISION_FAILURE {
+			return "", r.Message, 1
+		}
+		return r.Message, "", 0
+	default:
+		return r.Decision, r.Message, 0
+	}
+}
+
+// Decode JSON result of a successful run and write requested fields in ticket
+func (s *Script) processResult(res *SyncRunResponse, input []byte, log *logrus.Entry) {
+	if s.Options.Result != RESULT_JSON || res.internalError != nil || res.scriptError != nil {
+		return
+	}
+	if r, err := ParseScriptResult(s.Type, res.StdOut); err != nil {
+		log.Errorf("%s returned an invalid result: %v. Output was: %s", s, err, res.StdOut)
+		res.ResultError = err.Error()
+	} else {
+		res.Result = r
+		if len(r.Fields) > 0 {
+			if err := s.postFields(input, r.Fields); err != nil {
+				log.Warningf("cannot update ticket fields returned by %s: %v", s, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package mediatorscript
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseScriptResult(t *testing.T) {
+	tests := []struct {
+		name    string
+		t       ScriptType
+		stdout  string
+		want    *ScriptResult
+		wantErr error
+	}{
+		{
+			name:   "condition",
+			t:      ScriptCondition,
+			stdout: `{"decision": "TRUE", "message": "rule exists"}` + "\n",
+			want:   &ScriptResult{Decision: DECISION_TRUE, Message: "rule exists"},
+		},
+		{
+			name:    "condition with task decision",
+			t:       ScriptCondition,
+			stdout:  `{"decision": "success"}`,
+			wantErr: ErrInvalidResult,
+		},
+		{
+			name:   "task with fields",
+			t:      ScriptTask,
+			stdout: `{"decision": "failure", "message": "device unreachable", "fields": {"Result": "ko"}}`,
+			want:   &ScriptResult{Decision: DECISION_FAILURE, Message: "device unreachable", Fields: map[string]string{"Result": "ko"}},
+		},
+		{
+			name:    "task with empty field name",
+			t:       ScriptTask,
+			stdout:  `{"decision": "success", "fields": {" ": "ok"}}`,
+			wantErr: ErrInvalidResult,
+		},
+		{
+			name:   "pre-assignment",
+			t:      ScriptAssignment,
+			stdout: `{"decision": " jdoe "}`,
+			want:   &ScriptResult{Decision: "jdoe"},
+		},
+		{
+			name:    "pre-assignment without assignee",
+			t:       ScriptAssignment,
+			stdout:  `{"message": "nobody"}`,
+			wantErr: ErrInvalidResult,
+		},
+		{
+			name:    "text output",
+			t:       ScriptCondition,
+			stdout:  "true",
+			wantErr: ErrInvalidResult,
+		},
+		{
+			name:    "unknown field",
+			t:       ScriptCondition,
+			stdout:  `{"decision": "true", "exit_code": 0}`,
+			wantErr: ErrInvalidResult,
+		},
+		{
+			name:    "trailing data",
+			t:       ScriptCondition,
+			stdout:  `{"decision": "true"} {"decision": "false"}`,
+			wantErr: ErrInvalidResult,
+		},
+		{
+			name:    "risk analysis",
+			t:       RiskAnalysis,
+			stdout:  `{"decision": "true"}`,
+			wantErr: ErrResultNotSupported,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseScriptResult(tt.t, tt.stdout)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParseScriptResult() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseScriptResult() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScriptResult_Contract(t *testing.T) {
+	tests := []struct {
+		name       string
+		t          ScriptType
+		r          ScriptResult
+		wantStdout string
+		wantStderr string
+		wantCode   int
+	}{
+		{"condition met", ScriptCondition, ScriptResult{Decision: DECISION_TRUE, Message: "ok"}, "true", "ok", 0},
+		{"condition not met", ScriptCondition, ScriptResult{Decision: DECISION_FALSE}, "false", "", 0},
+		{"task success", ScriptTask, ScriptResult{Decision: DECISION_SUCCESS, Message: "done"}, "done", "", 0},
+		{"task failure", ScriptTask, ScriptResult{Decision: DECISION_FAILURE, Message: "unreachable"}, "", "unreachable", 1},
+		{"pre-assignment", ScriptAssignment, ScriptResult{Decision: "jdoe"}, "jdoe", "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr, code := tt.r.Contract(tt.t)
+			if stdout != tt.wantStdout || stderr != tt.wantStderr || code != tt.wantCode {
+				t.Errorf("Contract() = %q, %q, %d, want %q, %q, %d", stdout, stderr, code, tt.wantStdout, tt.wantStderr, tt.wantCode)
+			}
+		})
+	}
+}
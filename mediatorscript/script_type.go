@@ -64,6 +64,12 @@ func (ft ScriptType) Slug() string {
 	}
 }
 
+// Name mediator-client is called with for this interactive script type when it is installed as a symlink.
+// Its helper script has the same name with '.sh' extension.
+func (ft ScriptType) HelperName() string {
+	return "mediator-client-" + ft.Slug()
+}
+
 func GetTypeFromSlug(s string) (ScriptType, error) {
 	if t, ok := fromSlugToID[s]; ok {
 		return t, nil
@@ -6,7 +6,7 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-func TestAllScriptsByTypeAndName(script_type ScriptType, script_name string, res *RunResponse) {
+func TestAllScriptsByTypeAndName(script_type ScriptType, script_name string, res *RunResponse, log *logrus.Entry) {
 	var (
 		list ScriptList
 	)
@@ -26,8 +26,8 @@ func TestAllScriptsByTypeAndName(script_type ScriptType, script_name string, res
 			continue
 		}
 
-		logrus.Infof("Testing %s", script)
-		res.RunResults[script.Name] = script.Test()
+		log.Infof("Testing %s", script)
+		res.RunResults[script.Name] = script.Test(log)
 	}
 
 	// return OK status because everythin went well on our side
@@ -0,0 +1,136 @@
+package mediatorscript
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+)
+
+// Version of the JSON document given to scripts.
+// It is increased on any change that could break existing scripts.
+const TICKET_DOCUMENT_VERSION = 1
+
+// Stable JSON representation of a ticket given to scripts registered with JSON input.
+// TicketInfo is not used directly: its field names are the ones of the XML sent by Securechange.
+type TicketDocument struct {
+	SchemaVersion    int                    `json:"schema_version"`
+	ID               int                    `json:"id"`
+	Subject          string                 `json:"subject"`
+	Priority         TicketPriorityDocument `json:"priority"`
+	CreateDate       int                    `json:"create_date"`
+	UpdateDate       int                    `json:"update_date"`
+	Requester        TicketUserDocument     `json:"requester"`
+	CurrentStage     *TicketStageDocument   `json:"current_stage"`
+	CompletionStage  *TicketStageDocument   `json:"completion_stage"`
+	OpenRequestStage TicketStageDocument    `json:"open_request_stage"`
+	Comment          string                 `json:"comment"`
+}
+
+type TicketPriorityDocument struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type TicketStageDocument struct {
+	ID   int                `json:"id"`
+	Name string             `json:"name"`
+	Task TicketTaskDocument `json:"task"`
+}
+
+type TicketTaskDocument struct {
+	ID      int                `json:"id"`
+	Name    string             `json:"name"`
+	Handler TicketUserDocument `json:"handler"`
+}
+
+type TicketUserDocument struct {
+	ID          int    `json:"id"`
+	Login       string `json:"login"`
+	DisplayName string `json:"display_name"`
+}
+
+func (ti *TicketInfo) Document() *TicketDocument {
+	return &TicketDocument{
+		SchemaVersion: TICKET_DOCUMENT_VERSION,
+		ID:            ti.ID,
+		Subject:       ti.Subject,
+		Priority: TicketPriorityDocument{
+			ID:   ti.PriorityID,
+			Name: ti.PriorityName,
+		},
+		CreateDate:       ti.CreateDate,
+		UpdateDate:       ti.UpdateDate,
+		Requester:        ti.Requester.document(),
+		CurrentStage:     ti.CurrentStage.document(),
+		CompletionStage:  ti.CompletionData.document(),
+		OpenRequestStage: *ti.OpenRequestStage.document(),
+		Comment:          ti.Comment,
+	}
+}
+
+func (ts *TicketStage) document() *TicketStageDocument {
+	if ts == nil {
+		return nil
+	}
+	return &TicketStageDocument{
+		ID:   ts.ID,
+		Name: ts.Name,
+		Task: TicketTaskDocument{
+			ID:      ts.TaskID,
+			Name:    ts.TaskName,
+			Handler: ts.TaskHandler.document(),
+		},
+	}
+}
+
+func (tu TicketUser) document() TicketUserDocument {
+	return TicketUserDocument{
+		ID:          tu.ID,
+		Login:       tu.Login,
+		DisplayName: tu.DisplayName,
+	}
+}
+
+// Convert ticket_info XML into JSON document
+func ticketDocumentFromXML(data []byte) ([]byte, error) {
+	var ti TicketInfo
+	if err := xml.Unmarshal(data, &ti); err != nil {
+		return nil, fmt.Errorf("cannot decode ticket info: %w", err)
+	}
+	return json.Marshal(ti.Document())
+}
+
+// Return what is sent to script on stdin and additional environment variables according to script input format.
+// Returned function removes any temporary file and must be called once script has run.
+func (s *Script) prepareInput(input []byte) ([]byte, []string, func(), error) {
+	nothing := func() {}
+	switch s.Options.Input {
+	case INPUT_JSON:
+		doc, err := ticketDocumentFromXML(input)
+		return doc, nil, nothing, err
+
+	case INPUT_BOTH:
+		doc, err := ticketDocumentFromXML(input)
+		if err != nil {
+			return nil, nil, nothing, err
+		}
+		f, err := os.CreateTemp("", "mediator-ticket-*.json")
+		if err != nil {
+			return nil, nil, nothing, err
+		}
+		cleanup := func() { os.Remove(f.Name()) }
+		if _, err := f.Write(doc); err != nil {
+			f.Close()
+			cleanup()
+			return nil, nil, nothing, err
+		} else if err := f.Close(); err != nil {
+			cleanup()
+			return nil, nil, nothing, err
+		}
+		return input, []string{fmt.Sprintf("%s=%s", ENV_TICKET_JSON, f.Name())}, cleanup, nil
+
+	default:
+		return input, nil, nothing, nil
+	}
+}
@@ -0,0 +1,93 @@
+package mediatorscript
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+const testTicketXML = `<ticket_info>
+	<id>42</id>
+	<subject>Open port 443</subject>
+	<priority><id>2</id><name>High</name></priority>
+	<createDate>1714557600</createDate>
+	<updateDate>1714561200</updateDate>
+	<requester><id>7</id><login>jdoe</login><display_name>John Doe</display_name></requester>
+	<current_stage>
+		<id>3</id><name>Approve</name>
+		<ticket_task><id>5</id><name>Approve access</name><handler><id>8</id><login>asmith</login><display_name>Alice Smith</display_name></handler></ticket_task>
+	</current_stage>
+	<open_request_stage><id>1</id><name>Open request</name></open_request_stage>
+	<comment>please</comment>
+</ticket_info>`
+
+const testTicketJSON = `{"schema_version":1,"id":42,"subject":"Open port 443","priority":{"id":2,"name":"High"},` +
+	`"create_date":1714557600,"update_date":1714561200,"requester":{"id":7,"login":"jdoe","display_name":"John Doe"},` +
+	`"current_stage":{"id":3,"name":"Approve","task":{"id":5,"name":"Approve access","handler":{"id":8,"login":"asmith","display_name":"Alice Smith"}}},` +
+	`"completion_stage":null,` +
+	`"open_request_stage":{"id":1,"name":"Open request","task":{"id":0,"name":"","handler":{"id":0,"login":"","display_name":""}}},` +
+	`"comment":"please"}`
+
+func TestTicketDocumentFromXML(t *testing.T) {
+	got, err := ticketDocumentFromXML([]byte(testTicketXML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != testTicketJSON {
+		t.Errorf("ticketDocumentFromXML() =\n%s\nwant\n%s", got, testTicketJSON)
+	}
+
+	// test mode input
+	if got, err := ticketDocumentFromXML([]byte("<ticket_info/>")); err != nil {
+		t.Fatal(err)
+	} else if !strings.HasPrefix(string(got), `{"schema_version":1,"id":0,`) {
+		t.Errorf("unexpected test document: %s", got)
+	}
+
+	if _, err := ticketDocumentFromXML([]byte("not xml")); err == nil {
+		t.Error("error expected on invalid XML")
+	}
+}
+
+func TestPrepareInput(t *testing.T) {
+	tests := []struct {
+		input     string
+		wantStdin string
+		wantFile  bool
+	}{
+		{INPUT_XML, testTicketXML, false},
+		{INPUT_JSON, testTicketJSON, false},
+		{INPUT_BOTH, testTicketXML, true},
+	}
+	for _, tt := range tests {
+		s := Script{Options: ScriptOptions{Input: tt.input}}
+		stdin, env, cleanup, err := s.prepareInput([]byte(testTicketXML))
+		if err != nil {
+			t.Fatalf("%q: %v", tt.input, err)
+		}
+		if string(stdin) != tt.wantStdin {
+			t.Errorf("%q: unexpected stdin %s", tt.input, stdin)
+		}
+		if !tt.wantFile {
+			if len(env) != 0 {
+				t.Errorf("%q: unexpected environment %v", tt.input, env)
+			}
+			cleanup()
+			continue
+		}
+
+		filename, found := strings.CutPrefix(env[0], ENV_TICKET_JSON+"=")
+		if len(env) != 1 || !found {
+			t.Fatalf("%q: unexpected environment %v", tt.input, env)
+		}
+		if content, err := os.ReadFile(filename); err != nil {
+			t.Fatal(err)
+		} else if string(content) != testTicketJSON {
+			t.Errorf("%q: unexpected file content %s", tt.input, content)
+		}
+		cleanup()
+		if _, err := os.Stat(filename); !os.IsNotExist(err) {
+			t.Errorf("%q: temporary file %s was not removed", tt.input, filename)
+		}
+	}
+}
@@ -32,3 +32,17 @@ type TicketUser struct {
 	Login       string `xml:"login"`
 	DisplayName string `xml:"display_name"`
 }
+
+// Name of the step the ticket is in.
+// Step name is in completion_data when a step is completed and in current_stage otherwise:
+// exactly one of them is expected.
+func (ti *TicketInfo) CurrentStep() (string, error) {
+	switch {
+	case ti.CompletionData != nil && ti.CurrentStage == nil:
+		return ti.CompletionData.Name, nil
+	case ti.CompletionData == nil && ti.CurrentStage != nil:
+		return ti.CurrentStage.Name, nil
+	default:
+		return "", ErrNoTicketStep
+	}
+}
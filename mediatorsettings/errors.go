@@ -16,4 +16,8 @@ var (
 	ErrMissingStepInRule        error = errors.New("no step in rule but rule trigger requires a step")
 	ErrUnknownScript            error = errors.New("missing or unknown script in rule")
 	ErrScriptIsNotTriggerScript error = errors.New("rule script is not a trigger script")
+	ErrScriptIsNotExecutable    error = errors.New("script file is not an executable file")
+	ErrWorkflowNotFound         error = errors.New("workflow was not found in settings")
+	ErrUnknownTrigger           error = errors.New("unknown or empty trigger")
+	ErrNoRuleSet                error = errors.New("workflow settings could not be downloaded from Securechange")
 )
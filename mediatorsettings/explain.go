@@ -0,0 +1,107 @@
+package mediatorsettings
+
+import (
+	"fmt"
+	"io"
+
+	"mediator/mediatorscript"
+	"mediator/scworkflow"
+)
+
+// Which rules of a workflow match a trigger event and why.
+// Built the same way scripts are found for a trigger event: nothing is run.
+type Explanation struct {
+	Workflow string `json:"workflow"`
+	Trigger  string `json:"trigger"`
+	TicketID int    `json:"ticket_id"`
+	Step     string `json:"step"`
+	// ticket info tag the step was read from: completion_data or current_stage
+	StepSource string             `json:"step_source"`
+	Rules      []*RuleExplanation `json:"rules"`
+	// scripts that would be run, in rule order
+	Scripts []string `json:"scripts"`
+}
+
+type RuleExplanation struct {
+	Rule   *Rule  `json:"rule"`
+	Match  bool   `json:"match"`
+	Reason string `json:"reason"`
+}
+
+// Explain which rules of workflow match trigger for ticket
+func (wm MediatorSettingsMap) Explain(workflow string, t scworkflow.SecurechangeTrigger, ti *mediatorscript.TicketInfo) (*Explanation, error) {
+	if t == scworkflow.NO_TRIGGER {
+		return nil, ErrUnknownTrigger
+	}
+	step, err := ti.CurrentStep()
+	if err != nil {
+		return nil, err
+	}
+	wf, err := wm.GetWorkflowSettings(workflow)
+	if err != nil {
+		return nil, err
+	}
+
+	e := Explanation{
+		Workflow:   workflow,
+		Trigger:    t.String(),
+		TicketID:   ti.ID,
+		Step:       step,
+		StepSource: "current_stage",
+		Rules:      []*RuleExplanation{},
+		Scripts:    wf.GetScriptsForTriggerAndStep(t, step),
+	}
+	if ti.CompletionData != nil {
+		e.StepSource = "completion_data"
+	}
+	for _, rule := range wf.Rules {
+		if rule != nil {
+			e.Rules = append(e.Rules, rule.explain(t, step))
+		}
+	}
+	return &e, nil
+}
+
+// Tell whether rule matches trigger and step. Same logic as WFSettings.GetScriptsForTriggerAndStep
+func (r *Rule) explain(t scworkflow.SecurechangeTrigger, step string) *RuleExplanation {
+	e := RuleExplanation{Rule: r}
+	switch {
+	case r.Script == "":
+		e.Reason = "rule has no script"
+	case r.Trigger != t.String():
+		e.Reason = fmt.Sprintf("rule trigger is '%s'", r.Trigger)
+	case r.Step == nil:
+		e.Match = true
+		e.Reason = "rule has no step: it matches every step"
+	case *r.Step != step:
+		e.Reason = fmt.Sprintf("rule step is '%s'", *r.Step)
+	default:
+		e.Match = true
+		e.Reason = "trigger and step match"
+	}
+	if e.Match && t.UseNextStep() {
+		e.Reason += fmt.Sprintf(". %s rules are stored with the step the ticket leaves", t)
+	}
+	return &e
+}
+
+// Print explanation in a human readable way
+func (e *Explanation) Print(w io.Writer) {
+	fmt.Fprintf(w, "Workflow: %s\n", e.Workflow)
+	fmt.Fprintf(w, "Trigger:  %s\n", e.Trigger)
+	fmt.Fprintf(w, "Ticket:   %d, step '%s' (from %s)\n", e.TicketID, e.Step, e.StepSource)
+	fmt.Fprintf(w, "\n%d rule(s):\n", len(e.Rules))
+	for _, r := range e.Rules {
+		status := "no match"
+		if r.Match {
+			status = "MATCH"
+		}
+		fmt.Fprintf(w, "  [%s] %s\n", status, r.Rule)
+		fmt.Fprintf(w, "      %s\n", r.Reason)
+	}
+	if len(e.Scripts) == 0 {
+		fmt.Fprintln(w, "\nNo script would be run.")
+	} else {
+		fmt.Fprintf(w, "\nScript(s) that would be run: %v\n", e.Scripts)
+	}
+}
@@ -0,0 +1,60 @@
+package mediatorsettings
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"mediator/mediatorscript"
+	"mediator/scworkflow"
+)
+
+func TestMediatorSettingsMap_Explain(t *testing.T) {
+	review, approve := "Review", "Approve"
+	settings := MediatorSettingsMap{"wf": {
+		WFname: "wf",
+		Rules: RulesSlice{
+			{Trigger: "Advance", Script: "review.sh", Step: &review},
+			{Trigger: "Advance", Script: "approve.sh", Step: &approve},
+			{Trigger: "Create", Script: "create.sh"},
+			{Trigger: "Advance", Script: "every-step.sh"},
+			{Trigger: "Advance", Script: "", Step: &review},
+		},
+	}}
+	ti := &mediatorscript.TicketInfo{ID: 42, CompletionData: &mediatorscript.TicketStage{Name: review}}
+
+	e, err := settings.Explain("wf", scworkflow.ADVANCE, ti)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Step != review || e.StepSource != "completion_data" || e.TicketID != 42 {
+		t.Errorf("unexpected ticket step: %+v", e)
+	}
+	wantMatch := []bool{true, false, false, true, false}
+	wantReason := []string{"trigger and step match", "rule step is 'Approve'", "rule trigger is 'Create'", "rule has no step", "rule has no script"}
+	if len(e.Rules) != len(wantMatch) {
+		t.Fatalf("expected %d rules, got %d", len(wantMatch), len(e.Rules))
+	}
+	for i, r := range e.Rules {
+		if r.Match != wantMatch[i] || !strings.HasPrefix(r.Reason, wantReason[i]) {
+			t.Errorf("rule %d: got %v %q, want %v %q", i, r.Match, r.Reason, wantMatch[i], wantReason[i])
+		}
+	}
+	// same scripts as the ones run by /execute-trigger
+	if want := settings["wf"].GetScriptsForTriggerAndStep(scworkflow.ADVANCE, review); strings.Join(e.Scripts, ",") != strings.Join(want, ",") {
+		t.Errorf("Explain() scripts = %v, want %v", e.Scripts, want)
+	}
+
+	var out bytes.Buffer
+	e.Print(&out)
+	if !strings.Contains(out.String(), "[MATCH] Trigger Advance on step Review fires script review.sh.") || !strings.Contains(out.String(), "[review.sh every-step.sh]") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+
+	if _, err := settings.Explain("other", scworkflow.ADVANCE, ti); err == nil {
+		t.Error("expected an error for unknown workflow")
+	}
+	if _, err := settings.Explain("wf", scworkflow.ADVANCE, &mediatorscript.TicketInfo{}); err == nil {
+		t.Error("expected an error for ticket without step")
+	}
+}
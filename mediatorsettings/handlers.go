@@ -3,13 +3,24 @@ package mediatorsettings
 import (
 	"fmt"
 	"net/http"
+	"strings"
 	"sync"
 
+	"mediator/audit"
+
 	"github.com/labstack/echo/v4"
 )
 
 var mutex sync.Mutex
 
+func AddMediatorsettingsAPI(g *echo.Group) {
+	g.GET("/settings", GetSettings)
+	g.POST("/settings", SetSettings)
+	g.POST("/settings/workflows", SetWorkflowSettings)
+	g.POST("/execute-trigger", ExecuteTrigger)
+	g.POST("/explain-trigger", ExplainTrigger)
+}
+
 func GetSettings(c echo.Context) error {
 	mutex.Lock()
 	defer mutex.Unlock()
@@ -17,6 +28,7 @@ func GetSettings(c echo.Context) error {
 	if err := DownloadSettingsFileFromSecurechange(download_to_securechange_script, settings_filename); err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err)
 	}
+	settings_version++
 
 	if settings, err := ReadWorkflowsSettings(settings_filename); err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err)
@@ -31,17 +43,24 @@ func GetSettings(c echo.Context) error {
 	}
 }
 
-func SetSettings(c echo.Context) error {
+func SetSettings(c echo.Context) (err error) {
 	mutex.Lock()
 	defer mutex.Unlock()
 
 	var (
 		data MediatorSettings
 	)
+	entry := audit.NewEntry(c, audit.ActionSettingsUpload)
+	defer func() { entry.Save(err) }()
 
 	if err := c.Bind(&data); err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err)
 	}
+	entry.Target = workflowNames(data)
+	entry.After = data
+	if previous, err := ReadWorkflowsSettings(settings_filename); err == nil {
+		entry.Before = previous.GetSlice()
+	}
 
 	// check settings
 	for _, settings := range data {
@@ -57,26 +76,29 @@ func SetSettings(c echo.Context) error {
 	if err := WriteWorkflowsSettings(data, settings_filename); err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err)
 	}
+	settings_version++
 	if err := UploadSettingsFileToSecurechange(upload_to_securechange_script, settings_filename); err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err)
 	}
 	return c.NoContent(http.StatusCreated)
 }
 
-func SetWorkflowSettings(c echo.Context) error {
+func SetWorkflowSettings(c echo.Context) (err error) {
 	mutex.Lock()
 	defer mutex.Unlock()
 
 	var (
 		settings    MediatorSettingsMap
 		wf_settings WFSettings
-		err         error
 	)
+	entry := audit.NewEntry(c, audit.ActionSettingsUpload)
+	defer func() { entry.Save(err) }()
 
 	// get current settings
 	if err := DownloadSettingsFileFromSecurechange(download_to_securechange_script, settings_filename); err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err)
 	}
+	settings_version++
 
 	if settings, err = ReadWorkflowsSettings(settings_filename); err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err)
@@ -92,10 +114,15 @@ func SetWorkflowSettings(c echo.Context) error {
 	}
 
 	// check settings
+	entry.Target = wf_settings.WFname
+	entry.After = wf_settings
 	if err := wf_settings.isValid(); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, err)
 	}
 
+	if previous, ok := settings[wf_settings.WFname]; ok {
+		entry.Before = *previous
+	}
 	settings[wf_settings.WFname] = &wf_settings
 
 	res := settings.GetSlice()
@@ -106,9 +133,21 @@ func SetWorkflowSettings(c echo.Context) error {
 	if err := WriteWorkflowsSettings(res, settings_filename); err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err)
 	}
+	settings_version++
 
 	if err := UploadSettingsFileToSecurechange(upload_to_securechange_script, settings_filename); err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err)
 	}
 	return c.NoContent(http.StatusCreated)
 }
+
+// Audit target of a settings upload: comma separated list of workflow names
+func workflowNames(data MediatorSettings) string {
+	names := []string{}
+	for _, s := range data {
+		if s != nil {
+			names = append(names, s.WFname)
+		}
+	}
+	return strings.Join(names, ",")
+}
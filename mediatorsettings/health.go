@@ -0,0 +1,43 @@
+package mediatorsettings
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Readiness check used by health package:
+// scripts used to upload and download settings must exist and be executable
+func CheckScripts() error {
+	errs := []error{}
+	if upload_to_securechange_script == "" {
+		errs = append(errs, ErrNoUploadScript)
+	} else if err := checkExecutable(upload_to_securechange_script); err != nil {
+		errs = append(errs, err)
+	}
+	if download_to_securechange_script == "" {
+		errs = append(errs, ErrNoDownloadScript)
+	} else if err := checkExecutable(download_to_securechange_script); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// scripts are run with sudo: we only make sure the file is executable by someone
+func checkExecutable(path string) error {
+	if info, err := os.Stat(path); err != nil {
+		return err
+	} else if !info.Mode().IsRegular() || info.Mode()&0111 == 0 {
+		return fmt.Errorf("%w: %s", ErrScriptIsNotExecutable, path)
+	}
+	return nil
+}
+
+// Readiness check used by health package:
+// workflow settings must have been downloaded from Securechange, otherwise no trigger can run
+func CheckSettings() error {
+	if _, err := os.Stat(settings_filename); err != nil {
+		return fmt.Errorf("%w: %w", ErrNoRuleSet, err)
+	}
+	return nil
+}
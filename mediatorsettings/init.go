@@ -1,6 +1,13 @@
 package mediatorsettings
 
-import "fmt"
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
 
 var (
 	settings_filename               string
@@ -10,8 +17,28 @@ var (
 
 const (
 	DEFAULT_SETTINGS_FILENAME = "/tmp/mediator-client-settings.json"
+	// local copy of settings is downloaded again from Securechange when it is older than this
+	SETTINGS_MAX_AGE = 10 * time.Minute
 )
 
+// Download of settings from Securechange. It runs without holding mutex so triggers are not blocked.
+var refresh struct {
+	sync.Mutex
+	// last time settings download was attempted
+	last time.Time
+	// closed when the download in flight ends. nil when no download is in flight
+	done chan struct{}
+	// error of last download
+	err error
+}
+
+// Incremented when settings file is written by back-end, under mutex.
+// A download that started before is then discarded: it may be older than what was written.
+var settings_version uint64
+
+// Download settings from Securechange. Replaced in tests.
+var downloadSettings = DownloadSettingsFileFromSecurechange
+
 func Init(settings_file, upl_script, dl_script string) []error {
 	errs := []error{}
 
@@ -32,5 +59,92 @@ func Init(settings_file, upl_script, dl_script string) []error {
 		errs = append(errs, fmt.Errorf("%w: will use %s", ErrNoSettingsFile, DEFAULT_SETTINGS_FILENAME))
 		settings_filename = DEFAULT_SETTINGS_FILENAME
 	}
+
+	// local copy may be missing or outdated, e.g. after a reboot when it is in /tmp
+	if download_to_securechange_script != "" {
+		if err := refreshSettings(true); err != nil {
+			errs = append(errs, err)
+		}
+	}
 	return errs
 }
+
+// Download settings from Securechange if local copy is missing, older than SETTINGS_MAX_AGE or if force is true.
+// An outdated local copy is refreshed in the background and used meanwhile.
+// Otherwise, wait for the download, which is shared with concurrent callers.
+// If download fails, the local copy is still used when it exists.
+// Caller must not hold mutex.
+func refreshSettings(force bool) error {
+	refresh.Lock()
+	info, serr := os.Stat(settings_filename)
+	if serr == nil && !force {
+		last := info.ModTime()
+		if refresh.last.After(last) {
+			// do not try again on every trigger while Securechange is unavailable
+			last = refresh.last
+		}
+		if time.Since(last) < SETTINGS_MAX_AGE {
+			refresh.Unlock()
+			return nil
+		}
+	}
+	done := refresh.done
+	if done == nil {
+		done = make(chan struct{})
+		refresh.done, refresh.last = done, time.Now()
+		go download(done)
+	}
+	refresh.Unlock()
+
+	if serr == nil && !force {
+		return nil
+	}
+	<-done
+	refresh.Lock()
+	err := refresh.err
+	refresh.Unlock()
+
+	if _, serr := os.Stat(settings_filename); serr == nil {
+		return nil
+	} else if err == nil {
+		err = serr
+	}
+	return fmt.Errorf("%w: %w", ErrNoRuleSet, err)
+}
+
+// Download settings in a temporary file and replace local copy with it under mutex,
+// so readers never see a partial file. done is closed when download ends.
+func download(done chan struct{}) {
+	mutex.Lock()
+	version := settings_version
+	mutex.Unlock()
+
+	tmp := settings_filename + ".download"
+	err := downloadSettings(download_to_securechange_script, tmp)
+	if err == nil {
+		mutex.Lock()
+		if version == settings_version {
+			err = os.Rename(tmp, settings_filename)
+		}
+		mutex.Unlock()
+	}
+	os.Remove(tmp)
+	if _, serr := os.Stat(settings_filename); err != nil && serr == nil {
+		logrus.Warningf("cannot refresh mediator-client settings, use local copy %s: %v", settings_filename, err)
+	}
+
+	refresh.Lock()
+	refresh.err, refresh.done = err, nil
+	refresh.Unlock()
+	close(done)
+}
+
+// Wait for the download in flight, if any
+func waitRefresh() {
+	refresh.Lock()
+	done := refresh.done
+	refresh.Unlock()
+	if done != nil {
+		<-done
+	}
+}
@@ -8,6 +8,9 @@ import (
 	"os"
 	"os/exec"
 
+	"mediator/metrics"
+	"mediator/notify"
+
 	"github.com/sirupsen/logrus"
 )
 
@@ -52,13 +55,28 @@ func WriteWorkflowsSettingsFromMap(wf_settings MediatorSettingsMap, filename str
 	return nil
 }
 
-func UploadSettingsFileToSecurechange(upload_script, filename string) error {
+func UploadSettingsFileToSecurechange(upload_script, filename string) (err error) {
+	// get stderr in a buffer
+	var stderr bytes.Buffer
+	defer func() {
+		metrics.SettingsOperations.Inc("upload", metrics.Outcome(err))
+		if err != nil {
+			e := notify.Event{
+				Type:   notify.EventSettingsUploadFailure,
+				Script: upload_script,
+				Stderr: stderr.String(),
+				Error:  err.Error(),
+			}
+			if exitError, ok := err.(*exec.ExitError); ok {
+				e.ExitCode = exitError.ExitCode()
+			}
+			notify.Notify(e)
+		}
+	}()
 	if upload_script == "" {
 		return ErrNoUploadScript
 	}
 	cmd := exec.Command("/usr/bin/sudo", upload_script, filename)
-	// get stderr in a buffer
-	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 
 	logrus.Infof("Upload mediator-client settings to Securechange using command: %s %s", upload_script, filename)
@@ -77,7 +95,8 @@ func UploadSettingsFileToSecurechange(upload_script, filename string) error {
 	return nil
 }
 
-func DownloadSettingsFileFromSecurechange(download_script, filename string) error {
+func DownloadSettingsFileFromSecurechange(download_script, filename string) (err error) {
+	defer func() { metrics.SettingsOperations.Inc("download", metrics.Outcome(err)) }()
 	if download_script == "" {
 		return ErrNoDownloadScript
 	}
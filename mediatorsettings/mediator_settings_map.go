@@ -55,7 +55,7 @@ func (wm MediatorSettingsMap) GetWorkflowSettings(name string) (*WFSettings, err
 	if w, ok := wm[name]; ok {
 		return w, nil
 	} else {
-		return nil, fmt.Errorf("workflow '%s' was not found in settings", name)
+		return nil, fmt.Errorf("%w: '%s'", ErrWorkflowNotFound, name)
 	}
 }
 
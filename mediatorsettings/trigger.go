@@ -0,0 +1,88 @@
+package mediatorsettings
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"mediator/mediatorscript"
+	"mediator/scworkflow"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Run the scripts attached to a trigger event.
+// Request gives workflow, trigger and ticket: back-end finds ticket step and matching rules
+// in workflow settings, the same way mediator-client used to do.
+// Settings are the ones last uploaded to (or downloaded from) Securechange by back-end.
+func ExecuteTrigger(c echo.Context) error {
+	br, ti, raw, e, status, err := explainRequest(c)
+	if err != nil {
+		return c.JSON(status, mediatorscript.RunResponse{Error: err.Error()})
+	} else if len(e.Scripts) == 0 {
+		mediatorscript.RequestLogger(c, strconv.Itoa(ti.ID)).Infof("no script for workflow '%s', trigger '%s' and step '%s'. Do nothing.", br.Workflow, br.Trigger, e.Step)
+		return c.JSON(http.StatusOK, mediatorscript.BatchResponse{Step: e.Step, Results: []*mediatorscript.BatchResult{}})
+	}
+	br.Scripts, br.Step = e.Scripts, e.Step
+	return mediatorscript.RunBatch(c, br, ti, raw)
+}
+
+// Tell which rules match a trigger event and why, without running any script.
+// Request is the same as /execute-trigger one.
+func ExplainTrigger(c echo.Context) error {
+	if _, _, _, e, status, err := explainRequest(c); err != nil {
+		return c.JSON(status, mediatorscript.RunResponse{Error: err.Error()})
+	} else {
+		return c.JSON(http.StatusOK, e)
+	}
+}
+
+// Read trigger request and explain which rules match it.
+// Returns the HTTP status code of the error, if any.
+func explainRequest(c echo.Context) (*mediatorscript.BatchRequest, *mediatorscript.TicketInfo, []byte, *Explanation, int, error) {
+	var br mediatorscript.BatchRequest
+	if err := c.Bind(&br); err != nil {
+		return nil, nil, nil, nil, http.StatusBadRequest, fmt.Errorf("error while processing parameters: %v", err)
+	}
+	ti, raw, err := br.TicketInfo()
+	if err != nil {
+		return nil, nil, nil, nil, http.StatusBadRequest, err
+	}
+
+	e, err := explain(br.Workflow, br.Trigger, ti)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, ErrWorkflowNotFound) {
+			status = http.StatusNotFound
+		} else if errors.Is(err, ErrCannotReadSettingsFile) || errors.Is(err, ErrCannotDecodeSettingsFile) {
+			status = http.StatusInternalServerError
+		} else if errors.Is(err, ErrNoRuleSet) {
+			// mediator-client spools the request and sends it again later
+			status = http.StatusServiceUnavailable
+		}
+		mediatorscript.RequestLogger(c, strconv.Itoa(ti.ID)).Error(err)
+		return nil, nil, nil, nil, status, err
+	}
+	return &br, ti, raw, e, http.StatusOK, nil
+}
+
+// Explain which rules of workflow settings match trigger for ticket.
+// Settings are downloaded from Securechange first if local copy is missing or outdated.
+func explain(workflow, trigger string, ti *mediatorscript.TicketInfo) (*Explanation, error) {
+	t := scworkflow.GetTriggerFromString(trigger)
+	if t == scworkflow.NO_TRIGGER {
+		return nil, fmt.Errorf("%w: '%s'", ErrUnknownTrigger, trigger)
+	}
+
+	if err := refreshSettings(false); err != nil {
+		return nil, err
+	}
+	mutex.Lock()
+	settings, err := ReadWorkflowsSettings(settings_filename)
+	mutex.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return settings.Explain(workflow, t, ti)
+}
@@ -0,0 +1,279 @@
+package mediatorsettings
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"mediator/mediatorscript"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestExecuteTrigger(t *testing.T) {
+	step := "Review"
+	previous := settings_filename
+	settings_filename = filepath.Join(t.TempDir(), "settings.json")
+	t.Cleanup(func() { settings_filename = previous })
+	if err := WriteWorkflowsSettingsFromMap(MediatorSettingsMap{"wf": {
+		WFname: "wf",
+		WFid:   1,
+		Rules: RulesSlice{
+			{Trigger: "Create", Script: "create.sh"},
+			{Trigger: "Advance", Script: "advance.sh", Step: &step},
+		},
+	}}, settings_filename); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantStep   string
+		wantNames  []string
+	}{
+		{
+			name:       "no script for step",
+			body:       `{"workflow": "wf", "trigger": "Advance", "ticket_xml": "<ticket_info><id>1</id><current_stage><name>Approve</name></current_stage></ticket_info>"}`,
+			wantStatus: http.StatusOK,
+			wantStep:   "Approve",
+			wantNames:  []string{},
+		},
+		{
+			name:       "unknown workflow",
+			body:       `{"workflow": "other", "trigger": "Create", "ticket_xml": "<ticket_info><current_stage><name>Review</name></current_stage></ticket_info>"}`,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "unknown trigger",
+			body:       `{"workflow": "wf", "trigger": "Nope", "ticket_xml": "<ticket_info><current_stage><name>Review</name></current_stage></ticket_info>"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "no step",
+			body:       `{"workflow": "wf", "trigger": "Create", "ticket_xml": "<ticket_info><id>1</id></ticket_info>"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "no ticket",
+			body:       `{"workflow": "wf", "trigger": "Create"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/execute-trigger", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			if err := ExecuteTrigger(echo.New().NewContext(req, rec)); err != nil {
+				t.Fatal(err)
+			}
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("ExecuteTrigger() status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var res mediatorscript.BatchResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+				t.Fatal(err)
+			}
+			names := []string{}
+			for _, r := range res.Results {
+				names = append(names, r.Script)
+			}
+			if res.Step != tt.wantStep || !reflect.DeepEqual(names, tt.wantNames) {
+				t.Errorf("ExecuteTrigger() = step %q, scripts %v, want step %q, scripts %v", res.Step, names, tt.wantStep, tt.wantNames)
+			}
+		})
+	}
+}
+
+func TestExplainTrigger(t *testing.T) {
+	step := "Review"
+	previous := settings_filename
+	settings_filename = filepath.Join(t.TempDir(), "settings.json")
+	t.Cleanup(func() { settings_filename = previous })
+	if err := WriteWorkflowsSettingsFromMap(MediatorSettingsMap{"wf": {
+		WFname: "wf",
+		WFid:   1,
+		Rules:  RulesSlice{{Trigger: "Advance", Script: "advance.sh", Step: &step}},
+	}}, settings_filename); err != nil {
+		t.Fatal(err)
+	}
+
+	body := `{"workflow": "wf", "trigger": "Advance", "ticket_xml": "<ticket_info><id>1</id><completion_data><stage><name>Review</name></stage></completion_data></ticket_info>"}`
+	req := httptest.NewRequest(http.MethodPost, "/explain-trigger", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	if err := ExplainTrigger(echo.New().NewContext(req, rec)); err != nil {
+		t.Fatal(err)
+	}
+	var e Explanation
+	if err := json.Unmarshal(rec.Body.Bytes(), &e); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("ExplainTrigger() = %d %s, %v", rec.Code, rec.Body.String(), err)
+	}
+	if e.Step != step || len(e.Rules) != 1 || !e.Rules[0].Match || !reflect.DeepEqual(e.Scripts, []string{"advance.sh"}) {
+		t.Errorf("unexpected explanation: %+v", e)
+	}
+}
+
+func TestExplain(t *testing.T) {
+	step := "Review"
+	previous := settings_filename
+	settings_filename = filepath.Join(t.TempDir(), "settings.json")
+	t.Cleanup(func() { settings_filename = previous })
+	if err := os.WriteFile(settings_filename, []byte(`{"wf": {"wf_name": "wf", "wf_id": 1, "settings": [
+		{"trigger": "Advance", "script": "advance.sh", "step": "Review"},
+		{"trigger": "Advance", "script": "other.sh", "step": "Approve"},
+		{"trigger": "Create", "script": "create.sh"}
+	]}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// step is in completion_data when step is completed
+	ti := &mediatorscript.TicketInfo{CompletionData: &mediatorscript.TicketStage{Name: step}}
+	if e, err := explain("wf", "advance", ti); err != nil || e.Step != step || e.StepSource != "completion_data" || !reflect.DeepEqual(e.Scripts, []string{"advance.sh"}) {
+		t.Errorf("explain() = %+v, %v", e, err)
+	}
+	ti = &mediatorscript.TicketInfo{CurrentStage: &mediatorscript.TicketStage{Name: step}}
+	if e, err := explain("wf", "Create", ti); err != nil || e.StepSource != "current_stage" || !reflect.DeepEqual(e.Scripts, []string{"create.sh"}) {
+		t.Errorf("explain() = %+v, %v", e, err)
+	}
+}
+
+// Settings are downloaded when local copy is missing, e.g. after a reboot, or outdated
+func TestExplainDownloadsSettings(t *testing.T) {
+	previous, previous_download := settings_filename, downloadSettings
+	t.Cleanup(func() {
+		settings_filename, downloadSettings = previous, previous_download
+		refresh.last = time.Time{}
+	})
+	content := []byte(`{"wf": {"wf_name": "wf", "wf_id": 1, "settings": [{"trigger": "Create", "script": "create.sh"}]}}`)
+	ti := &mediatorscript.TicketInfo{ID: 1, CurrentStage: &mediatorscript.TicketStage{Name: "Review"}}
+
+	tests := []struct {
+		name      string
+		existing  bool
+		age       time.Duration
+		fail      bool
+		wantCalls int
+		wantErr   error
+	}{
+		{name: "missing file", wantCalls: 1},
+		{name: "missing file and download failure", fail: true, wantCalls: 1, wantErr: ErrNoRuleSet},
+		{name: "fresh file", existing: true, wantCalls: 0},
+		{name: "outdated file", existing: true, age: 2 * SETTINGS_MAX_AGE, wantCalls: 1},
+		{name: "outdated file and download failure", existing: true, age: 2 * SETTINGS_MAX_AGE, fail: true, wantCalls: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings_filename = filepath.Join(t.TempDir(), "settings.json")
+			refresh.last = time.Time{}
+			if tt.existing {
+				if err := os.WriteFile(settings_filename, content, 0600); err != nil {
+					t.Fatal(err)
+				}
+				mtime := time.Now().Add(-tt.age)
+				if err := os.Chtimes(settings_filename, mtime, mtime); err != nil {
+					t.Fatal(err)
+				}
+			}
+			calls := 0
+			downloadSettings = func(script, filename string) error {
+				calls++
+				if tt.fail {
+					return errors.New("Securechange is unavailable")
+				}
+				return os.WriteFile(filename, content, 0600)
+			}
+
+			if err := CheckSettings(); (err == nil) != tt.existing {
+				t.Errorf("CheckSettings() before trigger = %v", err)
+			}
+			e, err := explain("wf", "Create", ti)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("explain() error = %v, want %v", err, tt.wantErr)
+			} else if err == nil && !reflect.DeepEqual(e.Scripts, []string{"create.sh"}) {
+				t.Errorf("explain() scripts = %v", e.Scripts)
+			}
+			// outdated copy is refreshed in the background
+			waitRefresh()
+			if calls != tt.wantCalls {
+				t.Errorf("%d downloads, want %d", calls, tt.wantCalls)
+			}
+			if err := CheckSettings(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("CheckSettings() = %v, want %v", err, tt.wantErr)
+			}
+
+			// failed download is not attempted again on every trigger if local copy exists
+			explain("wf", "Create", ti)
+			waitRefresh()
+			if tt.existing && calls != tt.wantCalls {
+				t.Errorf("%d downloads after second trigger, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+
+	// unavailable rule set makes mediator-client spool the request
+	settings_filename = filepath.Join(t.TempDir(), "settings.json")
+	downloadSettings = func(script, filename string) error { return errors.New("Securechange is unavailable") }
+	req := httptest.NewRequest(http.MethodPost, "/execute-trigger", strings.NewReader(`{"workflow": "wf", "trigger": "Create", "ticket_xml": "<ticket_info><id>1</id><current_stage><name>Review</name></current_stage></ticket_info>"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	if err := ExecuteTrigger(echo.New().NewContext(req, rec)); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("ExecuteTrigger() status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+}
+
+// Triggers are served from the outdated local copy while it is refreshed
+func TestExplainDoesNotWaitForRefresh(t *testing.T) {
+	previous, previous_download := settings_filename, downloadSettings
+	t.Cleanup(func() {
+		settings_filename, downloadSettings = previous, previous_download
+		refresh.last = time.Time{}
+	})
+	content := []byte(`{"wf": {"wf_name": "wf", "wf_id": 1, "settings": [{"trigger": "Create", "script": "create.sh"}]}}`)
+	ti := &mediatorscript.TicketInfo{ID: 1, CurrentStage: &mediatorscript.TicketStage{Name: "Review"}}
+
+	settings_filename = filepath.Join(t.TempDir(), "settings.json")
+	refresh.last = time.Time{}
+	if err := os.WriteFile(settings_filename, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Now().Add(-2 * SETTINGS_MAX_AGE)
+	if err := os.Chtimes(settings_filename, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	release := make(chan struct{})
+	downloadSettings = func(script, filename string) error {
+		<-release
+		return os.WriteFile(filename, []byte(`{"wf": {"wf_name": "wf", "wf_id": 1, "settings": [{"trigger": "Create", "script": "new.sh"}]}}`), 0600)
+	}
+
+	for range 3 {
+		if e, err := explain("wf", "Create", ti); err != nil {
+			t.Fatal(err)
+		} else if !reflect.DeepEqual(e.Scripts, []string{"create.sh"}) {
+			t.Errorf("explain() scripts = %v, want local copy", e.Scripts)
+		}
+	}
+	close(release)
+	waitRefresh()
+	if e, err := explain("wf", "Create", ti); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(e.Scripts, []string{"new.sh"}) {
+		t.Errorf("explain() scripts = %v, want downloaded settings", e.Scripts)
+	}
+}
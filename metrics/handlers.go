@@ -0,0 +1,38 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Handler sends all metrics of Registry in Prometheus exposition format
+var Handler = echo.WrapHandler(promhttp.HandlerFor(Registry, promhttp.HandlerOpts{}))
+
+// Middleware counts HTTP requests and measures their latency.
+// Requests are identified by their route (i.e. /v1/otp/execute/:script) rather than their path
+// so the number of series remains under control.
+//
+// Usage `Echo#Use(metrics.Middleware)`
+func Middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		start := time.Now()
+		err := next(c)
+		if err != nil {
+			// let echo build the response now so we know the actual status code
+			c.Error(err)
+		}
+
+		route := c.Path()
+		if route == "" {
+			route = "unmatched"
+		}
+		method := c.Request().Method
+		HTTPRequests.Inc(method, route, strconv.Itoa(c.Response().Status))
+		HTTPRequestDuration.Observe(time.Since(start).Seconds(), method, route)
+
+		return nil
+	}
+}
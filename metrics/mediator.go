@@ -0,0 +1,49 @@
+package metrics
+
+// Metrics exposed by mediator-server
+var (
+	HTTPRequests = NewCounter("mediator_http_requests_total",
+		"Number of HTTP requests by method, route and status code.",
+		"method", "route", "status")
+	HTTPRequestDuration = NewHistogram("mediator_http_request_duration_seconds",
+		"Latency of HTTP requests by method and route.",
+		DefBuckets, "method", "route")
+
+	ScriptExecutions = NewCounter("mediator_script_executions_total",
+		"Number of script executions by script name, script type and outcome (success, failure, timeout or error).",
+		"script", "type", "outcome")
+	ScriptExecutionDuration = NewHistogram("mediator_script_execution_duration_seconds",
+		"Duration of script executions by script name and script type.",
+		DefBuckets, "script", "type")
+	ScriptsRunning = NewGauge("mediator_scripts_running",
+		"Number of scripts currently running.")
+	AsyncQueueDepth = NewGauge("mediator_async_queue_depth",
+		"Number of asynchronous script executions accepted and not completed yet.")
+	HashMismatches = NewCounter("mediator_script_hash_mismatches_total",
+		"Number of executions refused because script file does not match its registered checksum.",
+		"script")
+
+	AuthFailures = NewCounter("mediator_auth_failures_total",
+		"Number of rejected requests by reason (invalid_key, locked_out, invalid_signature).",
+		"reason")
+
+	SettingsOperations = NewCounter("mediator_settings_operations_total",
+		"Number of mediator-client settings transfers to and from Securechange by operation (upload or download) and outcome (success or failure).",
+		"operation", "outcome")
+)
+
+// Outcome values used as label
+const (
+	OUTCOME_SUCCESS = "success"
+	OUTCOME_FAILURE = "failure"
+	OUTCOME_ERROR   = "error"
+	OUTCOME_TIMEOUT = "timeout"
+)
+
+// Return success or failure label value according to error
+func Outcome(err error) string {
+	if err != nil {
+		return OUTCOME_FAILURE
+	}
+	return OUTCOME_SUCCESS
+}
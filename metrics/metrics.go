@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// Thin wrappers around Prometheus client so callers only deal with label values.
+// Label values are given in the same order as label names when the metric was created.
+
+// Registry of all metrics exposed on /metrics: mediator metrics, Go runtime and process metrics
+var Registry = prometheus.NewRegistry()
+
+// Default buckets of histograms, in seconds
+var DefBuckets = prometheus.DefBuckets
+
+func init() {
+	Registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+}
+
+type Counter struct {
+	vec *prometheus.CounterVec
+}
+
+func NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{vec: prometheus.NewCounterVec(prometheus.CounterOpts{Name: name, Help: help}, labels)}
+	Registry.MustRegister(c.vec)
+	return c
+}
+
+func (c *Counter) Inc(values ...string) {
+	c.vec.WithLabelValues(values...).Inc()
+}
+
+// Add v to the counter. v must not be negative
+func (c *Counter) Add(v float64, values ...string) {
+	c.vec.WithLabelValues(values...).Add(v)
+}
+
+type Gauge struct {
+	vec *prometheus.GaugeVec
+}
+
+// Gauges without labels are exposed with value 0 before being set
+func NewGauge(name, help string, labels ...string) *Gauge {
+	g := &Gauge{vec: prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: name, Help: help}, labels)}
+	if len(labels) == 0 {
+		g.vec.WithLabelValues()
+	}
+	Registry.MustRegister(g.vec)
+	return g
+}
+
+func (g *Gauge) Inc(values ...string) {
+	g.vec.WithLabelValues(values...).Inc()
+}
+
+func (g *Gauge) Dec(values ...string) {
+	g.vec.WithLabelValues(values...).Dec()
+}
+
+func (g *Gauge) Add(v float64, values ...string) {
+	g.vec.WithLabelValues(values...).Add(v)
+}
+
+func (g *Gauge) Set(v float64, values ...string) {
+	g.vec.WithLabelValues(values...).Set(v)
+}
+
+type Histogram struct {
+	vec *prometheus.HistogramVec
+}
+
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	h := &Histogram{vec: prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: name, Help: help, Buckets: buckets}, labels)}
+	Registry.MustRegister(h.vec)
+	return h
+}
+
+func (h *Histogram) Observe(v float64, values ...string) {
+	h.vec.WithLabelValues(values...).Observe(v)
+}
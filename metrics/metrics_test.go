@@ -0,0 +1,102 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCounter(t *testing.T) {
+	c := &Counter{vec: prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_total", Help: "Test counter."}, []string{"name", "outcome"})}
+	c.Inc("b", "success")
+	c.Inc("a", "failure")
+	c.Add(2, "b", "success")
+	c.Inc(`quote"d`, "success")
+
+	want := `# HELP test_total Test counter.
+# TYPE test_total counter
+test_total{name="a",outcome="failure"} 1
+test_total{name="b",outcome="success"} 3
+test_total{name="quote\"d",outcome="success"} 1
+`
+	if err := testutil.CollectAndCompare(c.vec, strings.NewReader(want)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestGauge(t *testing.T) {
+	g := &Gauge{vec: prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_running", Help: "Test gauge."}, nil)}
+	g.vec.WithLabelValues()
+
+	if got := testutil.ToFloat64(g.vec); got != 0 {
+		t.Errorf("gauge without labels must be exposed before being set, got %v", got)
+	}
+
+	g.Inc()
+	g.Inc()
+	g.Dec()
+	if got := testutil.ToFloat64(g.vec); got != 1 {
+		t.Errorf("got %v, want 1", got)
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	h := &Histogram{vec: prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "test_seconds", Help: "Test histogram.", Buckets: []float64{0.1, 1}}, []string{"name"})}
+	h.Observe(0.05, "a")
+	h.Observe(0.5, "a")
+	h.Observe(5, "a")
+
+	want := `# HELP test_seconds Test histogram.
+# TYPE test_seconds histogram
+test_seconds_bucket{name="a",le="0.1"} 1
+test_seconds_bucket{name="a",le="1"} 2
+test_seconds_bucket{name="a",le="+Inf"} 3
+test_seconds_sum{name="a"} 5.55
+test_seconds_count{name="a"} 3
+`
+	if err := testutil.CollectAndCompare(h.vec, strings.NewReader(want)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	e := echo.New()
+	e.Use(Middleware)
+	e.GET("/metrics", Handler)
+	e.GET("/v1/items/:id", func(c echo.Context) error {
+		if c.Param("id") == "0" {
+			return echo.NewHTTPError(http.StatusNotFound, errors.New("no such item"))
+		}
+		return c.NoContent(http.StatusNoContent)
+	})
+
+	for _, path := range []string{"/v1/items/1", "/v1/items/2", "/v1/items/0", "/unknown/path"} {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("/metrics returned %d", rec.Code)
+	}
+	body := rec.Body.String()
+	for _, line := range []string{
+		`mediator_http_requests_total{method="GET",route="/v1/items/:id",status="204"} 2`,
+		`mediator_http_requests_total{method="GET",route="/v1/items/:id",status="404"} 1`,
+		`mediator_http_request_duration_seconds_count{method="GET",route="/v1/items/:id"} 3`,
+		`# TYPE mediator_scripts_running gauge`,
+	} {
+		if !strings.Contains(body, line) {
+			t.Errorf("missing line in /metrics output: %s", line)
+		}
+	}
+	if strings.Contains(body, "/unknown/path") {
+		t.Errorf("unmatched paths must not be used as route label")
+	}
+}
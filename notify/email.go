@@ -0,0 +1,89 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const DEFAULT_SMTP_PORT = 25
+
+type EmailConfiguration struct {
+	// SMTP server. Email notifications are disabled if empty
+	Host string `json:"host" mapstructure:"host"`
+	Port uint   `json:"port" mapstructure:"port"`
+	// credentials used for PLAIN authentication, if any.
+	// Go SMTP client refuses to send them unless connection is encrypted (STARTTLS) or server is localhost
+	Username string   `json:"username" mapstructure:"username"`
+	Password string   `json:"password" mapstructure:"password"`
+	From     string   `json:"from" mapstructure:"from"`
+	To       []string `json:"to" mapstructure:"to"`
+	// events sent by email. All events if empty
+	Events []string `json:"events" mapstructure:"events"`
+}
+
+func (c EmailConfiguration) IsEnabled() bool {
+	return c.Host != ""
+}
+
+type email struct {
+	conf EmailConfiguration
+	addr string
+	auth smtp.Auth
+}
+
+// overridden in tests
+var sendMail = smtp.SendMail
+
+func newEmail(conf EmailConfiguration) (*email, error) {
+	if conf.From == "" || len(conf.To) == 0 {
+		return nil, ErrNoEmailAddress
+	}
+	if err := checkEvents(conf.Events); err != nil {
+		return nil, err
+	}
+	if conf.Port == 0 {
+		conf.Port = DEFAULT_SMTP_PORT
+	}
+	n := &email{
+		conf: conf,
+		addr: net.JoinHostPort(conf.Host, strconv.Itoa(int(conf.Port))),
+	}
+	if conf.Username != "" {
+		n.auth = smtp.PlainAuth("", conf.Username, conf.Password, conf.Host)
+	}
+	return n, nil
+}
+
+func (n *email) String() string {
+	return fmt.Sprintf("email %s", n.addr)
+}
+
+func (n *email) accept(event string) bool {
+	return acceptEvent(n.conf.Events, event)
+}
+
+func (n *email) send(e *Event) error {
+	return sendMail(n.addr, n.auth, n.conf.From, n.conf.To, n.message(e))
+}
+
+func (n *email) message(e *Event) []byte {
+	// event fields come from scripts and tickets: make sure they cannot add headers
+	subject := strings.NewReplacer("\r", " ", "\n", " ").Replace("[mediator] " + e.Title())
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", n.conf.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(n.conf.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", e.Time.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(strings.ReplaceAll(e.Text(), "\n", "\r\n"))
+	return buf.Bytes()
+}
@@ -0,0 +1,11 @@
+package notify
+
+import "errors"
+
+var (
+	ErrUnknownEvent         = errors.New("unknown notification event")
+	ErrInvalidWebhookURL    = errors.New("invalid webhook URL")
+	ErrUnknownWebhookFormat = errors.New("unknown webhook format")
+	ErrWebhookStatus        = errors.New("webhook returned an error")
+	ErrNoEmailAddress       = errors.New("email notifications need a sender and at least one recipient")
+)
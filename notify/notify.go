@@ -0,0 +1,211 @@
+package notify
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Events notifications can be sent for
+const (
+	EventScriptFailure         = "script_failure"
+	EventScriptTimeout         = "script_timeout"
+	EventIntegrityViolation    = "integrity_violation"
+	EventSettingsUploadFailure = "settings_upload_failure"
+)
+
+var knownEvents = []string{
+	EventScriptFailure,
+	EventScriptTimeout,
+	EventIntegrityViolation,
+	EventSettingsUploadFailure,
+}
+
+const DEFAULT_STDERR_EXCERPT = 1000
+
+// Something that went wrong and should be reported
+type Event struct {
+	Type       string    `json:"event"`
+	Time       time.Time `json:"time"`
+	Host       string    `json:"host"`
+	TicketID   string    `json:"ticket_id,omitempty"`
+	RequestID  string    `json:"request_id,omitempty"`
+	Script     string    `json:"script,omitempty"`
+	ScriptType string    `json:"script_type,omitempty"`
+	ExitCode   int       `json:"exit_code"`
+	// last characters of script stderr
+	Stderr string `json:"stderr,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// One line summary of the event
+func (e *Event) Title() string {
+	var sb strings.Builder
+	switch e.Type {
+	case EventScriptFailure:
+		fmt.Fprintf(&sb, "%s '%s' failed with exit code %d", e.ScriptType, e.Script, e.ExitCode)
+	case EventScriptTimeout:
+		fmt.Fprintf(&sb, "%s '%s' timed out", e.ScriptType, e.Script)
+	case EventIntegrityViolation:
+		fmt.Fprintf(&sb, "%s '%s' was modified since it was registered", e.ScriptType, e.Script)
+	case EventSettingsUploadFailure:
+		sb.WriteString("Upload of mediator-client settings to Securechange failed")
+	default:
+		sb.WriteString(e.Type)
+	}
+	if e.TicketID != "" {
+		fmt.Fprintf(&sb, " (ticket %s)", e.TicketID)
+	}
+	return sb.String()
+}
+
+// Event details as name/value pairs, in display order. Empty values are skipped.
+func (e *Event) Facts() [][2]string {
+	facts := [][2]string{}
+	for _, f := range [][2]string{
+		{"Event", e.Type},
+		{"Time", e.Time.Format(time.RFC3339)},
+		{"Host", e.Host},
+		{"Ticket ID", e.TicketID},
+		{"Request ID", e.RequestID},
+		{"Script", e.Script},
+		{"Script type", e.ScriptType},
+		{"Exit code", fmt.Sprint(e.ExitCode)},
+		{"Error", e.Error},
+	} {
+		if f[1] != "" {
+			facts = append(facts, f)
+		}
+	}
+	return facts
+}
+
+// Plain text description of the event
+func (e *Event) Text() string {
+	var sb strings.Builder
+	for _, f := range e.Facts() {
+		fmt.Fprintf(&sb, "%s: %s\n", f[0], f[1])
+	}
+	if e.Stderr != "" {
+		fmt.Fprintf(&sb, "\nStderr:\n%s\n", e.Stderr)
+	}
+	return sb.String()
+}
+
+// Something able to deliver a notification
+type Notifier interface {
+	// accept returns true if notifier is interested in given event type
+	accept(event string) bool
+	send(e *Event) error
+	String() string
+}
+
+// Notification settings
+type Configuration struct {
+	// max number of stderr characters sent in notifications
+	StderrExcerpt int                    `json:"stderrexcerpt" mapstructure:"stderrexcerpt"`
+	Webhooks      []WebhookConfiguration `json:"webhooks" mapstructure:"webhooks"`
+	Email         EmailConfiguration     `json:"email" mapstructure:"email"`
+}
+
+var (
+	mutex         sync.RWMutex
+	notifiers     []Notifier
+	stderrExcerpt = DEFAULT_STDERR_EXCERPT
+	hostname, _   = os.Hostname()
+	pending       sync.WaitGroup
+)
+
+// Configure notifiers. Notifications are disabled if no webhook or email is configured.
+func Init(conf Configuration) error {
+	list := []Notifier{}
+	for _, w := range conf.Webhooks {
+		if n, err := newWebhook(w); err != nil {
+			return err
+		} else {
+			list = append(list, n)
+		}
+	}
+	if conf.Email.IsEnabled() {
+		if n, err := newEmail(conf.Email); err != nil {
+			return err
+		} else {
+			list = append(list, n)
+		}
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	notifiers = list
+	if conf.StderrExcerpt > 0 {
+		stderrExcerpt = conf.StderrExcerpt
+	} else {
+		stderrExcerpt = DEFAULT_STDERR_EXCERPT
+	}
+	return nil
+}
+
+// Send notification to every interested notifier.
+// Notifications are sent in background: errors are logged.
+func Notify(e Event) {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	if len(notifiers) == 0 {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	if e.Host == "" {
+		e.Host = hostname
+	}
+	e.Stderr = Excerpt(e.Stderr, stderrExcerpt)
+
+	for _, n := range notifiers {
+		if !n.accept(e.Type) {
+			continue
+		}
+		pending.Add(1)
+		go func(n Notifier) {
+			defer pending.Done()
+			if err := n.send(&e); err != nil {
+				logrus.Warningf("cannot send %s notification to %s: %v", e.Type, n, err)
+			}
+		}(n)
+	}
+}
+
+// Wait for pending notifications to be sent
+func Close() {
+	pending.Wait()
+}
+
+// Check event list is valid. Empty list means all events.
+func checkEvents(events []string) error {
+	for _, e := range events {
+		if !slices.Contains(knownEvents, e) {
+			return fmt.Errorf("%w: '%s'. Expected one of %s", ErrUnknownEvent, e, strings.Join(knownEvents, ", "))
+		}
+	}
+	return nil
+}
+
+func acceptEvent(events []string, event string) bool {
+	return len(events) == 0 || slices.Contains(events, event)
+}
+
+// Keep the last n characters of s: the end of stderr usually tells what went wrong
+func Excerpt(s string, n int) string {
+	s = strings.TrimSpace(s)
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return "..." + string(r[len(r)-n:])
+}
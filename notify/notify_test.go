@@ -0,0 +1,191 @@
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testEvent() Event {
+	return Event{
+		Type:       EventScriptFailure,
+		Time:       time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+		Host:       "mediator01",
+		TicketID:   "42",
+		RequestID:  "abc",
+		Script:     "create-rule",
+		ScriptType: "Trigger script",
+		ExitCode:   2,
+		Stderr:     "cannot reach <firewall>",
+		Error:      "exit status 2",
+	}
+}
+
+// Start a webhook receiver and return its URL and received bodies
+func webhookServer(t *testing.T, status int) (string, chan []byte) {
+	received := make(chan []byte, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected request: %s %s", r.Method, r.Header.Get("Content-Type"))
+		}
+		body, _ := io.ReadAll(r.Body)
+		received <- body
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL, received
+}
+
+func TestWebhookPayloads(t *testing.T) {
+	url, received := webhookServer(t, http.StatusOK)
+	e := testEvent()
+
+	testCases := []struct {
+		format string
+		check  func(map[string]any) bool
+	}{
+		{FORMAT_GENERIC, func(m map[string]any) bool {
+			return m["event"] == EventScriptFailure && m["ticket_id"] == "42" && m["exit_code"] == float64(2) && m["stderr"] == e.Stderr
+		}},
+		{FORMAT_SLACK, func(m map[string]any) bool {
+			text, _ := m["text"].(string)
+			return strings.Contains(text, "*Trigger script 'create-rule' failed with exit code 2 (ticket 42)*") &&
+				strings.Contains(text, "```cannot reach <firewall>```")
+		}},
+		{FORMAT_TEAMS, func(m map[string]any) bool {
+			sections, _ := m["sections"].([]any)
+			if m["@type"] != "MessageCard" || len(sections) != 1 {
+				return false
+			}
+			section := sections[0].(map[string]any)
+			return m["title"] == e.Title() && section["text"] == "<pre>cannot reach &lt;firewall&gt;</pre>"
+		}},
+	}
+	for _, tc := range testCases {
+		w, err := newWebhook(WebhookConfiguration{URL: url, Format: tc.format})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.send(&e); err != nil {
+			t.Fatalf("%s: %v", tc.format, err)
+		}
+		var m map[string]any
+		if err := json.Unmarshal(<-received, &m); err != nil {
+			t.Fatalf("%s: invalid JSON: %v", tc.format, err)
+		}
+		if !tc.check(m) {
+			t.Errorf("%s: unexpected payload %v", tc.format, m)
+		}
+	}
+}
+
+func TestWebhookStatus(t *testing.T) {
+	url, _ := webhookServer(t, http.StatusForbidden)
+	w, err := newWebhook(WebhookConfiguration{URL: url})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := testEvent()
+	if err := w.send(&e); !errors.Is(err, ErrWebhookStatus) {
+		t.Errorf("expected %v, got %v", ErrWebhookStatus, err)
+	}
+}
+
+func TestInitErrors(t *testing.T) {
+	testCases := []struct {
+		conf Configuration
+		err  error
+	}{
+		{Configuration{Webhooks: []WebhookConfiguration{{URL: "ftp://example.com"}}}, ErrInvalidWebhookURL},
+		{Configuration{Webhooks: []WebhookConfiguration{{URL: "https://example.com", Format: "discord"}}}, ErrUnknownWebhookFormat},
+		{Configuration{Webhooks: []WebhookConfiguration{{URL: "https://example.com", Events: []string{"script_crash"}}}}, ErrUnknownEvent},
+		{Configuration{Email: EmailConfiguration{Host: "localhost", From: "mediator@example.com"}}, ErrNoEmailAddress},
+	}
+	for _, tc := range testCases {
+		if err := Init(tc.conf); !errors.Is(err, tc.err) {
+			t.Errorf("%+v: expected %v, got %v", tc.conf, tc.err, err)
+		}
+	}
+}
+
+func TestNotify(t *testing.T) {
+	url, received := webhookServer(t, http.StatusOK)
+
+	var (
+		mutex sync.Mutex
+		mails []string
+	)
+	sendMail = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		mails = append(mails, string(msg))
+		return nil
+	}
+	defer func() { sendMail = smtp.SendMail }()
+
+	err := Init(Configuration{
+		StderrExcerpt: 10,
+		Webhooks: []WebhookConfiguration{
+			{URL: url, Events: []string{EventIntegrityViolation}},
+		},
+		Email: EmailConfiguration{
+			Host: "localhost",
+			From: "mediator@example.com",
+			To:   []string{"ops@example.com"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Init(Configuration{})
+
+	e := testEvent()
+	e.TicketID = "42\r\nBcc: attacker@example.com"
+	Notify(e)
+	Close()
+
+	// webhook only accepts integrity violations
+	select {
+	case body := <-received:
+		t.Errorf("unexpected webhook call: %s", body)
+	default:
+	}
+	if len(mails) != 1 {
+		t.Fatalf("1 email expected, got %d", len(mails))
+	}
+	headers, body, _ := strings.Cut(mails[0], "\r\n\r\n")
+	if strings.Contains(headers, "\r\nBcc:") {
+		t.Errorf("header injection: %q", headers)
+	}
+	if !strings.Contains(headers, "To: ops@example.com\r\n") {
+		t.Errorf("missing recipient: %q", headers)
+	}
+	if !strings.Contains(body, "Stderr:\r\n...<firewall>\r\n") {
+		t.Errorf("stderr excerpt expected in %q", body)
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	testCases := []struct {
+		in   string
+		n    int
+		want string
+	}{
+		{"short\n", 10, "short"},
+		{"0123456789", 10, "0123456789"},
+		{"first line\nlast line", 9, "...last line"},
+		{"ééééé", 2, "...éé"},
+	}
+	for _, tc := range testCases {
+		if got := Excerpt(tc.in, tc.n); got != tc.want {
+			t.Errorf("Excerpt(%q, %d): expected %q, got %q", tc.in, tc.n, tc.want, got)
+		}
+	}
+}
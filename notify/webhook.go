@@ -0,0 +1,151 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Webhook payload formats
+const (
+	FORMAT_GENERIC = "generic"
+	FORMAT_SLACK   = "slack"
+	FORMAT_TEAMS   = "teams"
+)
+
+const DEFAULT_WEBHOOK_TIMEOUT = 10
+
+type WebhookConfiguration struct {
+	URL string `json:"url" mapstructure:"url"`
+	// generic (default), slack or teams
+	Format string `json:"format" mapstructure:"format"`
+	// events sent to this webhook. All events if empty
+	Events []string `json:"events" mapstructure:"events"`
+	// additional HTTP headers, e.g. Authorization
+	Headers map[string]string `json:"headers" mapstructure:"headers"`
+	// request timeout in seconds
+	Timeout uint `json:"timeout" mapstructure:"timeout"`
+}
+
+type webhook struct {
+	conf   WebhookConfiguration
+	client *http.Client
+}
+
+func newWebhook(conf WebhookConfiguration) (*webhook, error) {
+	if u, err := url.Parse(conf.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%w: '%s'", ErrInvalidWebhookURL, conf.URL)
+	}
+	conf.Format = strings.ToLower(conf.Format)
+	switch conf.Format {
+	case "":
+		conf.Format = FORMAT_GENERIC
+	case FORMAT_GENERIC, FORMAT_SLACK, FORMAT_TEAMS:
+	default:
+		return nil, fmt.Errorf("%w: '%s'", ErrUnknownWebhookFormat, conf.Format)
+	}
+	if err := checkEvents(conf.Events); err != nil {
+		return nil, err
+	}
+	if conf.Timeout == 0 {
+		conf.Timeout = DEFAULT_WEBHOOK_TIMEOUT
+	}
+	return &webhook{
+		conf:   conf,
+		client: &http.Client{Timeout: time.Duration(conf.Timeout) * time.Second},
+	}, nil
+}
+
+func (w *webhook) String() string {
+	// do not log URL path: Slack and Teams webhook URLs contain secrets
+	if u, err := url.Parse(w.conf.URL); err == nil {
+		return fmt.Sprintf("%s webhook %s", w.conf.Format, u.Host)
+	}
+	return fmt.Sprintf("%s webhook", w.conf.Format)
+}
+
+func (w *webhook) accept(event string) bool {
+	return acceptEvent(w.conf.Events, event)
+}
+
+func (w *webhook) send(e *Event) error {
+	body, err := json.Marshal(w.payload(e))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, w.conf.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range w.conf.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%w: %s %s", ErrWebhookStatus, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+func (w *webhook) payload(e *Event) any {
+	switch w.conf.Format {
+	case FORMAT_SLACK:
+		return slackPayload(e)
+	case FORMAT_TEAMS:
+		return teamsPayload(e)
+	default:
+		return e
+	}
+}
+
+// Slack incoming webhook message
+// cf https://api.slack.com/messaging/webhooks
+func slackPayload(e *Event) map[string]any {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, ":warning: *%s*\n", e.Title())
+	for _, f := range e.Facts() {
+		fmt.Fprintf(&sb, "*%s:* %s\n", f[0], f[1])
+	}
+	if e.Stderr != "" {
+		fmt.Fprintf(&sb, "```%s```", e.Stderr)
+	}
+	return map[string]any{
+		"text": sb.String(),
+	}
+}
+
+// Microsoft Teams incoming webhook message card
+// cf https://learn.microsoft.com/en-us/outlook/actionable-messages/message-card-reference
+func teamsPayload(e *Event) map[string]any {
+	facts := []map[string]string{}
+	for _, f := range e.Facts() {
+		facts = append(facts, map[string]string{"name": f[0], "value": f[1]})
+	}
+	section := map[string]any{
+		"facts": facts,
+	}
+	if e.Stderr != "" {
+		section["text"] = "<pre>" + html.EscapeString(e.Stderr) + "</pre>"
+	}
+	return map[string]any{
+		"@type":      "MessageCard",
+		"@context":   "https://schema.org/extensions",
+		"themeColor": "D70000",
+		"summary":    e.Title(),
+		"title":      e.Title(),
+		"sections":   []any{section},
+	}
+}
@@ -0,0 +1,20 @@
+package openapi
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// OpenAPI 3 specification of /v1/otp entry points.
+// It is maintained by hand: update it whenever a route is added, removed or modified.
+// openapi_test.go makes sure it matches registered routes.
+//
+//go:embed openapi.json
+var spec []byte
+
+// Handler sends the OpenAPI specification
+func Handler(c echo.Context) error {
+	return c.Blob(http.StatusOK, echo.MIMEApplicationJSON, spec)
+}
@@ -0,0 +1,151 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+
+	"mediator/audit"
+	"mediator/mediatorscript"
+	"mediator/mediatorsettings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const prefix = "/v1/otp"
+
+var reParam = regexp.MustCompile(`:(\w+)`)
+
+type document struct {
+	OpenAPI string                                `json:"openapi"`
+	Servers []struct{ URL string }                `json:"servers"`
+	Paths   map[string]map[string]json.RawMessage `json:"paths"`
+}
+
+// Return "METHOD path" for all /v1/otp routes, using OpenAPI path syntax
+func registeredRoutes() []string {
+	e := echo.New()
+	otp := e.Group(prefix)
+	mediatorscript.AddMediatorscriptAPI(otp)
+	mediatorsettings.AddMediatorsettingsAPI(otp)
+	audit.AddAuditAPI(otp)
+
+	routes := []string{}
+	for _, r := range e.Routes() {
+		if !strings.HasPrefix(r.Path, prefix) || r.Method == echo.RouteNotFound {
+			continue
+		}
+		path := strings.TrimPrefix(r.Path, prefix)
+		if path == "" {
+			path = "/"
+		}
+		path = reParam.ReplaceAllString(path, "{$1}")
+		routes = append(routes, r.Method+" "+path)
+	}
+	sort.Strings(routes)
+	return routes
+}
+
+func documentedRoutes(t *testing.T) []string {
+	var doc document
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		t.Fatalf("invalid OpenAPI document: %v", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		t.Errorf("OpenAPI version 3 expected, got %s", doc.OpenAPI)
+	}
+	if len(doc.Servers) != 1 || doc.Servers[0].URL != prefix {
+		t.Errorf("server URL must be %s", prefix)
+	}
+
+	routes := []string{}
+	for path, operations := range doc.Paths {
+		for method := range operations {
+			if method == "parameters" {
+				continue
+			}
+			routes = append(routes, strings.ToUpper(method)+" "+path)
+		}
+	}
+	sort.Strings(routes)
+	return routes
+}
+
+func TestSpecMatchesRoutes(t *testing.T) {
+	registered := registeredRoutes()
+	documented := documentedRoutes(t)
+
+	for _, r := range registered {
+		if !slices.Contains(documented, r) {
+			t.Errorf("route %s is not documented in openapi.json", r)
+		}
+	}
+	for _, r := range documented {
+		if !slices.Contains(registered, r) {
+			t.Errorf("route %s is documented in openapi.json but is not registered", r)
+		}
+	}
+}
+
+func TestSpecReferences(t *testing.T) {
+	var doc map[string]any
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		t.Fatalf("invalid OpenAPI document: %v", err)
+	}
+	refs := []string{}
+	collectRefs(doc, &refs)
+	for _, ref := range refs {
+		if !strings.HasPrefix(ref, "#/") {
+			t.Errorf("unexpected external reference %s", ref)
+			continue
+		}
+		var node any = doc
+		for _, key := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+			m, ok := node.(map[string]any)
+			if !ok {
+				node = nil
+				break
+			}
+			node = m[key]
+		}
+		if node == nil {
+			t.Errorf("unresolved reference %s", ref)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	e := echo.New()
+	e.GET("/v1/openapi.json", Handler)
+	req, _ := http.NewRequest(http.MethodGet, "/v1/openapi.json", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("status code = %d, want 200", rec.Code)
+	}
+	if ct := rec.Header().Get(echo.HeaderContentType); !strings.HasPrefix(ct, echo.MIMEApplicationJSON) {
+		t.Errorf("content type = %s", ct)
+	}
+}
+
+func collectRefs(node any, refs *[]string) {
+	switch v := node.(type) {
+	case map[string]any:
+		for k, child := range v {
+			if s, ok := child.(string); ok && k == "$ref" {
+				*refs = append(*refs, s)
+			} else {
+				collectRefs(child, refs)
+			}
+		}
+	case []any:
+		for _, child := range v {
+			collectRefs(child, refs)
+		}
+	}
+}
@@ -0,0 +1,8 @@
+package scworkflow
+
+import "errors"
+
+var (
+	ErrFieldNotFound = errors.New("ticket task has no field with that name")
+	ErrFieldNotText  = errors.New("ticket task field is not a text field")
+)
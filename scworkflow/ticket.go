@@ -0,0 +1,115 @@
+package scworkflow
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Field types whose value is a free text
+const (
+	FIELD_TYPE_TEXT_FIELD = "text_field"
+	FIELD_TYPE_TEXT_AREA  = "text_area"
+)
+
+type TicketComment struct {
+	Comment struct {
+		Content string `json:"content"`
+	} `json:"comment"`
+}
+
+type TicketTaskFields struct {
+	Fields struct {
+		Field []*TicketTaskField `json:"field"`
+	} `json:"fields"`
+}
+
+type TicketTaskField struct {
+	Type string `json:"@xsi.type"`
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Text string `json:"text,omitempty"`
+}
+
+func taskEndpoint(ticket_id, step_id, task_id int) string {
+	return fmt.Sprintf("/tickets/%d/steps/%d/tasks/%d", ticket_id, step_id, task_id)
+}
+
+// Return the complete ticket document, in JSON format, as sent by Securechange.
+// Context can be used to limit request duration.
+func GetTicket(ctx context.Context, ticket_id int, username, pwd, host string) ([]byte, error) {
+	c := getSCclient(host)
+	c.SetContext(ctx)
+
+	if body, err := c.RunGETwithCredentials(fmt.Sprintf("/tickets/%d", ticket_id), username, pwd, "json", nil); err != nil {
+		return nil, err
+	} else {
+		return io.ReadAll(body)
+	}
+}
+
+// Add a comment to a ticket task
+func AddTicketTaskComment(ticket_id, step_id, task_id int, comment string, username, pwd, host string) error {
+	var (
+		buff bytes.Buffer
+		tc   TicketComment
+	)
+	c := getSCclient(host)
+	tc.Comment.Content = comment
+	if err := json.NewEncoder(&buff).Encode(tc); err != nil {
+		return err
+	}
+
+	if _, err := c.RunPOSTwithCredentials(taskEndpoint(ticket_id, step_id, task_id)+"/comments", username, pwd, &buff, "json", nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Return the fields of a ticket task
+func GetTicketTaskFields(ticket_id, step_id, task_id int, username, pwd, host string) ([]*TicketTaskField, error) {
+	var fields TicketTaskFields
+	c := getSCclient(host)
+
+	if _, err := c.RunGETwithCredentials(taskEndpoint(ticket_id, step_id, task_id)+"/fields", username, pwd, "json", &fields); err != nil {
+		return nil, err
+	}
+	return fields.Fields.Field, nil
+}
+
+// Set the value of a text field of a ticket task. Field is identified by its name.
+func SetTicketTaskTextField(ticket_id, step_id, task_id int, name, value string, username, pwd, host string) error {
+	var (
+		buff  bytes.Buffer
+		field *TicketTaskField
+	)
+	if fields, err := GetTicketTaskFields(ticket_id, step_id, task_id, username, pwd, host); err != nil {
+		return err
+	} else {
+		for _, f := range fields {
+			if f.Name == name {
+				field = f
+				break
+			}
+		}
+	}
+	if field == nil {
+		return fmt.Errorf("%w: '%s'", ErrFieldNotFound, name)
+	} else if field.Type != FIELD_TYPE_TEXT_FIELD && field.Type != FIELD_TYPE_TEXT_AREA {
+		return fmt.Errorf("%w: '%s' is a %s", ErrFieldNotText, name, field.Type)
+	}
+
+	field.Text = value
+	if err := json.NewEncoder(&buff).Encode(map[string]*TicketTaskField{field.Type: field}); err != nil {
+		return err
+	}
+
+	c := getSCclient(host)
+	endpoint := fmt.Sprintf("%s/fields/%d", taskEndpoint(ticket_id, step_id, task_id), field.ID)
+	if _, err := c.RunPUTwithCredentials(endpoint, username, pwd, &buff, "json", nil); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package scworkflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const taskPath = "/securechangeworkflow/api/securechange/tickets/12/steps/34/tasks/56"
+
+// Fake Securechange API. Return API URL and received requests (method path body)
+func securechangeServer(t *testing.T) (string, *[]string) {
+	received := []string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if u, p, ok := r.BasicAuth(); !ok || u != "user" || p != "pwd" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		received = append(received, r.Method+" "+r.URL.Path+" "+string(body))
+
+		if r.Method == http.MethodGet && r.URL.Path == "/securechangeworkflow/api/securechange/tickets/12" {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"ticket":{"id":12,"domain_name":"Default"}}`))
+			return
+		}
+		if r.Method == http.MethodGet && r.URL.Path == taskPath+"/fields" {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]any{"fields": map[string]any{"field": []map[string]any{
+				{"@xsi.type": "text_area", "id": 1, "name": "Result", "text": ""},
+				{"@xsi.type": "checkbox", "id": 2, "name": "Done"},
+			}}})
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL + "/securechangeworkflow/api/securechange", &received
+}
+
+func TestGetTicket(t *testing.T) {
+	host, _ := securechangeServer(t)
+	if ticket, err := GetTicket(context.Background(), 12, "user", "pwd", host); err != nil {
+		t.Fatal(err)
+	} else if string(ticket) != `{"ticket":{"id":12,"domain_name":"Default"}}` {
+		t.Errorf("unexpected ticket: %s", ticket)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := GetTicket(ctx, 12, "user", "pwd", host); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestAddTicketTaskComment(t *testing.T) {
+	host, received := securechangeServer(t)
+	if err := AddTicketTaskComment(12, 34, 56, "rule created", "user", "pwd", host); err != nil {
+		t.Fatal(err)
+	}
+	want := "POST " + taskPath + `/comments {"comment":{"content":"rule created"}}` + "\n"
+	if len(*received) != 1 || (*received)[0] != want {
+		t.Errorf("expected %q, got %q", want, *received)
+	}
+}
+
+func TestSetTicketTaskTextField(t *testing.T) {
+	host, received := securechangeServer(t)
+	if err := SetTicketTaskTextField(12, 34, 56, "Result", "done", "user", "pwd", host); err != nil {
+		t.Fatal(err)
+	}
+	want := "PUT " + taskPath + `/fields/1 {"text_area":{"@xsi.type":"text_area","id":1,"name":"Result","text":"done"}}` + "\n"
+	if len(*received) != 2 || (*received)[1] != want {
+		t.Errorf("expected %q, got %q", want, *received)
+	}
+
+	testCases := map[string]error{
+		"Missing": ErrFieldNotFound,
+		"Done":    ErrFieldNotText,
+	}
+	for name, want := range testCases {
+		if err := SetTicketTaskTextField(12, 34, 56, name, "x", "user", "pwd", host); !errors.Is(err, want) {
+			t.Errorf("%s: expected %v, got %v", name, want, err)
+		}
+	}
+}
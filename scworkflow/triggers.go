@@ -3,15 +3,16 @@ package scworkflow
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
+	"path"
 	"strings"
 )
 
 type SecurechangeTrigger int
 
-// WARNING!!!
-// Order is VERY IMPORTANT here!
-// triggers below ADVANCE have only one script
-// Other triggers need a step to get the appropriate script
+// Securechange events mediator-client can be triggered by.
+// Values are only used in memory: settings and requests use trigger names.
+// New triggers must be added before LAST_TRIGGER and in all maps below.
 const (
 	NO_TRIGGER SecurechangeTrigger = iota
 	CREATE
@@ -27,10 +28,6 @@ const (
 	LAST_TRIGGER
 )
 
-const (
-	NEED_STEP_THRESHOLD = ADVANCE
-)
-
 var scTriggersToString = map[SecurechangeTrigger]string{
 	NO_TRIGGER:        "",
 	CREATE:            "Create",
@@ -59,6 +56,46 @@ var scTriggersToID = map[string]SecurechangeTrigger{
 	"":                      NO_TRIGGER,
 }
 
+// Event names used in Securechange workflow trigger configuration: they are the values of the 'events'
+// list of workflow triggers read from and written to Securechange REST API
+// (GET and POST securechangeworkflow/api/securechange/triggers, cf GetSecurechangeWorkflowTriggers).
+var scTriggersToSlug = map[SecurechangeTrigger]string{
+	CREATE:            "CREATE",
+	CLOSE:             "CLOSE",
+	CANCEL:            "CANCEL",
+	REJECT:            "REJECT",
+	ADVANCE:           "ADVANCE",
+	REDO:              "REDO",
+	RESUBMIT:          "RESUBMIT",
+	REOPEN:            "REOPEN",
+	RESOLVE:           "RESOLVE",
+	AUTOMATION_FAILED: "AUTOMATION_FAILED",
+}
+
+// How rules of a trigger are matched against ticket step
+type triggerSteps struct {
+	// rules of the trigger are attached to a step. Otherwise, a rule matches every step.
+	needStep bool
+	// rules are set on the step the ticket enters but Securechange sends the step the ticket leaves:
+	// step of such rules is moved to the previous one when settings are uploaded
+	useNextStep bool
+}
+
+// Only ADVANCE uses next step: users pick the step the ticket enters, which is what they see in the
+// workflow, while Securechange sends the step it leaves. Other events are sent for the step the ticket is in.
+var scTriggersSteps = map[SecurechangeTrigger]triggerSteps{
+	CREATE:            {needStep: false, useNextStep: false},
+	CLOSE:             {needStep: false, useNextStep: false},
+	CANCEL:            {needStep: false, useNextStep: false},
+	REJECT:            {needStep: false, useNextStep: false},
+	RESUBMIT:          {needStep: false, useNextStep: false},
+	RESOLVE:           {needStep: false, useNextStep: false},
+	ADVANCE:           {needStep: true, useNextStep: true},
+	REDO:              {needStep: true, useNextStep: false},
+	REOPEN:            {needStep: true, useNextStep: false},
+	AUTOMATION_FAILED: {needStep: true, useNextStep: false},
+}
+
 func (t SecurechangeTrigger) String() string {
 	if s, ok := scTriggersToString[t]; ok {
 		return s
@@ -68,22 +105,42 @@ func (t SecurechangeTrigger) String() string {
 }
 
 func (t SecurechangeTrigger) Slug() string {
-	if t == AUTOMATION_FAILED {
-		return "AUTOMATION_FAILED"
-	}
-	if s, ok := scTriggersToString[t]; ok {
-		return strings.ToUpper(s)
+	if s, ok := scTriggersToSlug[t]; ok {
+		return s
 	} else {
 		return "UNKNOWN"
 	}
 }
 
+// Directory of Securechange scripts where mediator-client and its helper scripts are installed by default
+const DEFAULT_CLIENT_INSTALL_PATH = "/opt/tufin/data/securechange/scripts"
+
+// Name mediator-client is called with for this trigger when it is installed as a symlink
+func (t SecurechangeTrigger) HelperName() string {
+	return fmt.Sprintf("mediator-client-%s", strings.ReplaceAll(strings.ToLower(t.String()), " ", "-"))
+}
+
+// Name of the helper script that runs mediator-client for this trigger
+func (t SecurechangeTrigger) HelperScriptName() string {
+	return t.HelperName() + ".sh"
+}
+
+// Full path of the helper script when mediator-client is installed in install_path
+func (t SecurechangeTrigger) HelperScriptPath(install_path string) string {
+	return path.Join(install_path, t.HelperScriptName())
+}
+
+// Full path of the symlink to mediator-client when it is installed in install_path
+func (t SecurechangeTrigger) HelperLinkPath(install_path string) string {
+	return path.Join(install_path, t.HelperName())
+}
+
 func (t SecurechangeTrigger) NeedStepToGetScript() bool {
-	return t >= NEED_STEP_THRESHOLD
+	return scTriggersSteps[t].needStep
 }
 
 func (t SecurechangeTrigger) UseNextStep() bool {
-	return t == ADVANCE /*|| t == CREATE*/
+	return scTriggersSteps[t].useNextStep
 }
 
 func (t SecurechangeTrigger) MarshalJSON() ([]byte, error) {
@@ -102,6 +159,18 @@ func (t *SecurechangeTrigger) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
+// Get trigger from the name of its helper script or symlink, with or without directory.
+// Return NO_TRIGGER if name is not the one of a trigger helper.
+func GetTriggerFromHelperName(name string) SecurechangeTrigger {
+	name = strings.TrimSuffix(path.Base(name), ".sh")
+	for i := 1; i < int(LAST_TRIGGER); i++ {
+		if t := SecurechangeTrigger(i); t.HelperName() == name {
+			return t
+		}
+	}
+	return NO_TRIGGER
+}
+
 func GetTriggerFromString(t string) SecurechangeTrigger {
 	for trigger_name, trigger_id := range scTriggersToID {
 		if strings.EqualFold(t, trigger_name) {
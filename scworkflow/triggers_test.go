@@ -221,3 +221,101 @@ func TestSecurechangeTrigger_NeedStepToGetScript(t *testing.T) {
 		})
 	}
 }
+
+func TestSecurechangeTrigger_maps(t *testing.T) {
+	for i := 1; i < int(LAST_TRIGGER); i++ {
+		tr := SecurechangeTrigger(i)
+		if tr.String() == "unknown" {
+			t.Errorf("trigger %d has no name", i)
+		} else if GetTriggerFromString(tr.String()) != tr {
+			t.Errorf("trigger %d: name '%s' gives %v", i, tr, GetTriggerFromString(tr.String()))
+		}
+		if tr.Slug() == "UNKNOWN" {
+			t.Errorf("trigger %s has no slug", tr)
+		}
+		if _, ok := scTriggersSteps[tr]; !ok {
+			t.Errorf("trigger %s: step properties are not set", tr)
+		}
+		if tr.UseNextStep() && !tr.NeedStepToGetScript() {
+			t.Errorf("trigger %s uses next step but does not need a step", tr)
+		}
+	}
+}
+
+func TestSecurechangeTrigger_HelperScriptName(t *testing.T) {
+	tests := map[SecurechangeTrigger]string{
+		CREATE:            "mediator-client-create.sh",
+		AUTOMATION_FAILED: "mediator-client-automatic-step-failed.sh",
+	}
+	for tr, want := range tests {
+		if got := tr.HelperScriptName(); got != want {
+			t.Errorf("%s.HelperScriptName() = %s, want %s", tr, got, want)
+		}
+		if got := tr.HelperScriptPath(DEFAULT_CLIENT_INSTALL_PATH); got != DEFAULT_CLIENT_INSTALL_PATH+"/"+want {
+			t.Errorf("%s.HelperScriptPath() = %s", tr, got)
+		}
+	}
+}
+
+func TestGetTriggerFromHelperName(t *testing.T) {
+	tests := []struct {
+		name string
+		want SecurechangeTrigger
+	}{
+		{"mediator-client-advance", ADVANCE},
+		{"mediator-client-advance.sh", ADVANCE},
+		{"/opt/tufin/data/securechange/scripts/mediator-client-reopen", REOPEN},
+		{"./mediator-client-automatic-step-failed.sh", AUTOMATION_FAILED},
+		{"mediator-client", NO_TRIGGER},
+		{"mediator-client-scripted-task", NO_TRIGGER},
+		{"mediator-client-ADVANCE", NO_TRIGGER},
+		{"advance", NO_TRIGGER},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GetTriggerFromHelperName(tt.name); got != tt.want {
+				t.Errorf("GetTriggerFromHelperName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	for i := 1; i < int(LAST_TRIGGER); i++ {
+		tr := SecurechangeTrigger(i)
+		if got := GetTriggerFromHelperName(tr.HelperLinkPath(DEFAULT_CLIENT_INSTALL_PATH)); got != tr {
+			t.Errorf("GetTriggerFromHelperName(%s) = %v", tr.HelperLinkPath(DEFAULT_CLIENT_INSTALL_PATH), got)
+		}
+	}
+}
+
+// Rules of ADVANCE are set on the step the ticket enters and moved to the step it leaves, which is the
+// one Securechange sends. Other triggers are sent for the step the ticket is in.
+func TestTriggerSteps(t *testing.T) {
+	tests := []struct {
+		trigger     SecurechangeTrigger
+		needStep    bool
+		useNextStep bool
+	}{
+		{CREATE, false, false},
+		{CLOSE, false, false},
+		{CANCEL, false, false},
+		{REJECT, false, false},
+		{RESUBMIT, false, false},
+		{RESOLVE, false, false},
+		{ADVANCE, true, true},
+		{REDO, true, false},
+		{REOPEN, true, false},
+		{AUTOMATION_FAILED, true, false},
+	}
+	if len(tests) != int(LAST_TRIGGER)-1 {
+		t.Fatalf("%d triggers tested, %d expected", len(tests), int(LAST_TRIGGER)-1)
+	}
+	for _, tt := range tests {
+		t.Run(tt.trigger.String(), func(t *testing.T) {
+			if got := tt.trigger.NeedStepToGetScript(); got != tt.needStep {
+				t.Errorf("NeedStepToGetScript() = %v, want %v", got, tt.needStep)
+			}
+			if got := tt.trigger.UseNextStep(); got != tt.useNextStep {
+				t.Errorf("UseNextStep() = %v, want %v", got, tt.useNextStep)
+			}
+		})
+	}
+}
@@ -0,0 +1,12 @@
+package signature
+
+import "errors"
+
+var (
+	ErrMissingSignature = errors.New("request is not signed")
+	ErrInvalidTimestamp = errors.New("invalid request signature timestamp")
+	ErrExpiredSignature = errors.New("request signature has expired")
+	ErrInvalidSignature = errors.New("invalid request signature")
+	ErrReplayedRequest  = errors.New("request has already been received")
+	ErrBodyTooLarge     = errors.New("request body is too large")
+)
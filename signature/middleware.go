@@ -0,0 +1,92 @@
+package signature
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"mediator/metrics"
+	"mediator/ttlcache"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// nonces received during the last 2*MAX_CLOCK_SKEW
+	nonces = ttlcache.New[string, struct{}](2 * MAX_CLOCK_SKEW)
+	// body is read in memory to be verified: larger ones are rejected
+	max_body_size int64 = DEFAULT_MAX_BODY_SIZE
+)
+
+// Set the max size of a request body, in bytes. 0 or less means DEFAULT_MAX_BODY_SIZE.
+func SetMaxBodySize(size int64) {
+	if size <= 0 {
+		size = DEFAULT_MAX_BODY_SIZE
+	}
+	max_body_size = size
+}
+
+// Verify is a middleware that rejects requests with a missing or invalid signature.
+// A request is only accepted once: its nonce is remembered until its timestamp expires.
+//
+// Usage `Group#Use(signature.Verify)`
+func Verify(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if err := verifyRequest(c.Request(), time.Now()); errors.Is(err, ErrBodyTooLarge) {
+			logrus.Warningf("rejected request %s %s from %s: %v", c.Request().Method, c.Request().URL.Path, c.RealIP(), err)
+			return echo.NewHTTPError(http.StatusRequestEntityTooLarge, err.Error())
+		} else if err != nil {
+			metrics.AuthFailures.Inc("invalid_signature")
+			logrus.Warningf("rejected request %s %s from %s: %v", c.Request().Method, c.Request().URL.Path, c.RealIP(), err)
+			return echo.NewHTTPError(http.StatusUnauthorized, err.Error())
+		}
+		return next(c)
+	}
+}
+
+func verifyRequest(r *http.Request, now time.Time) error {
+	ts := r.Header.Get(HeaderTimestamp)
+	nonce := r.Header.Get(HeaderNonce)
+	sig := r.Header.Get(HeaderSignature)
+	if ts == "" || nonce == "" || sig == "" {
+		return ErrMissingSignature
+	}
+
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidTimestamp, ts)
+	}
+	if d := now.Sub(time.Unix(sec, 0)); d > MAX_CLOCK_SKEW || d < -MAX_CLOCK_SKEW {
+		return fmt.Errorf("%w: timestamp is %s away from server time", ErrExpiredSignature, d.Round(time.Second))
+	}
+
+	// read body so we can compute its digest
+	// then put it back so handlers can use it
+	var body []byte
+	if r.Body != nil {
+		var too_large *http.MaxBytesError
+		if body, err = io.ReadAll(http.MaxBytesReader(nil, r.Body, max_body_size)); errors.As(err, &too_large) {
+			return fmt.Errorf("%w: more than %d bytes", ErrBodyTooLarge, too_large.Limit)
+		} else if err != nil {
+			return fmt.Errorf("cannot read request body: %w", err)
+		}
+		r.Body.Close()
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	if !hmac.Equal([]byte(sig), []byte(compute(r.Method, r.URL, body, ts, nonce))) {
+		return ErrInvalidSignature
+	}
+
+	// signature is valid: make sure we have never seen this nonce before
+	if !nonces.Add(nonce, struct{}{}) {
+		return ErrReplayedRequest
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package signature
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func newSignedRequest(t *testing.T, method, url string, body []byte) *http.Request {
+	r, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Sign(r, body); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func Test_verifyRequest(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name    string
+		request func() *http.Request
+		wantErr error
+	}{
+		{
+			name: "ok",
+			request: func() *http.Request {
+				return newSignedRequest(t, "POST", "https://mediator/v1/otp/execute/script.sh", []byte(`{"ID":1}`))
+			},
+			wantErr: nil,
+		},
+		{
+			name: "ok multiple slashes and query",
+			request: func() *http.Request {
+				r := newSignedRequest(t, "GET", "https://mediator/v1//otp/settings/?b=2&a=1", nil)
+				// server routers merge slashes and remove trailing slash before middlewares run
+				r.URL.Path = "/v1/otp/settings"
+				r.URL.RawQuery = "a=1&b=2"
+				return r
+			},
+			wantErr: nil,
+		},
+		{
+			name: "not signed",
+			request: func() *http.Request {
+				r, _ := http.NewRequest("POST", "https://mediator/v1/otp/execute/script.sh", nil)
+				return r
+			},
+			wantErr: ErrMissingSignature,
+		},
+		{
+			name: "bad timestamp",
+			request: func() *http.Request {
+				r := newSignedRequest(t, "POST", "https://mediator/v1/otp/execute/script.sh", nil)
+				r.Header.Set(HeaderTimestamp, "yesterday")
+				return r
+			},
+			wantErr: ErrInvalidTimestamp,
+		},
+		{
+			name: "expired",
+			request: func() *http.Request {
+				r := newSignedRequest(t, "POST", "https://mediator/v1/otp/execute/script.sh", nil)
+				r.Header.Set(HeaderTimestamp, strconv.FormatInt(now.Add(-2*MAX_CLOCK_SKEW).Unix(), 10))
+				return r
+			},
+			wantErr: ErrExpiredSignature,
+		},
+		{
+			name: "tampered body",
+			request: func() *http.Request {
+				r := newSignedRequest(t, "POST", "https://mediator/v1/otp/execute/script.sh", []byte(`{"ID":1}`))
+				r.Body = http.NoBody
+				return r
+			},
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name: "tampered path",
+			request: func() *http.Request {
+				r := newSignedRequest(t, "POST", "https://mediator/v1/otp/execute/script.sh", nil)
+				r.URL.Path = "/v1/otp/execute/other.sh"
+				return r
+			},
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name: "tampered method",
+			request: func() *http.Request {
+				r := newSignedRequest(t, "POST", "https://mediator/v1/otp/unregister-all", nil)
+				r.Method = "DELETE"
+				return r
+			},
+			wantErr: ErrInvalidSignature,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := verifyRequest(tt.request(), now); !errors.Is(err, tt.wantErr) {
+				t.Errorf("verifyRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_verifyRequest_bodySize(t *testing.T) {
+	t.Cleanup(func() { SetMaxBodySize(0) })
+	SetMaxBodySize(8)
+
+	if err := verifyRequest(newSignedRequest(t, "POST", "https://mediator/v1/otp/execute/script.sh", []byte(`{"ID":1}`)), time.Now()); err != nil {
+		t.Errorf("body of max size: unexpected error %v", err)
+	}
+	if err := verifyRequest(newSignedRequest(t, "POST", "https://mediator/v1/otp/execute/script.sh", []byte(`{"ID":10}`)), time.Now()); !errors.Is(err, ErrBodyTooLarge) {
+		t.Errorf("body over max size: error = %v, want %v", err, ErrBodyTooLarge)
+	}
+}
+
+func Test_verifyRequest_replay(t *testing.T) {
+	r := newSignedRequest(t, "POST", "https://mediator/v1/otp/execute/script.sh", []byte(`{"ID":2}`))
+	if err := verifyRequest(r, time.Now()); err != nil {
+		t.Fatalf("first request: unexpected error %v", err)
+	}
+
+	// same request sent again
+	r.Body = http.NoBody
+	replay, _ := http.NewRequest(r.Method, r.URL.String(), bytes.NewReader([]byte(`{"ID":2}`)))
+	replay.Header = r.Header.Clone()
+	if err := verifyRequest(replay, time.Now()); !errors.Is(err, ErrReplayedRequest) {
+		t.Errorf("replayed request: error = %v, want %v", err, ErrReplayedRequest)
+	}
+}
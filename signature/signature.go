@@ -0,0 +1,102 @@
+package signature
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SECRETKEY = "a request signature key must be provided here"
+
+	HeaderTimestamp = "X-Mediator-Timestamp"
+	HeaderNonce     = "X-Mediator-Nonce"
+	HeaderSignature = "X-Mediator-Signature"
+
+	// Maximum accepted difference between the signature timestamp and server time.
+	// Nonces are remembered twice as long so a request cannot be replayed
+	// while its timestamp is still acceptable.
+	MAX_CLOCK_SKEW = 30 * time.Second
+
+	// Max size of a request body, in bytes, unless SetMaxBodySize is called
+	DEFAULT_MAX_BODY_SIZE = 10 << 20
+)
+
+var (
+	// This must be changed at build via compilation flag
+	secretKey = SECRETKEY
+)
+
+func init() {
+	if secretKey == SECRETKEY {
+		log.Fatalln("signature package secretKey has not been changed. Stop.")
+	}
+}
+
+// Sign an outgoing request.
+// body must be the exact content sent in the request body (nil if no body).
+// Sets the timestamp, nonce and signature headers.
+func Sign(r *http.Request, body []byte) error {
+	nonce, err := newNonce()
+	if err != nil {
+		return err
+	}
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+
+	r.Header.Set(HeaderTimestamp, ts)
+	r.Header.Set(HeaderNonce, nonce)
+	r.Header.Set(HeaderSignature, compute(r.Method, r.URL, body, ts, nonce))
+	return nil
+}
+
+// Compute the hex encoded HMAC-SHA256 of the canonical request
+func compute(method string, u *url.URL, body []byte, ts string, nonce string) string {
+	h := hmac.New(sha256.New, []byte(secretKey))
+	h.Write([]byte(canonicalRequest(method, u, body, ts, nonce)))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Build the string covered by the signature:
+// method, path (and query string), body digest, timestamp and nonce separated by new lines.
+func canonicalRequest(method string, u *url.URL, body []byte, ts string, nonce string) string {
+	digest := sha256.Sum256(body)
+	return strings.Join([]string{
+		strings.ToUpper(method),
+		canonicalPath(u),
+		hex.EncodeToString(digest[:]),
+		ts,
+		nonce,
+	}, "\n")
+}
+
+var reMultipleSlash = regexp.MustCompile(`/+`)
+
+// Path as seen by the server once the router "Pre" middlewares have run:
+// multiple slashes are merged and trailing slash is removed.
+// Query parameters are sorted so their order does not matter.
+func canonicalPath(u *url.URL) string {
+	path := reMultipleSlash.ReplaceAllString(u.Path, "/")
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+	if q := u.Query().Encode(); q != "" {
+		path += "?" + q
+	}
+	return path
+}
+
+func newNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
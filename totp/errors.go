@@ -0,0 +1,9 @@
+package totp
+
+import "errors"
+
+var (
+	ErrInvalidKey       = errors.New("invalid key")
+	ErrInvalidKeyFormat = errors.New("invalid key format: 12 digits expected")
+	ErrLockedOut        = errors.New("too many authentication failures")
+)
@@ -0,0 +1,42 @@
+package totp
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// IPExtractor returns how client IP addresses are found. Rate limiter, lockout and audit log rely on it.
+// Without trusted proxy, the address of the connection is used: X-Forwarded-For and X-Real-IP headers
+// are set by clients and ignored, otherwise a client could change its identity on every request.
+// With trusted proxies (IP addresses or CIDR ranges), X-Forwarded-For header is only trusted when
+// it has been set by one of them.
+func IPExtractor(trusted_proxies []string) (echo.IPExtractor, error) {
+	if len(trusted_proxies) == 0 {
+		return echo.ExtractIPDirect(), nil
+	}
+	options := []echo.TrustOption{
+		echo.TrustLoopback(false),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}
+	for _, p := range trusted_proxies {
+		if !strings.Contains(p, "/") {
+			if ip := net.ParseIP(p); ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy '%s'", p)
+			} else if ip.To4() != nil {
+				p += "/32"
+			} else {
+				p += "/128"
+			}
+		}
+		_, ipnet, err := net.ParseCIDR(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy '%s': %w", p, err)
+		}
+		options = append(options, echo.TrustIPRange(ipnet))
+	}
+	return echo.ExtractIPFromXFFHeader(options...), nil
+}
@@ -0,0 +1,107 @@
+package totp
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Keeps track of authentication failures per client
+// and locks clients out for an exponentially growing duration.
+type Lockout struct {
+	mutex       sync.Mutex
+	clients     map[string]*lockoutState
+	maxFailures int
+	duration    time.Duration
+	maxDuration time.Duration
+	now         func() time.Time
+}
+
+type lockoutState struct {
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+// Create a new Lockout.
+// A client is locked out for <duration> once it has failed <maxFailures> times in a row.
+// Every subsequent failure doubles the lockout duration, up to <maxDuration>.
+// Failures are forgotten after a success or when a client has neither failed
+// nor been locked out for <maxDuration>.
+func NewLockout(maxFailures int, duration time.Duration, maxDuration time.Duration) *Lockout {
+	if maxDuration < duration {
+		maxDuration = duration
+	}
+	return &Lockout{
+		clients:     make(map[string]*lockoutState),
+		maxFailures: maxFailures,
+		duration:    duration,
+		maxDuration: maxDuration,
+		now:         time.Now,
+	}
+}
+
+// Tells if a client is currently locked out and for how long.
+func (l *Lockout) IsLocked(client string) (bool, time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if s, ok := l.clients[client]; ok {
+		if remaining := s.lockedUntil.Sub(l.now()); remaining > 0 {
+			return true, remaining
+		}
+	}
+	return false, 0
+}
+
+// Record an authentication failure.
+// Return the number of consecutive failures and the lockout duration if client is now locked out.
+func (l *Lockout) Failure(client string) (int, time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	l.purge(now)
+
+	s, ok := l.clients[client]
+	if !ok {
+		s = &lockoutState{}
+		l.clients[client] = s
+	}
+	s.failures += 1
+	s.lastFailure = now
+
+	if s.failures < l.maxFailures {
+		return s.failures, 0
+	}
+
+	// lockout duration doubles for every failure over the threshold
+	d := l.maxDuration
+	if exp := s.failures - l.maxFailures; exp < 32 {
+		d = time.Duration(math.Min(float64(l.duration)*math.Pow(2, float64(exp)), float64(l.maxDuration)))
+	}
+	s.lockedUntil = now.Add(d)
+	return s.failures, d
+}
+
+// Record a successful authentication: failures are forgotten
+func (l *Lockout) Success(client string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	delete(l.clients, client)
+}
+
+// forget clients that have not failed for a long time
+// caller must hold the mutex
+func (l *Lockout) purge(now time.Time) {
+	for client, s := range l.clients {
+		last := s.lastFailure
+		if s.lockedUntil.After(last) {
+			last = s.lockedUntil
+		}
+		if now.Sub(last) > l.maxDuration {
+			delete(l.clients, client)
+		}
+	}
+}
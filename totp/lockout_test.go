@@ -0,0 +1,65 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLockout(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := NewLockout(3, 10*time.Second, 60*time.Second)
+	l.now = func() time.Time { return now }
+
+	steps := []struct {
+		name         string
+		success      bool
+		wait         time.Duration
+		wantLockout  time.Duration
+		wantIsLocked bool
+	}{
+		{name: "failure 1", wantLockout: 0},
+		{name: "failure 2", wantLockout: 0},
+		{name: "failure 3 locks out", wantLockout: 10 * time.Second, wantIsLocked: true},
+		{name: "failure 4 doubles", wantLockout: 20 * time.Second, wantIsLocked: true},
+		{name: "failure 5 doubles", wantLockout: 40 * time.Second, wantIsLocked: true},
+		{name: "failure 6 is capped", wantLockout: 60 * time.Second, wantIsLocked: true},
+		{name: "failure after lockout expired", wait: 61 * time.Second, wantLockout: 60 * time.Second, wantIsLocked: true},
+		{name: "forgotten after a while", wait: 121 * time.Second, wantLockout: 0},
+		{name: "success resets", success: true},
+		{name: "failure after success", wantLockout: 0},
+	}
+	for _, s := range steps {
+		now = now.Add(s.wait)
+		if s.success {
+			l.Success("1.2.3.4")
+		} else if _, d := l.Failure("1.2.3.4"); d != s.wantLockout {
+			t.Errorf("%s: Failure() lockout = %v, want %v", s.name, d, s.wantLockout)
+		}
+		if locked, _ := l.IsLocked("1.2.3.4"); locked != s.wantIsLocked {
+			t.Errorf("%s: IsLocked() = %v, want %v", s.name, locked, s.wantIsLocked)
+		}
+		if locked, _ := l.IsLocked("5.6.7.8"); locked {
+			t.Errorf("%s: IsLocked() on another client = true, want false", s.name)
+		}
+	}
+}
+
+func TestCheckKey_format(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+	}{
+		{name: "empty", key: ""},
+		{name: "too short", key: "12345"},
+		{name: "too long", key: "1234567890123"},
+		{name: "not digits", key: "12345a789012"},
+		{name: "spaces", key: " 12345678901"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if ok, err := CheckKey(tt.key, nil); ok || err != ErrInvalidKeyFormat {
+				t.Errorf("CheckKey() = %v, %v, want false, %v", ok, err, ErrInvalidKeyFormat)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package totp
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"mediator/audit"
+	"mediator/metrics"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/sirupsen/logrus"
+)
+
+// KeyAuthWithLockout returns a middleware that checks the tOTP key sent in Authorization header.
+// Clients are identified by their IP address.
+// Every authentication failure is logged and clients sending too many wrong keys are locked out:
+// their requests without a valid key receive a "429 Too Many Requests" response until the lockout expires.
+// Requests with a valid key are still accepted: all mediator-clients of a Securechange pod share its IP address.
+//
+// Usage `Group#Use(totp.KeyAuthWithLockout(l))`
+func KeyAuthWithLockout(l *Lockout) echo.MiddlewareFunc {
+	keyauth := middleware.KeyAuth(func(key string, c echo.Context) (bool, error) {
+		ok, err := CheckKey(key, c)
+		if ok && err == nil {
+			l.Success(c.RealIP())
+			return true, nil
+		}
+
+		if err == nil {
+			err = ErrInvalidKey
+		}
+		metrics.AuthFailures.Inc("invalid_key")
+		failures, d := l.Failure(c.RealIP())
+		entry := audit.NewEntry(c, audit.ActionAuthenticationError)
+		entry.Target = fmt.Sprintf("%s %s", c.Request().Method, c.Request().URL.Path)
+		entry.Save(err)
+		if d > 0 {
+			logrus.Warningf("authentication failure from %s on %s %s: %v. %d consecutive failures: locked out for %s", c.RealIP(), c.Request().Method, c.Request().URL.Path, err, failures, d)
+		} else {
+			logrus.Warningf("authentication failure from %s on %s %s: %v. %d consecutive failure(s)", c.RealIP(), c.Request().Method, c.Request().URL.Path, err, failures)
+		}
+		return false, err
+	})
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		h := keyauth(next)
+		return func(c echo.Context) error {
+			if locked, remaining := l.IsLocked(c.RealIP()); locked && !HasValidKey(c) {
+				metrics.AuthFailures.Inc("locked_out")
+				c.Response().Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(remaining.Seconds()))))
+				return echo.NewHTTPError(http.StatusTooManyRequests, ErrLockedOut.Error())
+			}
+			return h(c)
+		}
+	}
+}
+
+// Tell whether request carries a valid tOTP key in Authorization header.
+// Used to exempt authenticated clients from throttling meant for brute force attempts.
+func HasValidKey(c echo.Context) bool {
+	key, found := strings.CutPrefix(c.Request().Header.Get(echo.HeaderAuthorization), "Bearer ")
+	if !found {
+		return false
+	}
+	ok, err := CheckKey(key, c)
+	return ok && err == nil
+}
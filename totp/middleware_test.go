@@ -0,0 +1,109 @@
+package totp
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestKeyAuthWithLockout_forwardedFor(t *testing.T) {
+	tests := []struct {
+		name           string
+		trustedProxies []string
+		remoteAddr     string
+		wantLocked     bool
+	}{
+		{
+			name:       "headers are ignored without trusted proxy",
+			remoteAddr: "203.0.113.10:40000",
+			wantLocked: true,
+		},
+		{
+			name:           "headers are ignored from untrusted proxy",
+			trustedProxies: []string{"10.0.0.1"},
+			remoteAddr:     "203.0.113.10:40000",
+			wantLocked:     true,
+		},
+		{
+			name:           "headers are used from trusted proxy",
+			trustedProxies: []string{"10.0.0.0/24"},
+			remoteAddr:     "10.0.0.1:40000",
+			wantLocked:     false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			extractor, err := IPExtractor(tt.trustedProxies)
+			if err != nil {
+				t.Fatal(err)
+			}
+			e := echo.New()
+			e.IPExtractor = extractor
+			e.Use(KeyAuthWithLockout(NewLockout(3, time.Minute, time.Minute)))
+			e.GET("/", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+
+			// brute force with a different forwarded address on every request
+			locked := false
+			for i := range 10 {
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				req.RemoteAddr = tt.remoteAddr
+				req.Header.Set("Authorization", "Bearer 000000000000")
+				req.Header.Set(echo.HeaderXForwardedFor, fmt.Sprintf("198.51.100.%d", i+1))
+				req.Header.Set(echo.HeaderXRealIP, fmt.Sprintf("198.51.100.%d", i+1))
+				rec := httptest.NewRecorder()
+				e.ServeHTTP(rec, req)
+				if rec.Code == http.StatusTooManyRequests {
+					locked = true
+				} else if rec.Code != http.StatusUnauthorized {
+					t.Fatalf("request %d: status %d", i, rec.Code)
+				}
+			}
+			if locked != tt.wantLocked {
+				t.Errorf("client locked out = %v, want %v", locked, tt.wantLocked)
+			}
+		})
+	}
+}
+
+// A locked out client is still accepted with a valid key
+func TestKeyAuthWithLockout_validKey(t *testing.T) {
+	e := echo.New()
+	e.Use(KeyAuthWithLockout(NewLockout(2, time.Minute, time.Minute)))
+	e.GET("/", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+	send := func(key string) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+key)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	for range 2 {
+		send("000000000000")
+	}
+	if code := send("000000000000"); code != http.StatusTooManyRequests {
+		t.Fatalf("client should be locked out, got status %d", code)
+	}
+	key, err := GetKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code := send(key); code != http.StatusOK {
+		t.Errorf("valid key should be accepted, got status %d", code)
+	}
+}
+
+func TestIPExtractor(t *testing.T) {
+	for _, proxies := range [][]string{{"not an IP"}, {"10.0.0.0/33"}} {
+		if _, err := IPExtractor(proxies); err == nil {
+			t.Errorf("IPExtractor(%v) error = nil", proxies)
+		}
+	}
+	if _, err := IPExtractor([]string{"10.0.0.1", "2001:db8::1", "192.168.0.0/16"}); err != nil {
+		t.Errorf("IPExtractor() error = %v", err)
+	}
+}
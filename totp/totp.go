@@ -3,6 +3,7 @@ package totp
 import (
 	"encoding/base32"
 	"errors"
+	"regexp"
 	"time"
 
 	"github.com/labstack/echo/v4"
@@ -49,7 +50,15 @@ func checkSecrets() error {
 	return nil
 }
 
+// a key is made of 2 six-digit passwords
+var reKey = regexp.MustCompile(`^[0-9]{12}$`)
+
 func CheckKey(key string, c echo.Context) (bool, error) {
+	// reject anything that is not a well-formed key before slicing it
+	if !reKey.MatchString(key) {
+		return false, ErrInvalidKeyFormat
+	}
+
 	// sanity check: make sure secrets are properly encoded strings
 	if err := checkSecrets(); err != nil {
 		return false, err
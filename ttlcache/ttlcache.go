@@ -0,0 +1,103 @@
+package ttlcache
+
+import (
+	"sync"
+	"time"
+)
+
+// A small thread-safe in-memory cache whose entries expire after a fixed duration.
+// Expired entries are never returned and are purged lazily when new entries are added.
+type Cache[K comparable, V any] struct {
+	mutex sync.Mutex
+	ttl   time.Duration
+	items map[K]item[V]
+	now   func() time.Time
+}
+
+type item[V any] struct {
+	value   V
+	expires time.Time
+}
+
+func New[K comparable, V any](ttl time.Duration) *Cache[K, V] {
+	return &Cache[K, V]{
+		ttl:   ttl,
+		items: make(map[K]item[V]),
+		now:   time.Now,
+	}
+}
+
+// Returns the value stored for the provided key.
+// The boolean is false if the key is unknown or if the entry has expired.
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if i, ok := c.items[key]; ok && c.now().Before(i.expires) {
+		return i.value, true
+	}
+	var zero V
+	return zero, false
+}
+
+// Stores a value for the provided key, replacing any previous value.
+func (c *Cache[K, V]) Set(key K, value V) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.purge()
+	c.items[key] = item[V]{value: value, expires: c.now().Add(c.ttl)}
+}
+
+// Stores a value only if the key is not already in cache.
+// Returns false if a valid entry already exists for that key.
+// This is the atomic "check and set" needed to detect replays.
+func (c *Cache[K, V]) Add(key K, value V) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.purge()
+	if _, exist := c.items[key]; exist {
+		return false
+	}
+	c.items[key] = item[V]{value: value, expires: c.now().Add(c.ttl)}
+	return true
+}
+
+// Stores a value that expires at the provided time instead of after cache TTL.
+// It is used to restore entries saved before a restart.
+func (c *Cache[K, V]) SetUntil(key K, value V, expires time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.purge()
+	c.items[key] = item[V]{value: value, expires: expires}
+}
+
+// Removes the entry stored for the provided key, if any.
+func (c *Cache[K, V]) Delete(key K) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	delete(c.items, key)
+}
+
+// Returns the number of valid entries.
+func (c *Cache[K, V]) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.purge()
+	return len(c.items)
+}
+
+// remove expired entries
+// caller must hold the mutex
+func (c *Cache[K, V]) purge() {
+	now := c.now()
+	for k, i := range c.items {
+		if !now.Before(i.expires) {
+			delete(c.items, k)
+		}
+	}
+}